@@ -17,12 +17,15 @@ import (
 
 	"github.com/joho/godotenv"
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authcrypt"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cmd"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responses"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/store"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
@@ -61,9 +64,15 @@ func main() {
 	var iflowLogin bool
 	var iflowCookie bool
 	var noBrowser bool
+	var headless bool
 	var antigravityLogin bool
 	var projectID string
 	var vertexImport string
+	var encryptAuths bool
+	var rotateAuthKey string
+	var generateAuthKey bool
+	var exportAuths string
+	var importAuths string
 	var configPath string
 	var password string
 
@@ -75,10 +84,16 @@ func main() {
 	flag.BoolVar(&iflowLogin, "iflow-login", false, "Login to iFlow using OAuth")
 	flag.BoolVar(&iflowCookie, "iflow-cookie", false, "Login to iFlow using Cookie")
 	flag.BoolVar(&noBrowser, "no-browser", false, "Don't open browser automatically for OAuth")
+	flag.BoolVar(&headless, "headless", false, "Finish Claude, Codex or Gemini OAuth login by pasting the redirect URL (no local callback server)")
 	flag.BoolVar(&antigravityLogin, "antigravity-login", false, "Login to Antigravity using OAuth")
 	flag.StringVar(&projectID, "project_id", "", "Project ID (Gemini only, not required)")
 	flag.StringVar(&configPath, "config", DefaultConfigPath, "Configure File Path")
 	flag.StringVar(&vertexImport, "vertex-import", "", "Import Vertex service account key JSON file")
+	flag.BoolVar(&encryptAuths, "encrypt-auths", false, "Encrypt stored auth files with the configured master key")
+	flag.StringVar(&rotateAuthKey, "rotate-auth-key", "", "Re-encrypt stored auth files with the master key in this file")
+	flag.BoolVar(&generateAuthKey, "generate-auth-key", false, "Print a new random master key for auth-encryption")
+	flag.StringVar(&exportAuths, "export-auths", "", "Export all auth files to this bundle file (encrypted with CLIPROXY_BUNDLE_PASSPHRASE if set)")
+	flag.StringVar(&importAuths, "import-auths", "", "Import auth files from this bundle file")
 	flag.StringVar(&password, "password", "", "")
 
 	flag.CommandLine.Usage = func() {
@@ -111,6 +126,11 @@ func main() {
 	// Parse the command-line flags.
 	flag.Parse()
 
+	if generateAuthKey {
+		cmd.DoGenerateAuthKey()
+		return
+	}
+
 	// Core application variables.
 	var err error
 	var cfg *config.Config
@@ -405,10 +425,12 @@ func main() {
 		cfg.AuthDir = resolvedAuthDir
 	}
 	managementasset.SetCurrentConfig(cfg)
+	configureAuthEncryption(cfg, configFilePath)
 
 	// Create login options to be used in authentication flows.
 	options := &cmd.LoginOptions{
 		NoBrowser: noBrowser,
+		Headless:  headless,
 	}
 
 	// Register the shared token store once so all components use the same persistence backend.
@@ -430,6 +452,14 @@ func main() {
 	if vertexImport != "" {
 		// Handle Vertex service account import
 		cmd.DoVertexImport(cfg, vertexImport)
+	} else if encryptAuths {
+		cmd.DoEncryptAuths(cfg)
+	} else if rotateAuthKey != "" {
+		cmd.DoRotateAuthKey(cfg, rotateAuthKey)
+	} else if exportAuths != "" {
+		cmd.DoExportAuths(cfg, exportAuths)
+	} else if importAuths != "" {
+		cmd.DoImportAuths(cfg, importAuths)
 	} else if login {
 		// Handle Google/Gemini login
 		cmd.DoLogin(cfg, projectID, options)
@@ -455,8 +485,103 @@ func main() {
 			cmd.WaitForCloudDeploy()
 			return
 		}
+		// Restore persisted usage statistics before serving traffic.
+		if cfg.UsagePersistence.Enable {
+			var recordStore usage.RecordStore
+			if usePostgresStore {
+				recordStore = pgStoreInst.UsageRecordStore()
+			} else {
+				usagePath := resolveUsagePersistencePath(cfg.UsagePersistence.Path, configFilePath, writableBase)
+				fileStore, errStore := usage.NewFileRecordStore(usagePath)
+				if errStore != nil {
+					log.Fatalf("failed to initialize usage persistence: %v", errStore)
+				}
+				recordStore = fileStore
+				log.Infof("usage persistence enabled, file: %s", usagePath)
+			}
+			retention := time.Duration(cfg.UsagePersistence.RetentionDays) * 24 * time.Hour
+			usagePersistence, errPersist := usage.EnablePersistence(context.Background(), recordStore, retention)
+			if errPersist != nil {
+				log.Fatalf("failed to restore usage statistics: %v", errPersist)
+			}
+			defer usagePersistence.Close()
+		}
+		// Configure the Responses API store used for previous_response_id chaining.
+		configureResponsesStore(cfg, configFilePath)
+		// Load persisted batch jobs; the server resumes unfinished ones once it starts.
+		configureBatches(cfg, configFilePath)
 		// Start the main proxy service
 		managementasset.StartAutoUpdater(context.Background(), configFilePath)
 		cmd.StartService(cfg, configFilePath, password)
 	}
 }
+
+// configureAuthEncryption loads the master key that encrypts credential files at rest.
+func configureAuthEncryption(cfg *config.Config, configFilePath string) {
+	keyFile := strings.TrimSpace(cfg.AuthEncryption.KeyFile)
+	if keyFile != "" && !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(filepath.Dir(configFilePath), keyFile)
+	}
+	key, err := authcrypt.LoadMasterKey(keyFile)
+	if err != nil {
+		log.Fatalf("failed to load auth encryption key: %v", err)
+	}
+	authcrypt.SetKeys(key)
+	if key != nil {
+		log.Infof("auth files are encrypted at rest with key %s", key.ID())
+	}
+}
+
+// configureResponsesStore applies the responses-store settings to the process-wide store.
+func configureResponsesStore(cfg *config.Config, configFilePath string) {
+	storeCfg := cfg.ResponsesStore
+	store := responses.DefaultStore()
+	store.SetDisabled(!storeCfg.Enable)
+	store.Configure(storeCfg.MaxEntries, time.Duration(storeCfg.TTLHours)*time.Hour)
+	dir := strings.TrimSpace(storeCfg.Dir)
+	if !storeCfg.Enable || dir == "" {
+		return
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(configFilePath), dir)
+	}
+	backend, err := responses.NewFileBackend(dir)
+	if err != nil {
+		log.Fatalf("failed to initialize responses store: %v", err)
+	}
+	store.SetBackend(backend)
+	log.Infof("responses store persisted to %s", dir)
+}
+
+// configureBatches points the process-wide batch manager at its storage directory.
+func configureBatches(cfg *config.Config, configFilePath string) {
+	dir := strings.TrimSpace(cfg.Batch.Dir)
+	if dir == "" {
+		dir = "batches"
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(configFilePath), dir)
+	}
+	if err := batch.DefaultManager().Configure(dir, cfg.Batch.Concurrency); err != nil {
+		log.Fatalf("failed to initialize batch storage: %v", err)
+	}
+	log.Infof("batch jobs persisted to %s", dir)
+}
+
+// resolveUsagePersistencePath determines the JSON Lines file used for usage persistence.
+// Relative paths are resolved against the config directory; an empty path falls back to
+// usage/usage-records.jsonl under the writable base or the config directory.
+func resolveUsagePersistencePath(configured, configFilePath, writableBase string) string {
+	baseDir := filepath.Dir(configFilePath)
+	trimmed := strings.TrimSpace(configured)
+	if trimmed == "" {
+		if writableBase != "" {
+			baseDir = writableBase
+		}
+		return filepath.Join(baseDir, "usage", "usage-records.jsonl")
+	}
+	if filepath.IsAbs(trimmed) {
+		return trimmed
+	}
+	return filepath.Join(baseDir, trimmed)
+}
@@ -0,0 +1,133 @@
+package management
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authbundle"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authcrypt"
+)
+
+// bundlePassphraseHeader carries the passphrase that encrypts an exported bundle
+// or decrypts an imported one.
+const bundlePassphraseHeader = "X-Bundle-Passphrase"
+
+// maxBundleSize bounds the size of an uploaded auth bundle.
+const maxBundleSize = 64 << 20
+
+// ExportAuthFiles returns an archive of the auth files, or of those named by the
+// repeatable (or comma separated) name query parameter. Files encrypted at rest are
+// exported in plaintext; set X-Bundle-Passphrase to encrypt the archive itself.
+func (h *Handler) ExportAuthFiles(c *gin.Context) {
+	var names []string
+	for _, value := range c.QueryArray("name") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	entries, err := authbundle.Collect(h.cfg.AuthDir, names)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	passphrase := c.GetHeader(bundlePassphraseHeader)
+	var buf bytes.Buffer
+	if err = authbundle.Write(&buf, entries, passphrase); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to build bundle: %v", err)})
+		return
+	}
+	ext := "zip"
+	if passphrase != "" {
+		ext = "bundle"
+	}
+	name := fmt.Sprintf("auth-files-%s.%s", time.Now().UTC().Format("20060102-150405"), ext)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+}
+
+// ImportAuthFiles registers the credentials in an uploaded bundle, sent as the raw
+// body or as the multipart field "file". Entries are validated by their provider's
+// authenticator and duplicates of existing accounts are skipped unless
+// overwrite=true, which replaces them.
+func (h *Handler) ImportAuthFiles(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	data, err := readBundleUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entries, err := authbundle.Read(data, c.GetHeader(bundlePassphraseHeader))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, authbundle.ErrTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	overwrite := strings.EqualFold(strings.TrimSpace(c.Query("overwrite")), "true")
+	plan := authbundle.PlanImport(entries, h.authManager.List(), authbundle.DefaultValidator(), overwrite)
+
+	ctx := c.Request.Context()
+	imported := make([]string, 0, len(plan.Credentials))
+	replaced := make([]string, 0)
+	skipped := append([]authbundle.Skipped{}, plan.Skipped...)
+	for _, credential := range plan.Credentials {
+		path := filepath.Join(h.cfg.AuthDir, credential.Name)
+		if !filepath.IsAbs(path) {
+			if abs, errAbs := filepath.Abs(path); errAbs == nil {
+				path = abs
+			}
+		}
+		if errWrite := authcrypt.WriteFile(path, credential.Data, 0o600); errWrite != nil {
+			skipped = append(skipped, authbundle.Skipped{Name: credential.Name, Reason: fmt.Sprintf("failed to write file: %v", errWrite)})
+			continue
+		}
+		if errReg := h.registerAuthFromFile(ctx, path, credential.Data); errReg != nil {
+			skipped = append(skipped, authbundle.Skipped{Name: credential.Name, Reason: errReg.Error()})
+			continue
+		}
+		if credential.Replaces != "" {
+			replaced = append(replaced, credential.Name)
+		} else {
+			imported = append(imported, credential.Name)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"imported": imported, "replaced": replaced, "skipped": skipped})
+}
+
+func readBundleUpload(c *gin.Context) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleSize)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("multipart field \"file\" is required")
+		}
+		f, errOpen := file.Open()
+		if errOpen != nil {
+			return nil, fmt.Errorf("failed to read uploaded file: %w", errOpen)
+		}
+		defer func() { _ = f.Close() }()
+		return io.ReadAll(f)
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty bundle")
+	}
+	return data, nil
+}
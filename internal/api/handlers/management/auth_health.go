@@ -0,0 +1,48 @@
+package management
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// ProbeAuthFile runs a health check of the credential named by the name query
+// parameter (its file name or auth ID) right away and returns the outcome, which
+// also updates the credential's status.
+func (h *Handler) ProbeAuthFile(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" || strings.Contains(name, string(os.PathSeparator)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid name"})
+		return
+	}
+	id := name
+	if _, ok := h.authManager.GetByID(id); !ok {
+		full := filepath.Join(h.cfg.AuthDir, name)
+		if !filepath.IsAbs(full) {
+			if abs, errAbs := filepath.Abs(full); errAbs == nil {
+				full = abs
+			}
+		}
+		id = h.authIDForPath(full)
+	}
+	status, err := h.authManager.ProbeAuth(c.Request.Context(), id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		var authErr *coreauth.Error
+		if errors.As(err, &authErr) && authErr.HTTPStatus > 0 {
+			code = authErr.HTTPStatus
+		}
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "health": status})
+}
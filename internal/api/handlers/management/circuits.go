@@ -0,0 +1,30 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCircuitBreakers lists the state of every tracked upstream endpoint circuit.
+func (h *Handler) GetCircuitBreakers(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"circuits": h.authManager.CircuitStatuses()})
+}
+
+// DeleteCircuitBreakers closes circuits matching the optional provider and base_url
+// query parameters; without parameters every circuit is reset.
+func (h *Handler) DeleteCircuitBreakers(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	if !h.authManager.ResetCircuits(c.Query("provider"), c.Query("base_url")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "circuit not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
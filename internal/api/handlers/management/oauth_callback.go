@@ -0,0 +1,78 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+)
+
+// oauthCallbackProviders maps accepted provider names to the name used in the
+// callback files the *-auth-url flows wait for.
+var oauthCallbackProviders = map[string]string{
+	"anthropic":   "anthropic",
+	"claude":      "anthropic",
+	"codex":       "codex",
+	"gemini":      "gemini",
+	"gemini-cli":  "gemini",
+	"antigravity": "antigravity",
+	"iflow":       "iflow",
+}
+
+// PostOAuthCallback completes a pending *-auth-url login with the redirect URL the
+// user copied from a browser that could not reach the callback server, e.g. when
+// the proxy runs on a remote host. The body is
+// {"provider":"claude","redirect_url":"http://localhost:54545/callback?code=...&state=..."};
+// "state" may be given separately when only the code was copied.
+func (h *Handler) PostOAuthCallback(c *gin.Context) {
+	var body struct {
+		Provider    string `json:"provider"`
+		RedirectURL string `json:"redirect_url"`
+		State       string `json:"state"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	provider, ok := oauthCallbackProviders[strings.ToLower(strings.TrimSpace(body.Provider))]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported provider %q", body.Provider)})
+		return
+	}
+	result, err := misc.ParseOAuthCallback(body.RedirectURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	state := strings.TrimSpace(body.State)
+	if state == "" {
+		state = result.State
+	} else if result.State != "" && result.State != state {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state does not match the redirect URL"})
+		return
+	}
+	if state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state is required"})
+		return
+	}
+	if errStatus, pending := getOAuthStatus(state); !pending || errStatus != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no pending login for this state"})
+		return
+	}
+	data, err := json.Marshal(map[string]string{"code": result.Code, "state": state, "error": result.Error})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	file := filepath.Join(h.cfg.AuthDir, fmt.Sprintf(".oauth-%s-%s.oauth", provider, state))
+	if err = os.WriteFile(file, data, 0o600); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to record callback: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "state": state})
+}
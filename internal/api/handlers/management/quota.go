@@ -1,6 +1,13 @@
 package management
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
 
 // Quota exceeded toggles
 func (h *Handler) GetSwitchProject(c *gin.Context) {
@@ -16,3 +23,64 @@ func (h *Handler) GetSwitchPreviewModel(c *gin.Context) {
 func (h *Handler) PutSwitchPreviewModel(c *gin.Context) {
 	h.updateBoolField(c, func(v bool) { h.cfg.QuotaExceeded.SwitchPreviewModel = v })
 }
+
+// accountQuotaKey lists limits that cover the whole account rather than one model.
+const accountQuotaKey = "*"
+
+// GetQuota lists the remaining upstream quota reported for each credential, keyed
+// by model; limits covering the whole account are listed under "*". The optional
+// id query parameter restricts the result to one credential.
+func (h *Handler) GetQuota(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	id := strings.TrimSpace(c.Query("id"))
+	auths := h.authManager.List()
+	sort.Slice(auths, func(i, j int) bool { return auths[i].ID < auths[j].ID })
+	entries := make([]gin.H, 0)
+	for _, auth := range auths {
+		if id != "" && auth.ID != id {
+			continue
+		}
+		models := h.quotaModels(auth)
+		if len(models) == 0 {
+			continue
+		}
+		entry := gin.H{
+			"id":       auth.ID,
+			"provider": auth.Provider,
+			"label":    auth.Label,
+			"models":   models,
+		}
+		if _, account := auth.AccountInfo(); account != "" {
+			entry["account"] = account
+		}
+		entries = append(entries, entry)
+	}
+	c.JSON(http.StatusOK, gin.H{"quota": entries})
+}
+
+// quotaModels returns the reported limits of auth per model together with
+// whether selection currently avoids the credential for that model.
+func (h *Handler) quotaModels(auth *coreauth.Auth) gin.H {
+	states := h.authManager.RateLimits(auth.ID)
+	if len(states) == 0 {
+		return nil
+	}
+	models := make(gin.H, len(states))
+	for model, state := range states {
+		item := gin.H{"windows": state.Windows, "updated_at": state.UpdatedAt}
+		if low, resetAt := h.authManager.RateLimitLow(auth.ID, model); low {
+			item["avoided"] = true
+			if !resetAt.IsZero() {
+				item["avoided_until"] = resetAt
+			}
+		}
+		if model == "" {
+			model = accountQuotaKey
+		}
+		models[model] = item
+	}
+	return models
+}
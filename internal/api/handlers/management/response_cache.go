@@ -0,0 +1,25 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetResponseCache reports the response cache counters and its live entries.
+func (h *Handler) GetResponseCache(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, h.authManager.ResponseCacheStats())
+}
+
+// DeleteResponseCache flushes every cached response.
+func (h *Handler) DeleteResponseCache(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "flushed": h.authManager.FlushResponseCache()})
+}
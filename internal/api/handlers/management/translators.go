@@ -0,0 +1,94 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// GetTranslators lists the registered translator pairs and the formats they connect.
+// A pair's response capabilities describe translating the target format's responses
+// back into the source format.
+func (h *Handler) GetTranslators(c *gin.Context) {
+	pairs := sdktranslator.Pairs()
+	c.JSON(http.StatusOK, gin.H{"formats": translatorFormats(pairs), "pairs": pairs})
+}
+
+// PostTranslatorDryRun translates a source payload into the target format without
+// calling any upstream. The result is the translator output only; executors may
+// still apply payload overrides and provider-specific adjustments afterwards.
+func (h *Handler) PostTranslatorDryRun(c *gin.Context) {
+	var body struct {
+		From    string          `json:"from"`
+		To      string          `json:"to"`
+		Model   string          `json:"model"`
+		Stream  bool            `json:"stream"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	payload := gjson.ParseBytes(body.Payload)
+	// Accept payloads pasted from logs as a JSON-encoded string.
+	if payload.Type == gjson.String {
+		payload = gjson.Parse(payload.String())
+	}
+	if !payload.IsObject() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payload must be a JSON object"})
+		return
+	}
+	formats := translatorFormats(sdktranslator.Pairs())
+	from, to := strings.TrimSpace(body.From), strings.TrimSpace(body.To)
+	for _, format := range []string{from, to} {
+		if !containsFormat(formats, format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown format %q; known formats: %s", format, strings.Join(formats, ", "))})
+			return
+		}
+	}
+	model := strings.TrimSpace(body.Model)
+	if model == "" {
+		model = payload.Get("model").String()
+	}
+	fromFormat, toFormat := sdktranslator.FromString(from), sdktranslator.FromString(to)
+	// Without a registered translator the executors forward the payload unchanged.
+	translated := from != to && sdktranslator.HasRequestTransformer(fromFormat, toFormat)
+	out := sdktranslator.TranslateRequest(fromFormat, toFormat, model, []byte(payload.Raw), body.Stream)
+	var request any = string(out)
+	if json.Valid(out) {
+		request = json.RawMessage(out)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":       from,
+		"to":         to,
+		"model":      model,
+		"stream":     body.Stream,
+		"translated": translated,
+		"request":    request,
+	})
+}
+
+func translatorFormats(pairs []sdktranslator.Pair) []string {
+	seen := make(map[string]struct{})
+	for _, pair := range pairs {
+		seen[pair.From.String()] = struct{}{}
+		seen[pair.To.String()] = struct{}{}
+	}
+	formats := make([]string, 0, len(seen))
+	for format := range seen {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func containsFormat(formats []string, format string) bool {
+	i := sort.SearchStrings(formats, format)
+	return i < len(formats) && formats[i] == format
+}
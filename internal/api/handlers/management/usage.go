@@ -1,14 +1,35 @@
 package management
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 )
 
+const (
+	defaultUsageDetailLimit = 100
+	maxUsageDetailLimit     = 1000
+)
+
+// usageQueryParams lists the query parameters that switch GetUsageStatistics into query mode.
+var usageQueryParams = []string{
+	"from", "to", "group_by", "granularity", "offset", "limit",
+	"api_key", "model", "provider", "source", "auth_index",
+}
+
 // GetUsageStatistics returns the in-memory request statistics snapshot.
+// When any filter, grouping or pagination parameter is supplied it returns an
+// aggregated query result instead of the full snapshot.
 func (h *Handler) GetUsageStatistics(c *gin.Context) {
+	if hasUsageQuery(c) {
+		h.queryUsageStatistics(c)
+		return
+	}
 	var snapshot usage.StatisticsSnapshot
 	if h != nil && h.usageStats != nil {
 		snapshot = h.usageStats.Snapshot()
@@ -18,3 +39,88 @@ func (h *Handler) GetUsageStatistics(c *gin.Context) {
 		"failed_requests": snapshot.FailureCount,
 	})
 }
+
+func (h *Handler) queryUsageStatistics(c *gin.Context) {
+	query, err := parseUsageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var stats *usage.RequestStatistics
+	if h != nil {
+		stats = h.usageStats
+	}
+	result, err := stats.Query(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": result})
+}
+
+func hasUsageQuery(c *gin.Context) bool {
+	for _, key := range usageQueryParams {
+		if _, ok := c.GetQuery(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseUsageQuery(c *gin.Context) (usage.Query, error) {
+	query := usage.Query{
+		APIKey:      strings.TrimSpace(c.Query("api_key")),
+		Model:       strings.TrimSpace(c.Query("model")),
+		Provider:    strings.TrimSpace(c.Query("provider")),
+		Source:      strings.TrimSpace(c.Query("source")),
+		GroupBy:     strings.ToLower(strings.TrimSpace(c.Query("group_by"))),
+		Granularity: strings.ToLower(strings.TrimSpace(c.Query("granularity"))),
+		Limit:       defaultUsageDetailLimit,
+	}
+	var err error
+	if query.From, err = parseUsageTime(c.Query("from")); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+	if query.To, err = parseUsageTime(c.Query("to")); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+	if raw := strings.TrimSpace(c.Query("auth_index")); raw != "" {
+		idx, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			return query, fmt.Errorf("invalid auth_index: %w", errParse)
+		}
+		query.AuthIndex = &idx
+	}
+	if raw := strings.TrimSpace(c.Query("offset")); raw != "" {
+		if query.Offset, err = strconv.Atoi(raw); err != nil {
+			return query, fmt.Errorf("invalid offset: %w", err)
+		}
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		if query.Limit, err = strconv.Atoi(raw); err != nil {
+			return query, fmt.Errorf("invalid limit: %w", err)
+		}
+	}
+	if query.Limit > maxUsageDetailLimit {
+		query.Limit = maxUsageDetailLimit
+	}
+	return query, nil
+}
+
+// parseUsageTime accepts RFC3339 timestamps, YYYY-MM-DD dates (local time), or unix seconds.
+func parseUsageTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if ts, err := time.Parse(time.RFC3339, raw); err == nil {
+		return ts, nil
+	}
+	if ts, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		return ts, nil
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC3339, YYYY-MM-DD or unix seconds, got %q", raw)
+}
@@ -23,9 +23,11 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/middleware"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules"
 	ampmodule "github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules/amp"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -252,6 +254,14 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	}
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
+	metrics.SetEnabled(cfg.Metrics.Enable)
+	metrics.SetBearerToken(cfg.Metrics.BearerToken)
+	metrics.SetAuthSource(func() []*auth.Auth {
+		if s.handlers == nil || s.handlers.AuthManager == nil {
+			return nil
+		}
+		return s.handlers.AuthManager.List()
+	})
 	// Initialize management handler
 	s.mgmt = managementHandlers.NewHandler(cfg, configFilePath, authManager)
 	if optionState.localPassword != "" {
@@ -266,6 +276,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 
 	// Setup routes
 	s.setupRoutes()
+	batch.DefaultManager().Start(s.handlers.ExecuteBatchRequest)
 
 	// Register Amp module using V2 interface with Context
 	ampModule := ampmodule.NewLegacy(accessManager, AuthMiddleware(accessManager))
@@ -321,9 +332,29 @@ func (s *Server) setupRoutes() {
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
 		v1.POST("/completions", openaiHandlers.Completions)
+		v1.POST("/embeddings", openaiHandlers.Embeddings)
+		v1.POST("/images/generations", openaiHandlers.ImageGenerations)
+		v1.POST("/images/edits", openaiHandlers.ImageEdits)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
+		v1.GET("/responses/:id", openaiResponsesHandlers.GetResponse)
+		v1.DELETE("/responses/:id", openaiResponsesHandlers.DeleteResponse)
+		v1.POST("/messages/batches", claudeCodeHandlers.CreateMessageBatch)
+		v1.GET("/messages/batches", claudeCodeHandlers.ListMessageBatches)
+		v1.GET("/messages/batches/:id", claudeCodeHandlers.GetMessageBatch)
+		v1.GET("/messages/batches/:id/results", claudeCodeHandlers.MessageBatchResults)
+		v1.POST("/messages/batches/:id/cancel", claudeCodeHandlers.CancelMessageBatch)
+		v1.DELETE("/messages/batches/:id", claudeCodeHandlers.DeleteMessageBatch)
+		v1.POST("/batches", openaiHandlers.CreateBatch)
+		v1.GET("/batches", openaiHandlers.ListBatches)
+		v1.GET("/batches/:id", openaiHandlers.GetBatch)
+		v1.POST("/batches/:id/cancel", openaiHandlers.CancelBatch)
+		v1.POST("/files", openaiHandlers.UploadFile)
+		v1.GET("/files", openaiHandlers.ListFiles)
+		v1.GET("/files/:id", openaiHandlers.GetFile)
+		v1.GET("/files/:id/content", openaiHandlers.FileContent)
+		v1.DELETE("/files/:id", openaiHandlers.DeleteFile)
 	}
 
 	// Gemini compatible API routes
@@ -348,6 +379,13 @@ func (s *Server) setupRoutes() {
 	})
 	s.engine.POST("/v1internal:method", geminiCLIHandlers.CLIHandler)
 
+	// Images generated with response_format=url; served without auth so clients can embed them.
+	s.engine.GET("/v1/images/files/:id", openaiHandlers.ImageFile)
+
+	// Metrics endpoint; responds with 404 unless metrics.enable is set and requires
+	// metrics.bearer-token (or a loopback client) otherwise.
+	s.engine.GET("/metrics", metrics.Handler())
+
 	// OAuth callback endpoints (reuse main server port)
 	// These endpoints receive provider redirects and persist
 	// the short-lived code/state for the waiting goroutine.
@@ -466,6 +504,13 @@ func (s *Server) registerManagementRoutes() {
 	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware())
 	{
 		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
+		mgmt.GET("/circuit-breakers", s.mgmt.GetCircuitBreakers)
+		mgmt.DELETE("/circuit-breakers", s.mgmt.DeleteCircuitBreakers)
+		mgmt.GET("/quota", s.mgmt.GetQuota)
+		mgmt.GET("/response-cache", s.mgmt.GetResponseCache)
+		mgmt.DELETE("/response-cache", s.mgmt.DeleteResponseCache)
+		mgmt.GET("/translators", s.mgmt.GetTranslators)
+		mgmt.POST("/translators/dry-run", s.mgmt.PostTranslatorDryRun)
 		mgmt.GET("/config", s.mgmt.GetConfig)
 		mgmt.PUT("/config.yaml", s.mgmt.PutConfigYAML)
 		mgmt.GET("/config.yaml", s.mgmt.GetConfigFile)
@@ -547,6 +592,9 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/auth-files/download", s.mgmt.DownloadAuthFile)
 		mgmt.POST("/auth-files", s.mgmt.UploadAuthFile)
 		mgmt.DELETE("/auth-files", s.mgmt.DeleteAuthFile)
+		mgmt.GET("/auth-files/export", s.mgmt.ExportAuthFiles)
+		mgmt.POST("/auth-files/import", s.mgmt.ImportAuthFiles)
+		mgmt.POST("/auth-files/health-check", s.mgmt.ProbeAuthFile)
 		mgmt.POST("/vertex/import", s.mgmt.ImportVertexCredential)
 
 		mgmt.GET("/anthropic-auth-url", s.mgmt.RequestAnthropicToken)
@@ -556,6 +604,7 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/qwen-auth-url", s.mgmt.RequestQwenToken)
 		mgmt.GET("/iflow-auth-url", s.mgmt.RequestIFlowToken)
 		mgmt.POST("/iflow-auth-url", s.mgmt.RequestIFlowCookieToken)
+		mgmt.POST("/oauth-callback", s.mgmt.PostOAuthCallback)
 		mgmt.GET("/get-auth-status", s.mgmt.GetAuthStatus)
 	}
 }
@@ -830,6 +879,19 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		}
 	}
 
+	if oldCfg == nil || oldCfg.Metrics.Enable != cfg.Metrics.Enable {
+		metrics.SetEnabled(cfg.Metrics.Enable)
+		if oldCfg != nil {
+			log.Debugf("metrics.enable updated from %t to %t", oldCfg.Metrics.Enable, cfg.Metrics.Enable)
+		}
+	}
+	if oldCfg == nil || oldCfg.Metrics.BearerToken != cfg.Metrics.BearerToken {
+		metrics.SetBearerToken(cfg.Metrics.BearerToken)
+		if oldCfg != nil {
+			log.Debug("metrics.bearer-token updated")
+		}
+	}
+
 	if oldCfg == nil || oldCfg.DisableCooling != cfg.DisableCooling {
 		auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
 		if oldCfg != nil {
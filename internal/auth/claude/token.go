@@ -71,3 +71,13 @@ func (ts *ClaudeTokenStorage) SaveTokenToFile(authFilePath string) error {
 	}
 	return nil
 }
+
+// EncodeToken returns the token storage as the JSON document SaveTokenToFile writes.
+func (ts *ClaudeTokenStorage) EncodeToken() ([]byte, error) {
+	ts.Type = "claude"
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode token: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -64,3 +64,13 @@ func (ts *CodexTokenStorage) SaveTokenToFile(authFilePath string) error {
 	return nil
 
 }
+
+// EncodeToken returns the token storage as the JSON document SaveTokenToFile writes.
+func (ts *CodexTokenStorage) EncodeToken() ([]byte, error) {
+	ts.Type = "codex"
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode token: %w", err)
+	}
+	return append(data, '\n'), nil
+}
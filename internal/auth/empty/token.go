@@ -24,3 +24,9 @@ func (ts *EmptyStorage) SaveTokenToFile(_ string) error {
 	ts.Type = "empty"
 	return nil
 }
+
+// EncodeToken returns nil because empty storage has nothing to persist.
+func (ts *EmptyStorage) EncodeToken() ([]byte, error) {
+	ts.Type = "empty"
+	return nil, nil
+}
@@ -33,6 +33,10 @@ import (
 // It encapsulates the logic for obtaining, storing, and refreshing authentication tokens
 // for Google's Gemini AI services.
 type GeminiAuth struct {
+	// PromptCallback, when set, replaces the local callback server during login. It
+	// receives the authorization URL and returns the code and state the user copied
+	// from the browser's redirect.
+	PromptCallback func(authURL string) (code, state string, err error)
 }
 
 // NewGeminiAuth creates a new instance of GeminiAuth.
@@ -120,6 +124,30 @@ func (g *GeminiAuth) GetAuthenticatedClient(ctx context.Context, ts *GeminiToken
 	return conf.Client(ctx, token), nil
 }
 
+// getTokenFromPrompt runs the authorization step through PromptCallback instead of
+// a local callback server, for hosts the user's browser cannot reach.
+func (g *GeminiAuth) getTokenFromPrompt(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
+	const state = "state-token"
+	config.RedirectURL = "http://localhost:8085/oauth2callback"
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+
+	code, returnedState, err := g.PromptCallback(authURL)
+	if err != nil {
+		return nil, err
+	}
+	if returnedState != "" && returnedState != state {
+		return nil, fmt.Errorf("state mismatch in pasted redirect URL")
+	}
+
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	fmt.Println("Authentication successful.")
+	return token, nil
+}
+
 // createTokenStorage creates a new GeminiTokenStorage object. It fetches the user's email
 // using the provided token and populates the storage structure.
 //
@@ -199,6 +227,10 @@ func (g *GeminiAuth) createTokenStorage(ctx context.Context, config *oauth2.Conf
 //   - *oauth2.Token: The OAuth2 token obtained from the authorization flow
 //   - error: An error if the token acquisition fails, nil otherwise
 func (g *GeminiAuth) getTokenFromWeb(ctx context.Context, config *oauth2.Config, noBrowser ...bool) (*oauth2.Token, error) {
+	if g.PromptCallback != nil {
+		return g.getTokenFromPrompt(ctx, config)
+	}
+
 	// Use a channel to pass the authorization code from the HTTP handler to the main function.
 	codeChan := make(chan string)
 	errChan := make(chan error)
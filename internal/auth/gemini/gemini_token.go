@@ -85,3 +85,13 @@ func CredentialFileName(email, projectID string, includeProviderPrefix bool) str
 	}
 	return fmt.Sprintf("%s%s-%s.json", prefix, email, project)
 }
+
+// EncodeToken returns the token storage as the JSON document SaveTokenToFile writes.
+func (ts *GeminiTokenStorage) EncodeToken() ([]byte, error) {
+	ts.Type = "gemini"
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode token: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -42,3 +42,13 @@ func (ts *IFlowTokenStorage) SaveTokenToFile(authFilePath string) error {
 	}
 	return nil
 }
+
+// EncodeToken returns the token storage as the JSON document SaveTokenToFile writes.
+func (ts *IFlowTokenStorage) EncodeToken() ([]byte, error) {
+	ts.Type = "iflow"
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, fmt.Errorf("iflow token: encode token failed: %w", err)
+	}
+	return append(data, '\n'), nil
+}
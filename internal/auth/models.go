@@ -15,3 +15,11 @@ type TokenStorage interface {
 	//   - error: An error if the save operation fails, nil otherwise
 	SaveTokenToFile(authFilePath string) error
 }
+
+// TokenEncoder is implemented by token storages that can serialize themselves
+// without touching the file system, so callers can encrypt the result before it
+// is written. A nil result means there is nothing to persist.
+type TokenEncoder interface {
+	// EncodeToken returns the JSON document SaveTokenToFile would write.
+	EncodeToken() ([]byte, error)
+}
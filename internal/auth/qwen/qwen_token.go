@@ -61,3 +61,13 @@ func (ts *QwenTokenStorage) SaveTokenToFile(authFilePath string) error {
 	}
 	return nil
 }
+
+// EncodeToken returns the token storage as the JSON document SaveTokenToFile writes.
+func (ts *QwenTokenStorage) EncodeToken() ([]byte, error) {
+	ts.Type = "qwen"
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode token: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,257 @@
+// Package authbundle packs credential files into a single archive for moving them
+// between proxy instances. A bundle is a zip archive holding one plaintext JSON file
+// per credential; it can be sealed with a passphrase using scrypt and AES-256-GCM.
+package authbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PassphraseEnv supplies the bundle passphrase to the -export-auths and -import-auths commands.
+	PassphraseEnv = "CLIPROXY_BUNDLE_PASSPHRASE"
+
+	manifestName = "manifest.json"
+	entryDir     = "auths/"
+	version      = 1
+
+	// maxEntrySize caps the decompressed size of one credential file.
+	maxEntrySize = 1 << 20
+	// maxBundleSize caps the decompressed size of all credential files together.
+	maxBundleSize = 64 << 20
+
+	saltSize = 16
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+)
+
+// sealedMagic prefixes passphrase-protected bundles.
+var sealedMagic = []byte("CLIPROXY-AUTH-BUNDLE-1\n")
+
+var (
+	// ErrPassphraseRequired is returned when a sealed bundle is read without a passphrase.
+	ErrPassphraseRequired = errors.New("auth bundle is encrypted; a passphrase is required")
+	// ErrBadPassphrase is returned when a sealed bundle cannot be opened with the given passphrase.
+	ErrBadPassphrase = errors.New("auth bundle passphrase is incorrect or the bundle is corrupted")
+	// ErrTooLarge is returned when a bundle decompresses to more than the allowed size.
+	ErrTooLarge = errors.New("auth bundle is too large")
+)
+
+// Entry is one credential file in a bundle.
+type Entry struct {
+	Name string
+	Data []byte
+}
+
+type manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     []string  `json:"files"`
+}
+
+// Collect reads the credential files directly under dir, decrypting files that are
+// encrypted at rest. When names is not empty only those files are returned, and a
+// missing name is an error.
+func Collect(dir string, names []string) ([]Entry, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			wanted[name] = false
+		}
+	}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read auth dir: %w", err)
+	}
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !strings.HasSuffix(strings.ToLower(name), ".json") {
+			continue
+		}
+		if len(wanted) > 0 {
+			if _, ok := wanted[name]; !ok {
+				continue
+			}
+			wanted[name] = true
+		}
+		data, errRead := os.ReadFile(filepath.Join(dir, name))
+		if errRead != nil {
+			return nil, fmt.Errorf("read %s: %w", name, errRead)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if data, errRead = authcrypt.Open(name, data); errRead != nil {
+			return nil, fmt.Errorf("%s: %w", name, errRead)
+		}
+		entries = append(entries, Entry{Name: name, Data: data})
+	}
+	for name, found := range wanted {
+		if !found {
+			return nil, fmt.Errorf("auth file %s not found", name)
+		}
+	}
+	return entries, nil
+}
+
+// Write encodes entries as a bundle, sealed with passphrase when it is not empty.
+func Write(w io.Writer, entries []Entry, passphrase string) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	m := manifest{Version: version, CreatedAt: time.Now().UTC(), Files: make([]string, 0, len(entries))}
+	for _, entry := range entries {
+		fw, err := zw.Create(entryDir + entry.Name)
+		if err != nil {
+			return fmt.Errorf("add %s: %w", entry.Name, err)
+		}
+		if _, err = fw.Write(entry.Data); err != nil {
+			return fmt.Errorf("add %s: %w", entry.Name, err)
+		}
+		m.Files = append(m.Files, entry.Name)
+	}
+	sort.Strings(m.Files)
+	manifestData, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	fw, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	if _, err = fw.Write(manifestData); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if passphrase != "" {
+		if data, err = seal(data, passphrase); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Read decodes a bundle produced by Write.
+func Read(data []byte, passphrase string) ([]Entry, error) {
+	if IsSealed(data) {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		var err error
+		if data, err = open(data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth bundle: %w", err)
+	}
+	entries := make([]Entry, 0, len(zr.File))
+	var total int64
+	for _, file := range zr.File {
+		if !strings.HasPrefix(file.Name, entryDir) || file.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Base(file.Name)
+		if !strings.HasSuffix(strings.ToLower(name), ".json") {
+			continue
+		}
+		rc, errOpen := file.Open()
+		if errOpen != nil {
+			return nil, fmt.Errorf("read %s: %w", name, errOpen)
+		}
+		// The sizes in the zip headers are not trusted; read at most one byte past the cap.
+		content, errRead := io.ReadAll(io.LimitReader(rc, maxEntrySize+1))
+		_ = rc.Close()
+		if errRead != nil {
+			return nil, fmt.Errorf("read %s: %w", name, errRead)
+		}
+		if len(content) > maxEntrySize {
+			return nil, fmt.Errorf("read %s: %w", name, ErrTooLarge)
+		}
+		if total += int64(len(content)); total > maxBundleSize {
+			return nil, ErrTooLarge
+		}
+		entries = append(entries, Entry{Name: name, Data: content})
+	}
+	return entries, nil
+}
+
+// IsSealed reports whether data is a passphrase-protected bundle.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedMagic)
+}
+
+func seal(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	aead, err := passphraseAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	out := make([]byte, 0, len(sealedMagic)+saltSize+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, sealedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, sealedMagic), nil
+}
+
+func open(data []byte, passphrase string) ([]byte, error) {
+	data = data[len(sealedMagic):]
+	if len(data) < saltSize {
+		return nil, ErrBadPassphrase
+	}
+	aead, err := passphraseAEAD(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrBadPassphrase
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], sealedMagic)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return plaintext, nil
+}
+
+func passphraseAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,141 @@
+package authbundle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Name: "claude-user@example.com.json", Data: []byte(`{"type":"claude","email":"user@example.com"}`)},
+		{Name: "codex-user@example.com.json", Data: []byte(`{"type":"codex","email":"user@example.com"}`)},
+	}
+
+	testCases := []struct {
+		name           string
+		passphrase     string
+		readPassphrase string
+		wantSealed     bool
+		wantErr        error
+	}{
+		{
+			name: "plain bundle",
+		},
+		{
+			name:           "plain bundle ignores a passphrase",
+			readPassphrase: "unused",
+		},
+		{
+			name:           "sealed bundle",
+			passphrase:     "correct horse",
+			readPassphrase: "correct horse",
+			wantSealed:     true,
+		},
+		{
+			name:       "sealed bundle without passphrase",
+			passphrase: "correct horse",
+			wantSealed: true,
+			wantErr:    ErrPassphraseRequired,
+		},
+		{
+			name:           "sealed bundle with wrong passphrase",
+			passphrase:     "correct horse",
+			readPassphrase: "battery staple",
+			wantSealed:     true,
+			wantErr:        ErrBadPassphrase,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, entries, tc.passphrase); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if IsSealed(buf.Bytes()) != tc.wantSealed {
+				t.Fatalf("IsSealed() = %v, want %v", IsSealed(buf.Bytes()), tc.wantSealed)
+			}
+			if tc.wantSealed && bytes.Contains(buf.Bytes(), []byte("user@example.com")) {
+				t.Fatalf("sealed bundle contains plaintext credentials")
+			}
+
+			got, err := Read(buf.Bytes(), tc.readPassphrase)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Read() error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if len(got) != len(entries) {
+				t.Fatalf("Read() returned %d entries, want %d", len(got), len(entries))
+			}
+			for i := range entries {
+				if got[i].Name != entries[i].Name || !bytes.Equal(got[i].Data, entries[i].Data) {
+					t.Fatalf("entry %d = %s %s, want %s %s", i, got[i].Name, got[i].Data, entries[i].Name, entries[i].Data)
+				}
+			}
+		})
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries []Entry
+		wantErr error
+	}{
+		{
+			name:    "entry at the cap",
+			entries: []Entry{{Name: "a.json", Data: make([]byte, maxEntrySize)}},
+		},
+		{
+			name:    "entry over the cap",
+			entries: []Entry{{Name: "a.json", Data: make([]byte, maxEntrySize+1)}},
+			wantErr: ErrTooLarge,
+		},
+		{
+			name:    "bundle over the total cap",
+			entries: oversizedBundle(),
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tc.entries, ""); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			_, err := Read(buf.Bytes(), "")
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Read() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadRejectsInvalidData(t *testing.T) {
+	if _, err := Read([]byte("not a zip archive"), ""); err == nil {
+		t.Fatalf("Read() succeeded for invalid data")
+	}
+}
+
+// oversizedBundle returns entries that each fit the per-entry cap but together
+// exceed the bundle cap. The zero bytes compress to almost nothing.
+func oversizedBundle() []Entry {
+	count := maxBundleSize/maxEntrySize + 1
+	entries := make([]Entry, 0, count)
+	data := make([]byte, maxEntrySize)
+	for i := 0; i < count; i++ {
+		entries = append(entries, Entry{Name: fmt.Sprintf("auth-%d.json", i), Data: data})
+	}
+	return entries
+}
@@ -0,0 +1,162 @@
+package authbundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// Validator checks the metadata of a credential of the given provider type.
+type Validator func(provider string, metadata map[string]any) error
+
+// DefaultValidator validates credentials with the built-in authenticators.
+// Credential types without an authenticator, such as vertex service accounts,
+// only need a type.
+func DefaultValidator() Validator {
+	manager := sdkAuth.NewManager(nil,
+		sdkAuth.NewGeminiAuthenticator(),
+		sdkAuth.NewCodexAuthenticator(),
+		sdkAuth.NewClaudeAuthenticator(),
+		sdkAuth.NewQwenAuthenticator(),
+		sdkAuth.NewIFlowAuthenticator(),
+		sdkAuth.NewAntigravityAuthenticator(),
+	)
+	return func(provider string, metadata map[string]any) error {
+		if err := manager.Validate(provider, metadata); err != nil && !errors.Is(err, sdkAuth.ErrNoAuthenticator) {
+			return err
+		}
+		return nil
+	}
+}
+
+// Credential is a bundle entry accepted for import.
+type Credential struct {
+	// Name is the file name to store the credential under.
+	Name     string
+	Data     []byte
+	Provider string
+	Metadata map[string]any
+	// Replaces is the ID of the existing credential this one overwrites, if any.
+	Replaces string
+}
+
+// Skipped is a bundle entry that is not imported.
+type Skipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Plan lists what an import will write.
+type Plan struct {
+	Credentials []Credential
+	Skipped     []Skipped
+}
+
+// PlanImport decodes and validates entries and drops duplicates, both within the
+// bundle and of existing credentials. Credentials are the same when their type,
+// email and project match. A duplicate of an existing credential, or an entry
+// whose file name is taken, is skipped unless overwrite is set, in which case it
+// replaces the existing credential under that credential's file name.
+func PlanImport(entries []Entry, existing []*coreauth.Auth, validate Validator, overwrite bool) *Plan {
+	byIdentity := make(map[string]*coreauth.Auth)
+	byName := make(map[string]*coreauth.Auth)
+	for _, auth := range existing {
+		if auth == nil || auth.Metadata == nil {
+			continue
+		}
+		if key := identity(auth.Metadata); key != "" {
+			byIdentity[key] = auth
+		}
+		if name := fileName(auth); name != "" {
+			byName[name] = auth
+		}
+	}
+
+	plan := &Plan{}
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		name := filepath.Base(strings.TrimSpace(entry.Name))
+		skip := func(reason string) { plan.Skipped = append(plan.Skipped, Skipped{Name: name, Reason: reason}) }
+		if name == "" || name == "." || !strings.HasSuffix(strings.ToLower(name), ".json") {
+			skip("not a .json file")
+			continue
+		}
+		metadata := make(map[string]any)
+		if err := json.Unmarshal(entry.Data, &metadata); err != nil {
+			skip(fmt.Sprintf("invalid json: %v", err))
+			continue
+		}
+		provider, _ := metadata["type"].(string)
+		provider = strings.TrimSpace(provider)
+		if provider == "" {
+			skip("missing type")
+			continue
+		}
+		if validate != nil {
+			if err := validate(provider, metadata); err != nil {
+				skip(err.Error())
+				continue
+			}
+		}
+		key := identity(metadata)
+		dedupeKey := key
+		if dedupeKey == "" {
+			dedupeKey = "file:" + name
+		}
+		if first, dup := seen[dedupeKey]; dup {
+			skip(fmt.Sprintf("duplicate of %s in the bundle", first))
+			continue
+		}
+		seen[dedupeKey] = name
+
+		target := byName[name]
+		if key != "" {
+			if match := byIdentity[key]; match != nil {
+				target = match
+			}
+		}
+		credential := Credential{Name: name, Data: entry.Data, Provider: provider, Metadata: metadata}
+		if target != nil {
+			if !overwrite {
+				skip(fmt.Sprintf("already present as %s", target.ID))
+				continue
+			}
+			if targetName := fileName(target); targetName != "" {
+				credential.Name = targetName
+			}
+			credential.Replaces = target.ID
+		}
+		plan.Credentials = append(plan.Credentials, credential)
+	}
+	return plan
+}
+
+// identity returns the key under which two credentials count as the same account,
+// or "" when the credential names no account.
+func identity(metadata map[string]any) string {
+	provider, _ := metadata["type"].(string)
+	email, _ := metadata["email"].(string)
+	project, _ := metadata["project_id"].(string)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(provider)) + "|" + email + "|" + strings.ToLower(strings.TrimSpace(project))
+}
+
+func fileName(auth *coreauth.Auth) string {
+	if auth.Attributes != nil {
+		if p := strings.TrimSpace(auth.Attributes["path"]); p != "" {
+			return filepath.Base(p)
+		}
+	}
+	if name := strings.TrimSpace(auth.FileName); name != "" {
+		return filepath.Base(name)
+	}
+	return ""
+}
@@ -0,0 +1,382 @@
+// Package authcrypt encrypts credential files at rest. Each file is sealed with
+// its own random data key using AES-256-GCM, and the data key is wrapped with a
+// master key supplied by the operator. Sealed files remain JSON documents so the
+// token stores, the watcher and PostgreSQL can keep treating them as JSON.
+// Plaintext files are still accepted on read.
+package authcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	baseauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+)
+
+const (
+	// KeyEnv holds the master key, base64 or hex encoded.
+	KeyEnv = "CLIPROXY_AUTH_KEY"
+	// KeyFileEnv names a file holding the master key.
+	KeyFileEnv = "CLIPROXY_AUTH_KEY_FILE"
+
+	// KeySize is the master key length in bytes.
+	KeySize = 32
+
+	envelopeVersion   = 1
+	envelopeAlgorithm = "AES-256-GCM"
+	envelopeMarker    = `"cliproxy_encrypted"`
+)
+
+// additionalDataPrefix binds ciphertexts to this envelope format; the file name
+// is appended so a sealed file cannot be swapped for another credential's.
+const additionalDataPrefix = "cliproxy-auth-v1\x00"
+
+func additionalData(name string) []byte {
+	return []byte(additionalDataPrefix + filepath.Base(name))
+}
+
+var (
+	// ErrNoKey is returned when a sealed file is read but no master key is configured.
+	ErrNoKey = errors.New("authcrypt: file is encrypted but no master key is configured")
+	// ErrUnknownKey is returned when a sealed file was written with a key that is not loaded.
+	ErrUnknownKey = errors.New("authcrypt: file is encrypted with an unknown master key")
+)
+
+// Key is a master key.
+type Key struct {
+	id    string
+	bytes []byte
+}
+
+// ID returns a short fingerprint of the key, stored with every sealed file.
+func (k *Key) ID() string { return k.id }
+
+// envelope is the on-disk form of a sealed file.
+type envelope struct {
+	Version    int    `json:"cliproxy_encrypted"`
+	Algorithm  string `json:"alg"`
+	KeyID      string `json:"kid"`
+	WrappedKey string `json:"wrapped_key"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+var (
+	mu       sync.RWMutex
+	current  *Key
+	previous []*Key
+)
+
+// SetKeys installs the process-wide master keys. New files are sealed with
+// current; files sealed with any of previous can still be opened. A nil current
+// disables encryption.
+func SetKeys(cur *Key, prev ...*Key) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = cur
+	previous = previous[:0]
+	for _, key := range prev {
+		if key != nil {
+			previous = append(previous, key)
+		}
+	}
+}
+
+// CurrentKey returns the key new files are sealed with, or nil when encryption is disabled.
+func CurrentKey() *Key {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Enabled reports whether new files are sealed.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current != nil
+}
+
+// ParseKey decodes a base64 or hex encoded master key.
+func ParseKey(encoded string) (*Key, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, errors.New("authcrypt: empty master key")
+	}
+	decoders := []func(string) ([]byte, error){
+		hex.DecodeString,
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+	}
+	for _, decode := range decoders {
+		if raw, err := decode(encoded); err == nil && len(raw) == KeySize {
+			return newKey(raw), nil
+		}
+	}
+	return nil, fmt.Errorf("authcrypt: master key must be %d bytes encoded as base64 or hex", KeySize)
+}
+
+// LoadKeyFile reads a master key from path.
+func LoadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("authcrypt: read key file: %w", err)
+	}
+	key, err := ParseKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, path)
+	}
+	return key, nil
+}
+
+// LoadMasterKey returns the master key from CLIPROXY_AUTH_KEY, CLIPROXY_AUTH_KEY_FILE
+// or keyFile, in that order. It returns nil when none of them is set.
+func LoadMasterKey(keyFile string) (*Key, error) {
+	if value := strings.TrimSpace(os.Getenv(KeyEnv)); value != "" {
+		return ParseKey(value)
+	}
+	if path := strings.TrimSpace(os.Getenv(KeyFileEnv)); path != "" {
+		return LoadKeyFile(path)
+	}
+	if keyFile = strings.TrimSpace(keyFile); keyFile != "" {
+		return LoadKeyFile(keyFile)
+	}
+	return nil, nil
+}
+
+// GenerateKey returns a new random master key and its base64 encoding.
+func GenerateKey() (*Key, string, error) {
+	raw := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return nil, "", fmt.Errorf("authcrypt: generate key: %w", err)
+	}
+	return newKey(raw), base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func newKey(raw []byte) *Key {
+	sum := sha256.Sum256(raw)
+	return &Key{id: hex.EncodeToString(sum[:4]), bytes: raw}
+}
+
+// IsSealed reports whether data is a sealed file.
+func IsSealed(data []byte) bool {
+	if !bytes.Contains(data, []byte(envelopeMarker)) {
+		return false
+	}
+	var env envelope
+	return json.Unmarshal(data, &env) == nil && env.Version > 0
+}
+
+// Seal encrypts plaintext stored under the file name with the current master
+// key. Only the base of name is used, so the file can move between directories
+// but not be renamed. It returns plaintext unchanged when encryption is disabled.
+func Seal(name string, plaintext []byte) ([]byte, error) {
+	mu.RLock()
+	key := current
+	mu.RUnlock()
+	if key == nil {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("authcrypt: generate data key: %w", err)
+	}
+	wrapped, err := encrypt(key.bytes, dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	nonce, ciphertext, err := encryptParts(dataKey, plaintext, additionalData(name))
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(envelope{
+		Version:    envelopeVersion,
+		Algorithm:  envelopeAlgorithm,
+		KeyID:      key.id,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}, "", "  ")
+}
+
+// Open returns the plaintext of the file name sealed by Seal. Data that is not
+// sealed is returned unchanged, so plaintext files keep loading.
+func Open(name string, data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(envelopeMarker)) {
+		return data, nil
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("authcrypt: malformed envelope: %w", err)
+	}
+	if env.Version == 0 {
+		return data, nil
+	}
+	if env.Version != envelopeVersion || env.Algorithm != envelopeAlgorithm {
+		return nil, fmt.Errorf("authcrypt: unsupported envelope version %d (%s)", env.Version, env.Algorithm)
+	}
+	key, err := lookupKey(env.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(env.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("authcrypt: decode wrapped key: %w", err)
+	}
+	dataKey, err := decrypt(key.bytes, wrapped, nil)
+	if err != nil {
+		return nil, fmt.Errorf("authcrypt: unwrap data key: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("authcrypt: decode nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("authcrypt: decode ciphertext: %w", err)
+	}
+	plaintext, err := decryptParts(dataKey, nonce, ciphertext, additionalData(name))
+	if err != nil {
+		return nil, fmt.Errorf("authcrypt: decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Current reports whether data is stored the way Seal would store it now:
+// sealed with the current key when encryption is enabled, plaintext otherwise.
+func Current(data []byte) bool {
+	mu.RLock()
+	key := current
+	mu.RUnlock()
+	if !IsSealed(data) {
+		return key == nil
+	}
+	if key == nil {
+		return false
+	}
+	var env envelope
+	return json.Unmarshal(data, &env) == nil && env.KeyID == key.id
+}
+
+// WriteFile seals data for path and writes it through a temporary file.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	sealed, err := Seal(path, data)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, sealed, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SaveStorage persists a credential's token storage at path. When encryption is
+// enabled the storage is encoded in memory and only the sealed form reaches disk.
+func SaveStorage(path string, storage baseauth.TokenStorage) error {
+	if !Enabled() {
+		return storage.SaveTokenToFile(path)
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if encoder, ok := storage.(baseauth.TokenEncoder); ok {
+		data, err = encoder.EncodeToken()
+	} else {
+		data, err = json.Marshal(storage)
+	}
+	if err != nil {
+		return fmt.Errorf("authcrypt: encode token storage: %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	misc.LogSavingCredentials(path)
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return WriteFile(path, data, 0o600)
+}
+
+func lookupKey(id string) (*Key, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if current == nil && len(previous) == 0 {
+		return nil, ErrNoKey
+	}
+	if current != nil && current.id == id {
+		return current, nil
+	}
+	for _, key := range previous {
+		if key.id == id {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w (key id %s)", ErrUnknownKey, id)
+}
+
+// encrypt returns nonce||ciphertext.
+func encrypt(key, plaintext, aad []byte) ([]byte, error) {
+	nonce, ciphertext, err := encryptParts(key, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, ciphertext...), nil
+}
+
+func decrypt(key, sealed, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+}
+
+func encryptParts(key, plaintext, aad []byte) ([]byte, []byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("authcrypt: generate nonce: %w", err)
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func decryptParts(key, nonce, ciphertext, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("authcrypt: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
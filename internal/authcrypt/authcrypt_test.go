@@ -0,0 +1,223 @@
+package authcrypt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestKey(t *testing.T) *Key {
+	t.Helper()
+	key, _, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestSealOpen(t *testing.T) {
+	key := newTestKey(t)
+	other := newTestKey(t)
+	t.Cleanup(func() { SetKeys(nil) })
+
+	plaintext := []byte(`{"type":"claude","access_token":"secret"}`)
+
+	testCases := []struct {
+		name     string
+		sealKeys []*Key
+		openKeys []*Key
+		sealName string
+		openName string
+		wantErr  error
+		wantAny  bool
+	}{
+		{
+			name:     "same key and name",
+			sealKeys: []*Key{key},
+			openKeys: []*Key{key},
+			sealName: "claude-user.json",
+			openName: "claude-user.json",
+		},
+		{
+			name:     "moved to another directory",
+			sealKeys: []*Key{key},
+			openKeys: []*Key{key},
+			sealName: "/auths/claude-user.json",
+			openName: "/backup/claude-user.json",
+		},
+		{
+			name:     "renamed file",
+			sealKeys: []*Key{key},
+			openKeys: []*Key{key},
+			sealName: "claude-user.json",
+			openName: "claude-other.json",
+			wantAny:  true,
+		},
+		{
+			name:     "unknown key",
+			sealKeys: []*Key{key},
+			openKeys: []*Key{other},
+			sealName: "claude-user.json",
+			openName: "claude-user.json",
+			wantErr:  ErrUnknownKey,
+		},
+		{
+			name:     "no key configured",
+			sealKeys: []*Key{key},
+			openKeys: nil,
+			sealName: "claude-user.json",
+			openName: "claude-user.json",
+			wantErr:  ErrNoKey,
+		},
+		{
+			name:     "encryption disabled",
+			sealKeys: nil,
+			openKeys: nil,
+			sealName: "claude-user.json",
+			openName: "claude-user.json",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestKeys(tc.sealKeys)
+			sealed, err := Seal(tc.sealName, plaintext)
+			if err != nil {
+				t.Fatalf("Seal() error = %v", err)
+			}
+			if wantSealed := len(tc.sealKeys) > 0; IsSealed(sealed) != wantSealed {
+				t.Fatalf("IsSealed() = %v, want %v", IsSealed(sealed), wantSealed)
+			}
+			if len(tc.sealKeys) > 0 && bytes.Contains(sealed, []byte("secret")) {
+				t.Fatalf("sealed output contains the plaintext: %s", sealed)
+			}
+
+			setTestKeys(tc.openKeys)
+			opened, err := Open(tc.openName, sealed)
+			switch {
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Open() error = %v, want %v", err, tc.wantErr)
+				}
+			case tc.wantAny:
+				if err == nil {
+					t.Fatalf("Open() succeeded, want an error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				if !bytes.Equal(opened, plaintext) {
+					t.Fatalf("Open() = %s, want %s", opened, plaintext)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenRejectsMalformedEnvelope(t *testing.T) {
+	t.Cleanup(func() { SetKeys(nil) })
+	SetKeys(newTestKey(t))
+
+	testCases := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "truncated envelope",
+			data:    `{` + envelopeMarker + `: 1, "ciphertext": "`,
+			wantErr: "malformed envelope",
+		},
+		{
+			name:    "unsupported version",
+			data:    `{` + envelopeMarker + `: 99, "alg": "other"}`,
+			wantErr: "unsupported envelope version",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Open("claude-user.json", []byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Open() error = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	oldKey := newTestKey(t)
+	nextKey := newTestKey(t)
+	t.Cleanup(func() { SetKeys(nil) })
+
+	plaintext := []byte(`{"type":"codex"}`)
+	SetKeys(oldKey)
+	sealed, err := Seal("codex.json", plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !Current(sealed) {
+		t.Fatalf("Current() = false for a file sealed with the current key")
+	}
+
+	SetKeys(nextKey, oldKey)
+	if Current(sealed) {
+		t.Fatalf("Current() = true for a file sealed with a previous key")
+	}
+	opened, err := Open("codex.json", sealed)
+	if err != nil {
+		t.Fatalf("Open() with the previous key error = %v", err)
+	}
+	resealed, err := Seal("codex.json", opened)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !Current(resealed) {
+		t.Fatalf("Current() = false after resealing with the new key")
+	}
+
+	SetKeys(nextKey)
+	if _, err = Open("codex.json", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Open() of a file sealed with a retired key error = %v, want %v", err, ErrUnknownKey)
+	}
+	if opened, err = Open("codex.json", resealed); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open() after rotation = %s, %v", opened, err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{name: "hex", encoded: strings.Repeat("ab", KeySize)},
+		{name: "base64", encoded: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+		{name: "surrounding whitespace", encoded: "  " + strings.Repeat("01", KeySize) + "\n"},
+		{name: "empty", encoded: "", wantErr: true},
+		{name: "too short", encoded: strings.Repeat("ab", KeySize-1), wantErr: true},
+		{name: "not encoded", encoded: "not a key", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := ParseKey(tc.encoded)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseKey() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && key.ID() == "" {
+				t.Fatalf("ParseKey() returned a key without an ID")
+			}
+		})
+	}
+}
+
+func setTestKeys(keys []*Key) {
+	if len(keys) == 0 {
+		SetKeys(nil)
+		return
+	}
+	SetKeys(keys[0], keys[1:]...)
+}
@@ -0,0 +1,562 @@
+// Package batch emulates the Anthropic Message Batches API and the OpenAI Batch API
+// locally. Jobs and their results are persisted on disk and their requests are
+// executed asynchronously with a process-wide concurrency cap; unfinished jobs are
+// resumed when the process restarts.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Kinds of batch jobs, named after the API that created them.
+const (
+	KindAnthropic = "anthropic"
+	KindOpenAI    = "openai"
+)
+
+// Processing states of a job.
+const (
+	StatusInProgress = "in_progress"
+	StatusCanceling  = "canceling"
+	StatusEnded      = "ended"
+)
+
+// Result types of a single request.
+const (
+	ResultSucceeded = "succeeded"
+	ResultErrored   = "errored"
+	ResultCanceled  = "canceled"
+	ResultExpired   = "expired"
+)
+
+// Limits and defaults.
+const (
+	MaxRequests        = 100000
+	DefaultConcurrency = 8
+	DefaultRetention   = 29 * 24 * time.Hour
+	// ProcessingWindow is how long a job may run before its remaining requests expire.
+	ProcessingWindow = 24 * time.Hour
+)
+
+// ErrNotFound is returned for unknown jobs or files, or ones owned by another client.
+var ErrNotFound = errors.New("batch: not found")
+
+// ErrNotEnded is returned when an operation requires a finished job.
+var ErrNotEnded = errors.New("batch: job has not ended")
+
+// RequestCounts tallies the requests of a job by outcome.
+type RequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// Job is a submitted batch. Counts are derived from the stored results.
+type Job struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Owner is util.HashAPIKey of OwnerKey; only the hash is persisted.
+	Owner string `json:"owner,omitempty"`
+	// OwnerKey is the client API key that created the job. It is kept in memory
+	// only, so it is empty for jobs resumed after a restart.
+	OwnerKey string `json:"-"`
+	Endpoint string `json:"endpoint"`
+	Status   string `json:"status"`
+	Total    int    `json:"total"`
+
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	CancelInitiatedAt *time.Time `json:"cancel_initiated_at,omitempty"`
+	EndedAt           *time.Time `json:"ended_at,omitempty"`
+
+	// OpenAI batch fields.
+	InputFileID      string            `json:"input_file_id,omitempty"`
+	OutputFileID     string            `json:"output_file_id,omitempty"`
+	ErrorFileID      string            `json:"error_file_id,omitempty"`
+	CompletionWindow string            `json:"completion_window,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+
+	Counts RequestCounts `json:"-"`
+}
+
+// Request is one item of a batch.
+type Request struct {
+	CustomID string          `json:"custom_id"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// Result is the outcome of one request. Body holds the upstream response for
+// succeeded requests; Error holds the error message otherwise.
+type Result struct {
+	CustomID   string          `json:"custom_id"`
+	Type       string          `json:"type"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Executor runs a single request of job and reports its outcome. ctx is cancelled
+// when the job is canceled and expires with the job.
+type Executor func(ctx context.Context, job Job, req Request) Result
+
+// Manager owns batch jobs and the files they read and produce.
+type Manager struct {
+	mu          sync.Mutex
+	storage     *storage
+	jobs        map[string]*jobState
+	files       *FileStore
+	executor    Executor
+	concurrency int
+	slots       chan struct{}
+	started     bool
+}
+
+type jobState struct {
+	job     Job
+	results map[string]struct{}
+	cancel  context.CancelFunc
+	mu      sync.Mutex
+}
+
+// NewManager creates an in-memory manager. Call Configure to persist jobs.
+func NewManager() *Manager {
+	m := &Manager{
+		storage: &storage{},
+		jobs:    make(map[string]*jobState),
+	}
+	m.files = &FileStore{storage: m.storage, files: make(map[string]File)}
+	m.setConcurrency(0)
+	return m
+}
+
+var defaultManager = NewManager()
+
+// DefaultManager returns the process-wide batch manager.
+func DefaultManager() *Manager { return defaultManager }
+
+// Files returns the file store used for OpenAI batch input and output files.
+func (m *Manager) Files() *FileStore { return m.files }
+
+// Configure persists jobs and files under dir and loads the ones already there.
+// An empty dir keeps everything in memory. concurrency caps the requests executed
+// at once across all jobs; zero uses DefaultConcurrency.
+func (m *Manager) Configure(dir string, concurrency int) error {
+	m.setConcurrency(concurrency)
+	if dir == "" {
+		return nil
+	}
+	s, err := newStorage(dir)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.loadJobs()
+	if err != nil {
+		return err
+	}
+	files, err := s.loadFiles()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storage = s
+	m.files.replace(s, files)
+	for _, state := range jobs {
+		m.jobs[state.job.ID] = state
+	}
+	return nil
+}
+
+// Start sets the executor and, on the first call, resumes unfinished jobs and
+// prunes jobs past their retention.
+func (m *Manager) Start(executor Executor) {
+	m.mu.Lock()
+	m.executor = executor
+	if m.started {
+		m.mu.Unlock()
+		return
+	}
+	m.started = true
+	var resume []*jobState
+	for _, state := range m.jobs {
+		if state.job.Status != StatusEnded {
+			resume = append(resume, state)
+		}
+	}
+	m.mu.Unlock()
+	m.prune()
+	for _, state := range resume {
+		log.Infof("batch: resuming job %s (%d/%d requests done)", state.job.ID, len(state.results), state.job.Total)
+		go m.run(state)
+	}
+}
+
+// Create stores a new job and starts executing it.
+func (m *Manager) Create(job Job, requests []Request) (Job, error) {
+	if len(requests) == 0 {
+		return Job{}, errors.New("batch: requests must not be empty")
+	}
+	if len(requests) > MaxRequests {
+		return Job{}, errors.New("batch: too many requests")
+	}
+	prefix := "batch_"
+	if job.Kind == KindAnthropic {
+		prefix = "msgbatch_"
+	}
+	job.ID = newID(prefix)
+	job.Owner = util.HashAPIKey(job.OwnerKey)
+	job.Status = StatusInProgress
+	job.Total = len(requests)
+	job.CreatedAt = time.Now().UTC()
+	job.ExpiresAt = job.CreatedAt.Add(ProcessingWindow)
+	job.Counts = RequestCounts{Processing: len(requests)}
+
+	m.mu.Lock()
+	s := m.storage
+	m.mu.Unlock()
+	if err := s.saveRequests(job.ID, requests); err != nil {
+		return Job{}, err
+	}
+	if err := s.saveJob(job); err != nil {
+		return Job{}, err
+	}
+	state := &jobState{job: job, results: make(map[string]struct{})}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = state
+	started := m.started
+	m.mu.Unlock()
+	if started {
+		go m.run(state)
+	}
+	go m.prune()
+	return job, nil
+}
+
+// Get returns the job with id if viewer may see it.
+func (m *Manager) Get(id string, viewer Viewer) (Job, error) {
+	state, err := m.lookup(id, viewer)
+	if err != nil {
+		return Job{}, err
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.job, nil
+}
+
+// List returns the jobs of kind viewer may see, newest first.
+func (m *Manager) List(kind string, viewer Viewer) []Job {
+	m.mu.Lock()
+	states := make([]*jobState, 0, len(m.jobs))
+	for _, state := range m.jobs {
+		states = append(states, state)
+	}
+	m.mu.Unlock()
+	jobs := make([]Job, 0, len(states))
+	for _, state := range states {
+		state.mu.Lock()
+		job := state.job
+		state.mu.Unlock()
+		if job.Kind == kind && viewer.owns(job.Owner) {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].ID > jobs[j].ID
+		}
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Cancel asks a running job to stop; requests not yet started are marked canceled.
+func (m *Manager) Cancel(id string, viewer Viewer) (Job, error) {
+	state, err := m.lookup(id, viewer)
+	if err != nil {
+		return Job{}, err
+	}
+	s := m.currentStorage()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.job.Status != StatusInProgress {
+		return state.job, nil
+	}
+	now := time.Now().UTC()
+	state.job.Status = StatusCanceling
+	state.job.CancelInitiatedAt = &now
+	if state.cancel != nil {
+		state.cancel()
+	}
+	if errSave := s.saveJob(state.job); errSave != nil {
+		log.Warnf("batch: save job %s: %v", id, errSave)
+	}
+	return state.job, nil
+}
+
+// Delete removes an ended job and its results.
+func (m *Manager) Delete(id string, viewer Viewer) error {
+	state, err := m.lookup(id, viewer)
+	if err != nil {
+		return err
+	}
+	state.mu.Lock()
+	ended := state.job.Status == StatusEnded
+	state.mu.Unlock()
+	if !ended {
+		return ErrNotEnded
+	}
+	m.mu.Lock()
+	delete(m.jobs, id)
+	s := m.storage
+	m.mu.Unlock()
+	return s.deleteJob(id)
+}
+
+// Results returns the results recorded so far for the job.
+func (m *Manager) Results(id string, viewer Viewer) (Job, []Result, error) {
+	state, err := m.lookup(id, viewer)
+	if err != nil {
+		return Job{}, nil, err
+	}
+	state.mu.Lock()
+	job := state.job
+	state.mu.Unlock()
+	results, err := m.currentStorage().loadResults(id)
+	return job, results, err
+}
+
+func (m *Manager) lookup(id string, viewer Viewer) (*jobState, error) {
+	m.mu.Lock()
+	state, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	state.mu.Lock()
+	jobOwner := state.job.Owner
+	state.mu.Unlock()
+	if !viewer.owns(jobOwner) {
+		return nil, ErrNotFound
+	}
+	return state, nil
+}
+
+func (m *Manager) currentStorage() *storage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.storage
+}
+
+func (m *Manager) setConcurrency(concurrency int) {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.concurrency == concurrency {
+		return
+	}
+	// Running jobs keep the channel they captured; new dispatches use the new cap.
+	m.concurrency = concurrency
+	m.slots = make(chan struct{}, concurrency)
+}
+
+// run executes the pending requests of a job and finalizes it.
+func (m *Manager) run(state *jobState) {
+	s := m.currentStorage()
+	requests, err := s.loadRequests(state.job.ID)
+	if err != nil {
+		log.Errorf("batch: load requests of %s: %v", state.job.ID, err)
+		return
+	}
+	state.mu.Lock()
+	ctx, cancel := context.WithDeadline(context.Background(), state.job.ExpiresAt)
+	state.cancel = cancel
+	state.mu.Unlock()
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, req := range requests {
+		state.mu.Lock()
+		_, done := state.results[req.CustomID]
+		state.mu.Unlock()
+		if done {
+			continue
+		}
+		m.mu.Lock()
+		slots, executor := m.slots, m.executor
+		m.mu.Unlock()
+		slots <- struct{}{}
+		// Waiting for a slot can take a while; re-check the job before dispatching.
+		state.mu.Lock()
+		job := state.job
+		state.mu.Unlock()
+		skip := ""
+		switch {
+		case job.Status == StatusCanceling:
+			skip = ResultCanceled
+		case time.Now().After(job.ExpiresAt):
+			skip = ResultExpired
+		}
+		if skip != "" {
+			<-slots
+			m.record(state, Result{CustomID: req.CustomID, Type: skip})
+			continue
+		}
+		wg.Add(1)
+		go func(req Request) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			result := executor(ctx, job, req)
+			result.CustomID = req.CustomID
+			m.record(state, result)
+		}(req)
+	}
+	wg.Wait()
+	m.finish(state)
+}
+
+// record appends a result and updates the job counters.
+func (m *Manager) record(state *jobState, result Result) {
+	s := m.currentStorage()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if _, dup := state.results[result.CustomID]; dup {
+		return
+	}
+	if err := s.appendResult(state.job.ID, result); err != nil {
+		log.Errorf("batch: store result %s/%s: %v", state.job.ID, result.CustomID, err)
+	}
+	state.results[result.CustomID] = struct{}{}
+	countResult(&state.job.Counts, result.Type)
+}
+
+func (m *Manager) finish(state *jobState) {
+	s := m.currentStorage()
+	var results []Result
+	if state.job.Kind == KindOpenAI {
+		loaded, err := s.loadResults(state.job.ID)
+		if err != nil {
+			log.Errorf("batch: load results of %s: %v", state.job.ID, err)
+		}
+		results = loaded
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	now := time.Now().UTC()
+	state.job.Status = StatusEnded
+	state.job.EndedAt = &now
+	if state.job.Kind == KindOpenAI {
+		m.writeOpenAIFiles(&state.job, results)
+	}
+	if err := s.saveJob(state.job); err != nil {
+		log.Errorf("batch: save job %s: %v", state.job.ID, err)
+	}
+	log.Debugf("batch: job %s ended (%d succeeded, %d errored, %d canceled, %d expired)", state.job.ID,
+		state.job.Counts.Succeeded, state.job.Counts.Errored, state.job.Counts.Canceled, state.job.Counts.Expired)
+}
+
+// writeOpenAIFiles publishes the output and error files of an ended OpenAI job.
+func (m *Manager) writeOpenAIFiles(job *Job, results []Result) {
+	var output, errorsOut bytes.Buffer
+	for _, result := range results {
+		line := OpenAIResultLine(result)
+		if result.Type == ResultSucceeded {
+			output.Write(line)
+			output.WriteByte('\n')
+		} else {
+			errorsOut.Write(line)
+			errorsOut.WriteByte('\n')
+		}
+	}
+	if output.Len() > 0 {
+		if file, err := m.files.Create(job.Owner, job.ID+"_output.jsonl", PurposeBatchOutput, output.Bytes()); err == nil {
+			job.OutputFileID = file.ID
+		} else {
+			log.Errorf("batch: write output file of %s: %v", job.ID, err)
+		}
+	}
+	if errorsOut.Len() > 0 {
+		if file, err := m.files.Create(job.Owner, job.ID+"_error.jsonl", PurposeBatchOutput, errorsOut.Bytes()); err == nil {
+			job.ErrorFileID = file.ID
+		} else {
+			log.Errorf("batch: write error file of %s: %v", job.ID, err)
+		}
+	}
+}
+
+// prune deletes ended jobs past the retention period.
+func (m *Manager) prune() {
+	cutoff := time.Now().Add(-DefaultRetention)
+	m.mu.Lock()
+	var expired []string
+	for id, state := range m.jobs {
+		state.mu.Lock()
+		if state.job.EndedAt != nil && state.job.EndedAt.Before(cutoff) {
+			expired = append(expired, id)
+		}
+		state.mu.Unlock()
+	}
+	for _, id := range expired {
+		delete(m.jobs, id)
+	}
+	s := m.storage
+	m.mu.Unlock()
+	for _, id := range expired {
+		if err := s.deleteJob(id); err != nil {
+			log.Warnf("batch: prune job %s: %v", id, err)
+		}
+	}
+}
+
+func countResult(counts *RequestCounts, resultType string) {
+	counts.Processing--
+	switch resultType {
+	case ResultSucceeded:
+		counts.Succeeded++
+	case ResultCanceled:
+		counts.Canceled++
+	case ResultExpired:
+		counts.Expired++
+	default:
+		counts.Errored++
+	}
+}
+
+// Viewer identifies the client accessing jobs and files. Each record belongs to the
+// API key that created it; records created without a key are visible only to keys
+// with the admin role, or to every client when no API keys are configured.
+type Viewer struct {
+	// Key is the client API key, empty when the request was not authenticated.
+	Key string
+	// Admin is set when Key has the admin role.
+	Admin bool
+}
+
+// Owner returns the owner stored with records the viewer creates, a hash of Key.
+func (v Viewer) Owner() string {
+	return util.HashAPIKey(v.Key)
+}
+
+func (v Viewer) owns(owner string) bool {
+	return owner == v.Owner() || (owner == "" && v.Admin)
+}
+
+func newID(prefix string) string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return prefix + hex.EncodeToString(buf)
+}
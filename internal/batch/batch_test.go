@@ -0,0 +1,310 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+// recordingExecutor succeeds for every request except the custom IDs in fail, and
+// remembers the requests and jobs it ran.
+type recordingExecutor struct {
+	fail map[string]bool
+
+	mu   sync.Mutex
+	ran  []string
+	jobs []Job
+}
+
+func (e *recordingExecutor) execute(_ context.Context, job Job, req Request) Result {
+	e.mu.Lock()
+	e.ran = append(e.ran, req.CustomID)
+	e.jobs = append(e.jobs, job)
+	e.mu.Unlock()
+	if e.fail[req.CustomID] {
+		return Result{Type: ResultErrored, StatusCode: 400, Error: "bad request"}
+	}
+	return Result{Type: ResultSucceeded, StatusCode: 200, Body: []byte(`{"id":"` + req.CustomID + `"}`)}
+}
+
+func (e *recordingExecutor) ranIDs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := append([]string(nil), e.ran...)
+	sort.Strings(ids)
+	return ids
+}
+
+func testRequests(ids ...string) []Request {
+	requests := make([]Request, 0, len(ids))
+	for _, id := range ids {
+		requests = append(requests, Request{CustomID: id, Body: []byte(`{"model":"gpt-5"}`)})
+	}
+	return requests
+}
+
+// waitEnded polls the job until it ends.
+func waitEnded(t *testing.T, m *Manager, id string, viewer Viewer) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(id, viewer)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", id, err)
+		}
+		if job.Status == StatusEnded {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not end, status %s", id, job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func resultTypes(t *testing.T, m *Manager, id string, viewer Viewer) map[string]string {
+	t.Helper()
+	_, results, err := m.Results(id, viewer)
+	if err != nil {
+		t.Fatalf("Results(%s) error = %v", id, err)
+	}
+	types := make(map[string]string, len(results))
+	for _, result := range results {
+		types[result.CustomID] = result.Type
+	}
+	return types
+}
+
+func TestManagerResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	owner := Viewer{Key: "sk-batch-owner"}
+
+	// The first process accepts the job and finishes one request before it stops.
+	first := NewManager()
+	if err := first.Configure(dir, 1); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	job, err := first.Create(Job{Kind: KindAnthropic, OwnerKey: owner.Key, Endpoint: "/v1/messages"}, testRequests("req-1", "req-2", "req-3"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	first.record(first.jobs[job.ID], Result{CustomID: "req-1", Type: ResultSucceeded, Body: []byte(`{}`)})
+
+	data, err := os.ReadFile(filepath.Join(dir, "jobs", job.ID, "job.json"))
+	if err != nil {
+		t.Fatalf("read job.json: %v", err)
+	}
+	if strings.Contains(string(data), owner.Key) {
+		t.Fatalf("job.json stores the owner key: %s", data)
+	}
+
+	second := NewManager()
+	if err = second.Configure(dir, 1); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	executor := &recordingExecutor{}
+	second.Start(executor.execute)
+	ended := waitEnded(t, second, job.ID, owner)
+
+	if got := strings.Join(executor.ranIDs(), ","); got != "req-2,req-3" {
+		t.Fatalf("resumed job ran %s, want only the unfinished req-2,req-3", got)
+	}
+	for _, ran := range executor.jobs {
+		if ran.OwnerKey != "" || ran.Owner != util.HashAPIKey(owner.Key) {
+			t.Fatalf("resumed job owner = %q/%q, want only the key hash", ran.Owner, ran.OwnerKey)
+		}
+	}
+	if ended.Counts.Succeeded != 3 || ended.Counts.Processing != 0 {
+		t.Fatalf("counts = %+v, want 3 succeeded", ended.Counts)
+	}
+	if types := resultTypes(t, second, job.ID, owner); len(types) != 3 {
+		t.Fatalf("results = %v, want one per request", types)
+	}
+}
+
+func TestManagerEndsUnfinishedRequests(t *testing.T) {
+	testCases := []struct {
+		name string
+		// stop ends the job while its first request is running.
+		stop       func(m *Manager, id string, viewer Viewer) error
+		wantType   string
+		wantCancel bool
+	}{
+		{
+			name: "cancel",
+			stop: func(m *Manager, id string, viewer Viewer) error {
+				_, err := m.Cancel(id, viewer)
+				return err
+			},
+			wantType:   ResultCanceled,
+			wantCancel: true,
+		},
+		{
+			name: "expiry",
+			stop: func(m *Manager, id string, _ Viewer) error {
+				m.mu.Lock()
+				state := m.jobs[id]
+				m.mu.Unlock()
+				state.mu.Lock()
+				state.job.ExpiresAt = time.Now().Add(-time.Second)
+				state.mu.Unlock()
+				return nil
+			},
+			wantType: ResultExpired,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viewer := Viewer{Key: "sk-" + tc.name}
+			m := NewManager()
+			if err := m.Configure(t.TempDir(), 1); err != nil {
+				t.Fatalf("Configure() error = %v", err)
+			}
+			started := make(chan struct{})
+			release := make(chan struct{})
+			var once sync.Once
+			m.Start(func(ctx context.Context, _ Job, _ Request) Result {
+				once.Do(func() { close(started) })
+				select {
+				case <-ctx.Done():
+				case <-release:
+				}
+				return Result{Type: tc.wantType}
+			})
+			job, err := m.Create(Job{Kind: KindAnthropic, OwnerKey: viewer.Key, Endpoint: "/v1/messages"}, testRequests("req-1", "req-2", "req-3"))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			<-started
+			if err = tc.stop(m, job.ID, viewer); err != nil {
+				t.Fatalf("stopping the job: %v", err)
+			}
+			close(release)
+
+			ended := waitEnded(t, m, job.ID, viewer)
+			for id, resultType := range resultTypes(t, m, job.ID, viewer) {
+				if resultType != tc.wantType {
+					t.Fatalf("request %s ended %s, want %s", id, resultType, tc.wantType)
+				}
+			}
+			counts := RequestCounts{Canceled: 3}
+			if tc.wantType == ResultExpired {
+				counts = RequestCounts{Expired: 3}
+			}
+			if ended.Counts != counts {
+				t.Fatalf("counts = %+v, want %+v", ended.Counts, counts)
+			}
+			if (ended.CancelInitiatedAt != nil) != tc.wantCancel {
+				t.Fatalf("CancelInitiatedAt = %v, want set %v", ended.CancelInitiatedAt, tc.wantCancel)
+			}
+		})
+	}
+}
+
+func TestOpenAIJobSplitsOutputAndErrorFiles(t *testing.T) {
+	viewer := Viewer{Key: "sk-openai-owner"}
+	m := NewManager()
+	executor := &recordingExecutor{fail: map[string]bool{"bad": true}}
+	m.Start(executor.execute)
+	job, err := m.Create(Job{Kind: KindOpenAI, OwnerKey: viewer.Key, Endpoint: "/v1/chat/completions"}, testRequests("ok-1", "bad", "ok-2"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	ended := waitEnded(t, m, job.ID, viewer)
+
+	testCases := []struct {
+		name   string
+		fileID string
+		want   []string
+	}{
+		{name: "output file", fileID: ended.OutputFileID, want: []string{"ok-1", "ok-2"}},
+		{name: "error file", fileID: ended.ErrorFileID, want: []string{"bad"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.fileID == "" {
+				t.Fatalf("job has no %s", tc.name)
+			}
+			file, data, errContent := m.Files().Content(tc.fileID, viewer)
+			if errContent != nil {
+				t.Fatalf("Content() error = %v", errContent)
+			}
+			if file.Purpose != PurposeBatchOutput || file.Owner != viewer.Owner() {
+				t.Fatalf("file = %+v, want a batch_output file owned by the job owner", file)
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				got = append(got, gjson.Get(line, "custom_id").String())
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("%s holds %v, want %v", tc.name, got, tc.want)
+			}
+		})
+	}
+	if _, _, err = m.Files().Content(ended.OutputFileID, Viewer{Key: "sk-other"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("another client read the output file: %v", err)
+	}
+}
+
+func TestOwnerIsolation(t *testing.T) {
+	m := NewManager()
+	owned, err := m.Create(Job{Kind: KindAnthropic, OwnerKey: "sk-owner", Endpoint: "/v1/messages"}, testRequests("req-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	unowned, err := m.Create(Job{Kind: KindAnthropic, Endpoint: "/v1/messages"}, testRequests("req-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	file, err := m.Files().Create(Viewer{Key: "sk-owner"}.Owner(), "input.jsonl", PurposeBatch, []byte("{}\n"))
+	if err != nil {
+		t.Fatalf("Files().Create() error = %v", err)
+	}
+	if owned.Owner != util.HashAPIKey("sk-owner") || file.Owner != owned.Owner {
+		t.Fatalf("owners = %q/%q, want the key hash", owned.Owner, file.Owner)
+	}
+
+	testCases := []struct {
+		name        string
+		viewer      Viewer
+		wantOwned   bool
+		wantUnowned bool
+	}{
+		{name: "owner", viewer: Viewer{Key: "sk-owner"}, wantOwned: true},
+		{name: "other client", viewer: Viewer{Key: "sk-other"}},
+		{name: "admin of another key", viewer: Viewer{Key: "sk-admin", Admin: true}, wantUnowned: true},
+		{name: "no keys configured", viewer: Viewer{}, wantUnowned: true},
+		{name: "owner hash used as a key", viewer: Viewer{Key: owned.Owner}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			visible := make(map[string]bool)
+			for _, job := range m.List(KindAnthropic, tc.viewer) {
+				visible[job.ID] = true
+			}
+			if visible[owned.ID] != tc.wantOwned || visible[unowned.ID] != tc.wantUnowned {
+				t.Fatalf("List() = %v, want owned %v unowned %v", visible, tc.wantOwned, tc.wantUnowned)
+			}
+			if _, errGet := m.Get(owned.ID, tc.viewer); (errGet == nil) != tc.wantOwned {
+				t.Fatalf("Get(owned) error = %v, want visible %v", errGet, tc.wantOwned)
+			}
+			if _, errCancel := m.Cancel(owned.ID, tc.viewer); !tc.wantOwned && !errors.Is(errCancel, ErrNotFound) {
+				t.Fatalf("Cancel(owned) error = %v, want ErrNotFound", errCancel)
+			}
+			if _, errFile := m.Files().Get(file.ID, tc.viewer); (errFile == nil) != tc.wantOwned {
+				t.Fatalf("Files().Get() error = %v, want visible %v", errFile, tc.wantOwned)
+			}
+		})
+	}
+}
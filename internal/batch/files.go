@@ -0,0 +1,114 @@
+package batch
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Purposes of stored files.
+const (
+	PurposeBatch       = "batch"
+	PurposeBatchOutput = "batch_output"
+)
+
+// File describes an uploaded or generated file.
+type File struct {
+	ID        string `json:"id"`
+	Owner     string `json:"owner,omitempty"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// FileStore keeps the files backing OpenAI batches.
+type FileStore struct {
+	mu      sync.Mutex
+	storage *storage
+	files   map[string]File
+}
+
+func (fs *FileStore) replace(s *storage, files map[string]File) {
+	fs.mu.Lock()
+	fs.storage = s
+	fs.files = files
+	fs.mu.Unlock()
+}
+
+// Create stores data as a new file owned by owner, a Viewer.Owner value.
+func (fs *FileStore) Create(owner, filename, purpose string, data []byte) (File, error) {
+	file := File{
+		ID:        newID("file-"),
+		Owner:     owner,
+		Filename:  filename,
+		Purpose:   purpose,
+		Bytes:     len(data),
+		CreatedAt: time.Now().Unix(),
+	}
+	fs.mu.Lock()
+	s := fs.storage
+	fs.mu.Unlock()
+	if err := s.saveFile(file, data); err != nil {
+		return File{}, err
+	}
+	fs.mu.Lock()
+	fs.files[file.ID] = file
+	fs.mu.Unlock()
+	return file, nil
+}
+
+// Get returns the file with id if viewer may see it.
+func (fs *FileStore) Get(id string, viewer Viewer) (File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	file, ok := fs.files[id]
+	if !ok || !viewer.owns(file.Owner) {
+		return File{}, ErrNotFound
+	}
+	return file, nil
+}
+
+// Content returns the content of the file with id if viewer may see it.
+func (fs *FileStore) Content(id string, viewer Viewer) (File, []byte, error) {
+	file, err := fs.Get(id, viewer)
+	if err != nil {
+		return File{}, nil, err
+	}
+	fs.mu.Lock()
+	s := fs.storage
+	fs.mu.Unlock()
+	data, err := s.loadFileData(id)
+	return file, data, err
+}
+
+// List returns the files viewer may see, newest first, optionally filtered by purpose.
+func (fs *FileStore) List(viewer Viewer, purpose string) []File {
+	fs.mu.Lock()
+	files := make([]File, 0, len(fs.files))
+	for _, file := range fs.files {
+		if viewer.owns(file.Owner) && (purpose == "" || file.Purpose == purpose) {
+			files = append(files, file)
+		}
+	}
+	fs.mu.Unlock()
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].CreatedAt == files[j].CreatedAt {
+			return files[i].ID > files[j].ID
+		}
+		return files[i].CreatedAt > files[j].CreatedAt
+	})
+	return files
+}
+
+// Delete removes the file with id if viewer may see it.
+func (fs *FileStore) Delete(id string, viewer Viewer) error {
+	if _, err := fs.Get(id, viewer); err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	delete(fs.files, id)
+	s := fs.storage
+	fs.mu.Unlock()
+	return s.deleteFile(id)
+}
@@ -0,0 +1,203 @@
+package batch
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// AnthropicBatch renders job as an Anthropic message batch object. resultsURL is
+// reported once the job has ended.
+func AnthropicBatch(job Job, resultsURL string) map[string]any {
+	out := map[string]any{
+		"id":                job.ID,
+		"type":              "message_batch",
+		"processing_status": job.Status,
+		"request_counts":    job.Counts,
+		"created_at":        job.CreatedAt.Format(time.RFC3339Nano),
+		"expires_at":        job.ExpiresAt.Format(time.RFC3339Nano),
+		"ended_at":          formatTime(job.EndedAt),
+		// Deleting a batch removes it outright, so nothing is ever archived.
+		"archived_at":         nil,
+		"cancel_initiated_at": formatTime(job.CancelInitiatedAt),
+		"results_url":         nil,
+	}
+	if job.Status == StatusEnded {
+		out["results_url"] = resultsURL
+	}
+	return out
+}
+
+// AnthropicResultLine renders result as one line of a message batch results file.
+func AnthropicResultLine(result Result) []byte {
+	out := []byte(`{"custom_id":"","result":{"type":""}}`)
+	out, _ = sjson.SetBytes(out, "custom_id", result.CustomID)
+	out, _ = sjson.SetBytes(out, "result.type", result.Type)
+	switch result.Type {
+	case ResultSucceeded:
+		out, _ = sjson.SetRawBytes(out, "result.message", result.Body)
+	case ResultErrored:
+		body := []byte(result.Error)
+		if !gjson.ValidBytes(body) || gjson.GetBytes(body, "type").String() != "error" {
+			body = []byte(`{"type":"error","error":{"type":"","message":""}}`)
+			body, _ = sjson.SetBytes(body, "error.type", anthropicErrorType(result.StatusCode))
+			body, _ = sjson.SetBytes(body, "error.message", errorMessage(result.Error))
+		}
+		out, _ = sjson.SetRawBytes(out, "result.error", body)
+	}
+	return out
+}
+
+// OpenAIBatch renders job as an OpenAI batch object.
+func OpenAIBatch(job Job) map[string]any {
+	out := map[string]any{
+		"id":                job.ID,
+		"object":            "batch",
+		"endpoint":          job.Endpoint,
+		"errors":            nil,
+		"input_file_id":     job.InputFileID,
+		"completion_window": job.CompletionWindow,
+		"status":            openAIStatus(job),
+		"output_file_id":    nullable(job.OutputFileID),
+		"error_file_id":     nullable(job.ErrorFileID),
+		"created_at":        job.CreatedAt.Unix(),
+		"in_progress_at":    job.CreatedAt.Unix(),
+		"expires_at":        job.ExpiresAt.Unix(),
+		"finalizing_at":     nil,
+		"completed_at":      nil,
+		"failed_at":         nil,
+		"expired_at":        nil,
+		"cancelling_at":     nil,
+		"cancelled_at":      nil,
+		"request_counts": map[string]int{
+			"total":     job.Total,
+			"completed": job.Counts.Succeeded,
+			"failed":    job.Counts.Errored,
+		},
+		"metadata": job.Metadata,
+	}
+	if job.CancelInitiatedAt != nil {
+		out["cancelling_at"] = job.CancelInitiatedAt.Unix()
+	}
+	if job.EndedAt != nil {
+		ended := job.EndedAt.Unix()
+		out["finalizing_at"] = ended
+		switch openAIStatus(job) {
+		case "cancelled":
+			out["cancelled_at"] = ended
+		case "expired":
+			out["expired_at"] = ended
+		default:
+			out["completed_at"] = ended
+		}
+	}
+	return out
+}
+
+// OpenAIResultLine renders result as one line of an OpenAI batch output or error file.
+func OpenAIResultLine(result Result) []byte {
+	out := []byte(`{"id":"","custom_id":"","response":null,"error":null}`)
+	out, _ = sjson.SetBytes(out, "id", newID("batch_req_"))
+	out, _ = sjson.SetBytes(out, "custom_id", result.CustomID)
+	switch result.Type {
+	case ResultSucceeded, ResultErrored:
+		response := []byte(`{"status_code":0,"request_id":"","body":{}}`)
+		response, _ = sjson.SetBytes(response, "request_id", newID("req_"))
+		if result.Type == ResultSucceeded {
+			response, _ = sjson.SetBytes(response, "status_code", http.StatusOK)
+			response, _ = sjson.SetRawBytes(response, "body", result.Body)
+		} else {
+			status := result.StatusCode
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			body := []byte(result.Error)
+			if !gjson.ValidBytes(body) || !gjson.GetBytes(body, "error").IsObject() {
+				body = []byte(`{"error":{"message":"","type":""}}`)
+				body, _ = sjson.SetBytes(body, "error.message", errorMessage(result.Error))
+				body, _ = sjson.SetBytes(body, "error.type", openAIErrorType(status))
+			}
+			response, _ = sjson.SetBytes(response, "status_code", status)
+			response, _ = sjson.SetRawBytes(response, "body", body)
+		}
+		out, _ = sjson.SetRawBytes(out, "response", response)
+	case ResultCanceled:
+		out, _ = sjson.SetRawBytes(out, "error", []byte(`{"code":"batch_cancelled","message":"This request was not executed because the batch was cancelled."}`))
+	case ResultExpired:
+		out, _ = sjson.SetRawBytes(out, "error", []byte(`{"code":"batch_expired","message":"This request could not be executed before the completion window expired."}`))
+	}
+	return out
+}
+
+func openAIStatus(job Job) string {
+	switch job.Status {
+	case StatusCanceling:
+		return "cancelling"
+	case StatusEnded:
+		switch {
+		case job.CancelInitiatedAt != nil:
+			return "cancelled"
+		case job.Counts.Expired > 0:
+			return "expired"
+		default:
+			return "completed"
+		}
+	default:
+		return "in_progress"
+	}
+}
+
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+func openAIErrorType(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status == http.StatusUnauthorized:
+		return "authentication_error"
+	case status >= 400 && status < 500:
+		return "invalid_request_error"
+	default:
+		return "server_error"
+	}
+}
+
+func errorMessage(message string) string {
+	if message == "" {
+		return "request failed"
+	}
+	return message
+}
+
+func formatTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
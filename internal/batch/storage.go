@@ -0,0 +1,315 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// storage persists jobs and files below a directory:
+//
+//	jobs/<id>/job.json        job state
+//	jobs/<id>/requests.jsonl  submitted requests
+//	jobs/<id>/results.jsonl   results, appended as requests finish
+//	files/<id>.json           file metadata
+//	files/<id>.data           file content
+//
+// A storage without a directory keeps everything in memory.
+type storage struct {
+	dir string
+
+	mu       sync.Mutex
+	requests map[string][]Request
+	results  map[string][]Result
+	fileData map[string][]byte
+}
+
+func newStorage(dir string) (*storage, error) {
+	for _, sub := range []string{"jobs", "files"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("batch: create directory: %w", err)
+		}
+	}
+	return &storage{dir: dir}, nil
+}
+
+func (s *storage) jobDir(id string) string { return filepath.Join(s.dir, "jobs", id) }
+
+func (s *storage) saveJob(job Job) error {
+	if s.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(s.jobDir(job.ID), 0o700); err != nil {
+		return fmt.Errorf("batch: create job directory: %w", err)
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("batch: marshal job: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(s.jobDir(job.ID), "job.json"), data)
+}
+
+func (s *storage) saveRequests(id string, requests []Request) error {
+	if s.dir == "" {
+		s.mu.Lock()
+		if s.requests == nil {
+			s.requests = make(map[string][]Request)
+		}
+		s.requests[id] = requests
+		s.mu.Unlock()
+		return nil
+	}
+	if err := os.MkdirAll(s.jobDir(id), 0o700); err != nil {
+		return fmt.Errorf("batch: create job directory: %w", err)
+	}
+	var buf bytes.Buffer
+	for _, req := range requests {
+		line, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("batch: marshal request: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return writeFileAtomic(filepath.Join(s.jobDir(id), "requests.jsonl"), buf.Bytes())
+}
+
+func (s *storage) loadRequests(id string) ([]Request, error) {
+	if s.dir == "" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.requests[id], nil
+	}
+	var requests []Request
+	err := readJSONLines(filepath.Join(s.jobDir(id), "requests.jsonl"), func(line []byte) error {
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			return fmt.Errorf("batch: decode request: %w", err)
+		}
+		requests = append(requests, req)
+		return nil
+	})
+	return requests, err
+}
+
+func (s *storage) appendResult(id string, result Result) error {
+	if s.dir == "" {
+		s.mu.Lock()
+		if s.results == nil {
+			s.results = make(map[string][]Result)
+		}
+		s.results[id] = append(s.results[id], result)
+		s.mu.Unlock()
+		return nil
+	}
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("batch: marshal result: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(s.jobDir(id), "results.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+func (s *storage) loadResults(id string) ([]Result, error) {
+	if s.dir == "" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return append([]Result(nil), s.results[id]...), nil
+	}
+	var results []Result
+	seen := make(map[string]struct{})
+	err := readJSONLines(filepath.Join(s.jobDir(id), "results.jsonl"), func(line []byte) error {
+		var result Result
+		// A crash while appending can leave a truncated last line; that request is rerun.
+		if json.Unmarshal(line, &result) != nil || result.CustomID == "" {
+			return nil
+		}
+		if _, dup := seen[result.CustomID]; !dup {
+			seen[result.CustomID] = struct{}{}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return results, err
+}
+
+func (s *storage) deleteJob(id string) error {
+	if s.dir == "" {
+		s.mu.Lock()
+		delete(s.requests, id)
+		delete(s.results, id)
+		s.mu.Unlock()
+		return nil
+	}
+	return os.RemoveAll(s.jobDir(id))
+}
+
+// loadJobs reads every stored job and rebuilds its counters from the results.
+func (s *storage) loadJobs() (map[string]*jobState, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, "jobs"))
+	if err != nil {
+		return nil, fmt.Errorf("batch: list jobs: %w", err)
+	}
+	jobs := make(map[string]*jobState, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, errRead := os.ReadFile(filepath.Join(s.jobDir(entry.Name()), "job.json"))
+		if errRead != nil {
+			log.Warnf("batch: skip job %s: %v", entry.Name(), errRead)
+			continue
+		}
+		var job Job
+		if errDecode := json.Unmarshal(data, &job); errDecode != nil || job.ID != entry.Name() {
+			log.Warnf("batch: skip job %s: invalid job.json", entry.Name())
+			continue
+		}
+		results, errResults := s.loadResults(job.ID)
+		if errResults != nil {
+			log.Warnf("batch: skip job %s: %v", job.ID, errResults)
+			continue
+		}
+		state := &jobState{job: job, results: make(map[string]struct{}, len(results))}
+		state.job.Counts = RequestCounts{Processing: job.Total}
+		for _, result := range results {
+			if _, dup := state.results[result.CustomID]; dup {
+				continue
+			}
+			state.results[result.CustomID] = struct{}{}
+			countResult(&state.job.Counts, result.Type)
+		}
+		jobs[job.ID] = state
+	}
+	return jobs, nil
+}
+
+func (s *storage) saveFile(file File, data []byte) error {
+	if s.dir == "" {
+		s.mu.Lock()
+		if s.fileData == nil {
+			s.fileData = make(map[string][]byte)
+		}
+		s.fileData[file.ID] = data
+		s.mu.Unlock()
+		return nil
+	}
+	meta, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("batch: marshal file: %w", err)
+	}
+	if err = writeFileAtomic(filepath.Join(s.dir, "files", file.ID+".data"), data); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.dir, "files", file.ID+".json"), meta)
+}
+
+func (s *storage) loadFileData(id string) ([]byte, error) {
+	if s.dir == "" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		data, ok := s.fileData[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, "files", id+".data"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *storage) deleteFile(id string) error {
+	if s.dir == "" {
+		s.mu.Lock()
+		delete(s.fileData, id)
+		s.mu.Unlock()
+		return nil
+	}
+	for _, ext := range []string{".json", ".data"} {
+		if err := os.Remove(filepath.Join(s.dir, "files", id+ext)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *storage) loadFiles() (map[string]File, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, "files"))
+	if err != nil {
+		return nil, fmt.Errorf("batch: list files: %w", err)
+	}
+	files := make(map[string]File)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, errRead := os.ReadFile(filepath.Join(s.dir, "files", entry.Name()))
+		if errRead != nil {
+			continue
+		}
+		var file File
+		if json.Unmarshal(data, &file) == nil && file.ID != "" {
+			files[file.ID] = file
+		}
+	}
+	return files, nil
+}
+
+// readJSONLines calls fn for every non-empty line of a JSON Lines file.
+func readJSONLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	reader := bufio.NewReader(f)
+	for {
+		line, errRead := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if errFn := fn(trimmed); errFn != nil {
+				return errFn
+			}
+		}
+		if errRead == io.EOF {
+			return nil
+		}
+		if errRead != nil {
+			return errRead
+		}
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("batch: write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("batch: rename %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Package cmd contains CLI helpers. This file implements exporting credentials to
+// an auth bundle and importing them from one.
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authbundle"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoExportAuths writes every credential in the auth directory to a bundle at
+// outPath, encrypted with CLIPROXY_BUNDLE_PASSPHRASE when it is set.
+func DoExportAuths(cfg *config.Config, outPath string) {
+	entries, err := authbundle.Collect(cfg.AuthDir, nil)
+	if err != nil {
+		log.Fatalf("export-auths: %v", err)
+		return
+	}
+	passphrase := os.Getenv(authbundle.PassphraseEnv)
+	var buf bytes.Buffer
+	if err = authbundle.Write(&buf, entries, passphrase); err != nil {
+		log.Fatalf("export-auths: %v", err)
+		return
+	}
+	if err = os.WriteFile(outPath, buf.Bytes(), 0o600); err != nil {
+		log.Fatalf("export-auths: write bundle: %v", err)
+		return
+	}
+	if passphrase == "" {
+		log.Warnf("export-auths: bundle is not encrypted; set %s to protect it with a passphrase", authbundle.PassphraseEnv)
+	}
+	fmt.Printf("Exported %d auth files to %s\n", len(entries), outPath)
+}
+
+// DoImportAuths saves the credentials in the bundle at bundlePath through the
+// registered token store. Entries are validated by their provider's authenticator;
+// duplicates of existing accounts are skipped.
+func DoImportAuths(cfg *config.Config, bundlePath string) {
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		log.Fatalf("import-auths: read bundle: %v", err)
+		return
+	}
+	entries, err := authbundle.Read(data, os.Getenv(authbundle.PassphraseEnv))
+	if errors.Is(err, authbundle.ErrPassphraseRequired) || errors.Is(err, authbundle.ErrBadPassphrase) {
+		log.Fatalf("import-auths: %v (the passphrase is read from %s)", err, authbundle.PassphraseEnv)
+		return
+	}
+	if err != nil {
+		log.Fatalf("import-auths: %v", err)
+		return
+	}
+
+	store := sdkAuth.GetTokenStore()
+	if setter, ok := store.(interface{ SetBaseDir(string) }); ok {
+		setter.SetBaseDir(cfg.AuthDir)
+	}
+	ctx := context.Background()
+	existing, err := store.List(ctx)
+	if err != nil {
+		log.Fatalf("import-auths: list existing auth files: %v", err)
+		return
+	}
+
+	plan := authbundle.PlanImport(entries, existing, authbundle.DefaultValidator(), false)
+	imported := 0
+	for _, credential := range plan.Credentials {
+		record := &coreauth.Auth{
+			ID:         credential.Name,
+			Provider:   credential.Provider,
+			FileName:   credential.Name,
+			Metadata:   credential.Metadata,
+			Attributes: map[string]string{"path": filepath.Join(cfg.AuthDir, credential.Name)},
+		}
+		if _, errSave := store.Save(ctx, record); errSave != nil {
+			log.Errorf("import-auths: save %s: %v", credential.Name, errSave)
+			continue
+		}
+		imported++
+	}
+	for _, skipped := range plan.Skipped {
+		fmt.Printf("Skipped %s: %s\n", skipped.Name, strings.TrimSpace(skipped.Reason))
+	}
+	fmt.Printf("Imported %d of %d auth files from %s\n", imported, len(entries), bundlePath)
+}
@@ -0,0 +1,89 @@
+// Package cmd contains CLI helpers. This file implements encrypting stored
+// credentials at rest and rotating the master key that protects them.
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authcrypt"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoGenerateAuthKey prints a new random master key for auth-encryption.
+func DoGenerateAuthKey() {
+	key, encoded, err := authcrypt.GenerateKey()
+	if err != nil {
+		log.Fatalf("generate-auth-key: %v", err)
+		return
+	}
+	fmt.Println(encoded)
+	log.Infof("generated auth master key %s; store it in a key file or CLIPROXY_AUTH_KEY", key.ID())
+}
+
+// DoEncryptAuths re-saves every stored credential so that plaintext files are
+// encrypted with the configured master key.
+func DoEncryptAuths(cfg *config.Config) {
+	key := authcrypt.CurrentKey()
+	if key == nil {
+		log.Fatalf("encrypt-auths: no master key configured; set %s, %s or auth-encryption.key-file", authcrypt.KeyEnv, authcrypt.KeyFileEnv)
+		return
+	}
+	count, err := resealAuths(cfg)
+	if err != nil {
+		log.Fatalf("encrypt-auths: %v", err)
+		return
+	}
+	fmt.Printf("Encrypted %d auth files with key %s\n", count, key.ID())
+}
+
+// DoRotateAuthKey re-encrypts every stored credential with the master key in
+// newKeyFile. Files encrypted with the currently configured key, and plaintext
+// files, are both migrated. The server must be restarted with the new key.
+func DoRotateAuthKey(cfg *config.Config, newKeyFile string) {
+	newKey, err := authcrypt.LoadKeyFile(newKeyFile)
+	if err != nil {
+		log.Fatalf("rotate-auth-key: %v", err)
+		return
+	}
+	oldKey := authcrypt.CurrentKey()
+	if oldKey != nil && oldKey.ID() == newKey.ID() {
+		log.Fatalf("rotate-auth-key: %s holds the key already in use", newKeyFile)
+		return
+	}
+	authcrypt.SetKeys(newKey, oldKey)
+	count, err := resealAuths(cfg)
+	if err != nil {
+		log.Fatalf("rotate-auth-key: %v", err)
+		return
+	}
+	fmt.Printf("Re-encrypted %d auth files with key %s\n", count, newKey.ID())
+	fmt.Printf("Point auth-encryption.key-file or %s at %s before restarting the server.\n", authcrypt.KeyFileEnv, newKeyFile)
+}
+
+// resealAuths loads every credential from the registered token store and saves
+// it again, which seals it with the current master key.
+func resealAuths(cfg *config.Config) (int, error) {
+	store := sdkAuth.GetTokenStore()
+	if setter, ok := store.(interface{ SetBaseDir(string) }); ok && cfg != nil {
+		setter.SetBaseDir(cfg.AuthDir)
+	}
+	ctx := context.Background()
+	auths, err := store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list auth files: %w", err)
+	}
+	count := 0
+	for _, auth := range auths {
+		if auth == nil || auth.Metadata == nil {
+			continue
+		}
+		if _, errSave := store.Save(ctx, auth); errSave != nil {
+			return count, fmt.Errorf("save %s: %w", auth.ID, errSave)
+		}
+		count++
+	}
+	return count, nil
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/iflow"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 )
 
 // DoIFlowCookieAuth performs the iFlow cookie-based authentication.
@@ -50,11 +52,33 @@ func DoIFlowCookieAuth(cfg *config.Config, options *LoginOptions) {
 	// Create token storage
 	tokenStorage := auth.CreateCookieTokenStorage(tokenData)
 
-	// Get auth file path using email in filename
-	authFilePath := getAuthFilePath(cfg, "iflow", tokenData.Email)
+	// Name the auth file after the account email
+	fileName := fmt.Sprintf("iflow-%s.json", iflow.SanitizeIFlowFileName(tokenData.Email))
+	record := &coreauth.Auth{
+		ID:       fileName,
+		Provider: "iflow",
+		FileName: fileName,
+		Storage:  tokenStorage,
+		Metadata: map[string]any{
+			"email":        tokenStorage.Email,
+			"api_key":      tokenStorage.APIKey,
+			"expired":      tokenStorage.Expire,
+			"cookie":       tokenStorage.Cookie,
+			"type":         tokenStorage.Type,
+			"last_refresh": tokenStorage.LastRefresh,
+		},
+		Attributes: map[string]string{
+			"api_key": tokenStorage.APIKey,
+		},
+	}
 
-	// Save token to file
-	if err := tokenStorage.SaveTokenToFile(authFilePath); err != nil {
+	// Save through the token store so auth encryption and remote stores apply
+	store := sdkAuth.GetTokenStore()
+	if setter, ok := store.(interface{ SetBaseDir(string) }); ok {
+		setter.SetBaseDir(cfg.AuthDir)
+	}
+	authFilePath, err := store.Save(ctx, record)
+	if err != nil {
 		fmt.Printf("Failed to save authentication: %v\n", err)
 		return
 	}
@@ -78,9 +102,3 @@ func promptForCookie(promptFn func(string) (string, error)) (string, error) {
 
 	return cookie, nil
 }
-
-// getAuthFilePath returns the auth file path for the given provider and email
-func getAuthFilePath(cfg *config.Config, provider, email string) string {
-	fileName := iflow.SanitizeIFlowFileName(email)
-	return fmt.Sprintf("%s/%s-%s.json", cfg.AuthDir, provider, fileName)
-}
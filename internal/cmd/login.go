@@ -57,6 +57,7 @@ func DoLogin(cfg *config.Config, projectID string, options *LoginOptions) {
 
 	loginOpts := &sdkAuth.LoginOptions{
 		NoBrowser: options.NoBrowser,
+		Headless:  options.Headless,
 		ProjectID: strings.TrimSpace(projectID),
 		Metadata:  map[string]string{},
 		Prompt:    options.Prompt,
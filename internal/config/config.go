@@ -40,6 +40,9 @@ type Config struct {
 	// AuthDir is the directory where authentication token files are stored.
 	AuthDir string `yaml:"auth-dir" json:"-"`
 
+	// AuthEncryption encrypts credential files at rest in every token store.
+	AuthEncryption AuthEncryptionConfig `yaml:"auth-encryption" json:"-"`
+
 	// Debug enables or disables debug-level logging and other debug features.
 	Debug bool `yaml:"debug" json:"debug"`
 
@@ -49,6 +52,12 @@ type Config struct {
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
+	// UsagePersistence stores usage records durably so statistics survive restarts.
+	UsagePersistence UsagePersistence `yaml:"usage-persistence" json:"usage-persistence"`
+
+	// Metrics configures the Prometheus /metrics endpoint.
+	Metrics MetricsConfig `yaml:"metrics" json:"metrics"`
+
 	// DisableCooling disables quota cooldown scheduling when true.
 	DisableCooling bool `yaml:"disable-cooling" json:"disable-cooling"`
 
@@ -78,6 +87,37 @@ type Config struct {
 	// OpenAICompatibility defines OpenAI API compatibility configurations for external providers.
 	OpenAICompatibility []OpenAICompatibility `yaml:"openai-compatibility" json:"openai-compatibility"`
 
+	// ResponsesStore keeps completed /v1/responses turns for previous_response_id chaining.
+	ResponsesStore ResponsesStoreConfig `yaml:"responses-store" json:"responses-store"`
+
+	// Batch configures the local Message Batches and OpenAI Batch API emulation.
+	Batch BatchConfig `yaml:"batch" json:"batch"`
+
+	// Routing controls how credentials are chosen for each request.
+	Routing RoutingConfig `yaml:"routing" json:"routing"`
+
+	// ModelFallbacks maps a model to the ordered models tried when every credential for
+	// it is cooling down or the upstream answers with 429 or 5xx or times out.
+	ModelFallbacks map[string][]string `yaml:"model-fallbacks" json:"model-fallbacks"`
+
+	// Hedging races a second streaming attempt when the first chunk is slow to arrive.
+	Hedging HedgingConfig `yaml:"hedging" json:"hedging"`
+
+	// ResponseCache replays responses to repeated deterministic requests.
+	ResponseCache ResponseCacheConfig `yaml:"response-cache" json:"response-cache"`
+
+	// Cassette records upstream HTTP exchanges or replays them for offline development.
+	Cassette CassetteConfig `yaml:"cassette" json:"cassette"`
+
+	// CircuitBreaker stops routing to custom upstream base URLs that keep failing.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit-breaker" json:"circuit-breaker"`
+
+	// HealthCheck probes credentials in the background so broken accounts surface before users hit them.
+	HealthCheck HealthCheckConfig `yaml:"health-check" json:"health-check"`
+
+	// QuotaTracking controls how upstream rate-limit headers steer credential selection.
+	QuotaTracking QuotaTrackingConfig `yaml:"quota-tracking" json:"quota-tracking"`
+
 	// RemoteManagement nests management-related options under 'remote-management'.
 	RemoteManagement RemoteManagement `yaml:"remote-management" json:"-"`
 
@@ -95,6 +135,160 @@ type TLSConfig struct {
 	Key string `yaml:"key" json:"key"`
 }
 
+// UsagePersistence configures durable storage for usage statistics.
+// Changes take effect on restart.
+type UsagePersistence struct {
+	// Enable toggles writing usage records to durable storage and replaying them at startup.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Path is the JSON Lines file used when no database-backed store is active.
+	// Relative paths resolve against the config directory. Defaults to usage/usage-records.jsonl.
+	Path string `yaml:"path" json:"path"`
+	// RetentionDays drops records older than this many days; 0 keeps records forever.
+	RetentionDays int `yaml:"retention-days" json:"retention-days"`
+}
+
+// ResponsesStoreConfig configures storage of OpenAI Responses API turns.
+type ResponsesStoreConfig struct {
+	// Enable records responses so previous_response_id can be resolved locally. When
+	// disabled, previous_response_id is forwarded unchanged, except to Codex, whose
+	// executor always strips it.
+	Enable bool `yaml:"enable" json:"enable"`
+	// MaxEntries caps the responses kept in memory (default 10000).
+	MaxEntries int `yaml:"max-entries" json:"max-entries"`
+	// TTLHours is how long stored responses remain retrievable (default 720).
+	TTLHours int `yaml:"ttl-hours" json:"ttl-hours"`
+	// Dir persists responses as JSON files in this directory so chains survive restarts.
+	// Relative paths resolve against the config directory. Empty keeps responses in memory only.
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+// BatchConfig configures batch job execution and persistence.
+type BatchConfig struct {
+	// Dir stores batch jobs, results and files so unfinished jobs resume after a restart.
+	// Relative paths resolve against the config directory (default "batches").
+	Dir string `yaml:"dir" json:"dir"`
+	// Concurrency caps the batch requests executed at once across all jobs (default 8).
+	Concurrency int `yaml:"concurrency" json:"concurrency"`
+}
+
+// RoutingConfig controls credential selection.
+type RoutingConfig struct {
+	// Strategy selects the auth selector: round-robin (default), least-in-flight,
+	// weighted (uses the "weight" auth attribute), fill-first, or lowest-error-rate.
+	Strategy string `yaml:"strategy" json:"strategy"`
+	// SessionAffinity keeps consecutive turns of a conversation on the same credential.
+	SessionAffinity SessionAffinityConfig `yaml:"session-affinity" json:"session-affinity"`
+}
+
+// SessionAffinityConfig configures conversation-to-credential pinning.
+type SessionAffinityConfig struct {
+	// Enable wraps the configured strategy so requests with the same affinity key reuse
+	// the credential that served the previous turn until it becomes unavailable.
+	Enable bool `yaml:"enable" json:"enable"`
+	// TTLSeconds is how long an idle conversation stays pinned; 0 uses 30 minutes.
+	TTLSeconds int `yaml:"ttl-seconds" json:"ttl-seconds"`
+}
+
+// HedgingConfig configures hedged streaming requests.
+type HedgingConfig struct {
+	// DelayMS is how long to wait for the first chunk before launching a second attempt
+	// on another credential (default 2000).
+	DelayMS int `yaml:"delay-ms" json:"delay-ms"`
+	// Models lists the models hedged by default; a trailing "*" matches by prefix.
+	// Admin API keys can also opt in per request with the X-CLIProxy-Hedge header.
+	Models []string `yaml:"models" json:"models"`
+}
+
+// ResponseCacheConfig configures the cache of responses to deterministic requests.
+// Only requests with temperature 0 are cached unless an admin key sends "X-CLIProxy-Cache: on".
+type ResponseCacheConfig struct {
+	// Enable turns the cache on.
+	Enable bool `yaml:"enable" json:"enable"`
+	// TTLSeconds is how long a cached response is served (default 3600).
+	TTLSeconds int `yaml:"ttl-seconds" json:"ttl-seconds"`
+	// MaxEntries caps the number of cached responses (default 1000).
+	MaxEntries int `yaml:"max-entries" json:"max-entries"`
+	// MaxSizeMB caps the total size of cached responses (default 64).
+	MaxSizeMB int `yaml:"max-size-mb" json:"max-size-mb"`
+	// MaxEntryKB caps a single cached response; larger ones are not stored (default 1024).
+	MaxEntryKB int `yaml:"max-entry-kb" json:"max-entry-kb"`
+}
+
+// AuthEncryptionConfig configures encryption of credential files at rest.
+type AuthEncryptionConfig struct {
+	// KeyFile holds the base64 or hex encoded 32-byte master key. Relative paths
+	// resolve against the config directory. The CLIPROXY_AUTH_KEY and
+	// CLIPROXY_AUTH_KEY_FILE environment variables take precedence.
+	KeyFile string `yaml:"key-file" json:"-"`
+}
+
+// CassetteConfig configures recording and replay of upstream HTTP exchanges.
+type CassetteConfig struct {
+	// Mode is "record" to store every executor exchange, "replay" to answer from the
+	// stored exchanges without network access, or empty to disable both.
+	Mode string `yaml:"mode" json:"mode"`
+	// Dir holds one JSON file per exchange. Relative paths resolve against the config
+	// directory (default "cassettes").
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+// CircuitBreakerConfig tunes the shared circuit breaker kept per provider and base URL.
+// Zero values use the built-in defaults.
+type CircuitBreakerConfig struct {
+	// Disable turns the circuit breaker off.
+	Disable bool `yaml:"disable" json:"disable"`
+	// FailureThreshold is the number of consecutive endpoint failures that opens the circuit (default 5).
+	FailureThreshold int `yaml:"failure-threshold" json:"failure-threshold"`
+	// OpenSeconds is how long an open circuit rejects traffic before probing (default 30).
+	OpenSeconds int `yaml:"open-seconds" json:"open-seconds"`
+	// HalfOpenProbes is the number of concurrent probe requests while half-open (default 1).
+	HalfOpenProbes int `yaml:"half-open-probes" json:"half-open-probes"`
+}
+
+// HealthCheckConfig configures background credential probing.
+type HealthCheckConfig struct {
+	// Enable starts probing every credential on its interval.
+	Enable bool `yaml:"enable" json:"enable"`
+	// IntervalSeconds is the time between probes of one credential (default 1800).
+	IntervalSeconds int `yaml:"interval-seconds" json:"interval-seconds"`
+	// TimeoutSeconds bounds a single probe (default 30).
+	TimeoutSeconds int `yaml:"timeout-seconds" json:"timeout-seconds"`
+	// Providers overrides the settings per provider key (e.g. "claude", "gemini-cli").
+	Providers map[string]HealthCheckProvider `yaml:"providers" json:"providers"`
+}
+
+// HealthCheckProvider overrides health checking for one provider.
+type HealthCheckProvider struct {
+	// Disable skips this provider's credentials.
+	Disable bool `yaml:"disable" json:"disable"`
+	// IntervalSeconds replaces the global interval when positive.
+	IntervalSeconds int `yaml:"interval-seconds" json:"interval-seconds"`
+	// Model is the model probed; empty uses the first model registered for the credential.
+	Model string `yaml:"model" json:"model"`
+	// Method is "generate" (default), a one-token completion that also detects exhausted
+	// quota, or "count-tokens", which costs no quota where the provider supports it.
+	Method string `yaml:"method" json:"method"`
+}
+
+// QuotaTrackingConfig controls use of the remaining quota reported in upstream
+// rate-limit headers (anthropic-ratelimit-*, x-ratelimit-*, Codex usage windows).
+type QuotaTrackingConfig struct {
+	// DisableAvoidance keeps routing to credentials whose reported quota is nearly exhausted.
+	DisableAvoidance bool `yaml:"disable-avoidance" json:"disable-avoidance"`
+	// MinRemainingPercent skips a credential while other credentials remain once less than
+	// this share of any reported limit is left (default 5).
+	MinRemainingPercent int `yaml:"min-remaining-percent" json:"min-remaining-percent"`
+}
+
+// MetricsConfig configures the Prometheus-compatible metrics endpoint.
+type MetricsConfig struct {
+	// Enable exposes /metrics and starts collecting request, token and credential metrics.
+	Enable bool `yaml:"enable" json:"enable"`
+	// BearerToken is required as "Authorization: Bearer <token>" on /metrics.
+	// When empty, only loopback clients may scrape.
+	BearerToken string `yaml:"bearer-token" json:"-"`
+}
+
 // RemoteManagement holds management API configuration under 'remote-management'.
 type RemoteManagement struct {
 	// AllowRemote toggles remote (non-localhost) access to management API.
@@ -0,0 +1,416 @@
+// Package contextguard estimates the input size of chat requests in the client
+// formats the proxy accepts and shortens conversations that do not fit a model's
+// context window.
+//
+// Token counts are estimates: they use the model's tiktoken encoding where one is
+// known and o200k_base otherwise, and charge a flat amount per inline attachment.
+// Conversations are only ever cut at the start of a user turn, so tool calls stay
+// paired with their results and the remaining history starts with a user message.
+package contextguard
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+const (
+	// attachmentTokens is charged for each inline image or file instead of its encoded bytes.
+	attachmentTokens = 1000
+	// minAttachmentLength is the length from which an unbroken string is treated as encoded data.
+	minAttachmentLength = 1024
+)
+
+// schema describes where a client format keeps its conversation.
+type schema struct {
+	// messages is the path of the message array.
+	messages string
+	// pinned reports whether a message is a system message that is always kept.
+	pinned func(gjson.Result) bool
+	// turnStart reports whether a message opens a new user turn.
+	turnStart func(gjson.Result) bool
+	// addSummary stores a summary of dropped turns with the request's instructions.
+	// When it is nil the summary is inserted as a system message instead.
+	addSummary func(payload []byte, summary string) []byte
+}
+
+var schemas = map[string]schema{
+	"openai": {
+		messages:  "messages",
+		pinned:    isSystemRole,
+		turnStart: func(m gjson.Result) bool { return m.Get("role").String() == "user" },
+	},
+	"openai-response": {
+		messages: "input",
+		pinned:   isSystemRole,
+		turnStart: func(m gjson.Result) bool {
+			itemType := m.Get("type").String()
+			return m.Get("role").String() == "user" && (itemType == "" || itemType == "message")
+		},
+		addSummary: func(payload []byte, summary string) []byte {
+			return appendInstructionText(payload, "instructions", summary)
+		},
+	},
+	"claude": {
+		messages: "messages",
+		pinned:   func(gjson.Result) bool { return false },
+		turnStart: func(m gjson.Result) bool {
+			if m.Get("role").String() != "user" {
+				return false
+			}
+			for _, block := range m.Get("content").Array() {
+				if block.Get("type").String() == "tool_result" {
+					return false
+				}
+			}
+			return true
+		},
+		addSummary: addClaudeSystem,
+	},
+	"gemini": {
+		messages:   "contents",
+		pinned:     func(gjson.Result) bool { return false },
+		turnStart:  isGeminiUserTurn,
+		addSummary: func(payload []byte, summary string) []byte { return addGeminiSystem(payload, "", summary) },
+	},
+	"gemini-cli": {
+		messages:   "request.contents",
+		pinned:     func(gjson.Result) bool { return false },
+		turnStart:  isGeminiUserTurn,
+		addSummary: func(payload []byte, summary string) []byte { return addGeminiSystem(payload, "request.", summary) },
+	},
+}
+
+// InputLimit returns how many input tokens info accepts once maxOutput tokens are
+// reserved for the reply, or 0 when the model does not publish its limits.
+func InputLimit(info *registry.ModelInfo, maxOutput int) int {
+	if info == nil {
+		return 0
+	}
+	if info.InputTokenLimit > 0 {
+		return info.InputTokenLimit
+	}
+	if info.ContextLength > 0 {
+		if limit := info.ContextLength - max(maxOutput, 0); limit > 0 {
+			return limit
+		}
+	}
+	return 0
+}
+
+// MaxOutputTokens returns the output budget requested in payload, or 0 when none is set.
+func MaxOutputTokens(payload []byte) int {
+	for _, path := range []string{
+		"max_completion_tokens",
+		"max_tokens",
+		"max_output_tokens",
+		"generationConfig.maxOutputTokens",
+		"request.generationConfig.maxOutputTokens",
+	} {
+		if value := gjson.GetBytes(payload, path); value.Exists() {
+			return int(value.Int())
+		}
+	}
+	return 0
+}
+
+// Conversation is a request whose message history can be shortened.
+type Conversation struct {
+	schema  schema
+	payload []byte
+	items   []gjson.Result
+	// tokens holds the estimate for each message; base covers everything else.
+	tokens []int
+	base   int
+}
+
+// Parse measures payload, a request in the given client format, with enc. It
+// returns nil when the format is not supported or the request has no message array.
+func Parse(format string, payload []byte, enc tokenizer.Codec) *Conversation {
+	s, ok := schemas[format]
+	if !ok {
+		return nil
+	}
+	messages := gjson.GetBytes(payload, s.messages)
+	if !messages.IsArray() {
+		return nil
+	}
+	c := &Conversation{schema: s, payload: payload, items: messages.Array()}
+	c.tokens = make([]int, len(c.items))
+	for i, item := range c.items {
+		c.tokens[i] = countTokens(enc, item)
+	}
+	rest, err := sjson.SetRawBytes(append([]byte(nil), payload...), s.messages, []byte("[]"))
+	if err == nil {
+		c.base = countTokens(enc, gjson.ParseBytes(rest))
+	}
+	return c
+}
+
+// Len returns the number of messages in the conversation.
+func (c *Conversation) Len() int { return len(c.items) }
+
+// Tokens returns the estimated input tokens of the request after the messages
+// before keepFrom, other than system messages, are removed.
+func (c *Conversation) Tokens(keepFrom int) int {
+	total := c.base
+	for i, n := range c.tokens {
+		if i >= keepFrom || c.schema.pinned(c.items[i]) {
+			total += n
+		}
+	}
+	return total
+}
+
+// Removed returns how many messages trimming at keepFrom removes.
+func (c *Conversation) Removed(keepFrom int) int {
+	removed := 0
+	for i := 0; i < keepFrom && i < len(c.items); i++ {
+		if !c.schema.pinned(c.items[i]) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// TurnStarts returns the indexes at which the conversation may be cut, oldest first.
+// The first message is never included because cutting there removes nothing.
+func (c *Conversation) TurnStarts() []int {
+	var starts []int
+	for i := 1; i < len(c.items); i++ {
+		if !c.schema.pinned(c.items[i]) && c.schema.turnStart(c.items[i]) {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// DropOldest returns the earliest cut that brings the request within limit, or
+// -1 when even the most recent turn alone is too large.
+func (c *Conversation) DropOldest(limit int) int {
+	for _, start := range c.TurnStarts() {
+		if c.Tokens(start) <= limit {
+			return start
+		}
+	}
+	return -1
+}
+
+// KeepLast returns the cut that keeps the last n messages. When no turn starts
+// there the cut moves to the nearest earlier turn start, keeping a few more; it
+// is 0 when the conversation has no more than n messages or cannot be cut.
+func (c *Conversation) KeepLast(n int) int {
+	target := len(c.items) - max(n, 1)
+	if target <= 0 {
+		return 0
+	}
+	cut := 0
+	for _, start := range c.TurnStarts() {
+		if start > target {
+			break
+		}
+		cut = start
+	}
+	return cut
+}
+
+// Trim returns the request without the messages before keepFrom, keeping system messages.
+func (c *Conversation) Trim(keepFrom int) []byte {
+	return c.rebuild(keepFrom, "")
+}
+
+// Summarized returns the request with the messages before keepFrom replaced by summary.
+func (c *Conversation) Summarized(keepFrom int, summary string) []byte {
+	if c.schema.addSummary == nil {
+		return c.rebuild(keepFrom, summary)
+	}
+	return c.schema.addSummary(c.rebuild(keepFrom, ""), summary)
+}
+
+// rebuild writes the kept messages back into the payload. A non-empty summary is
+// inserted as a system message where the removed messages were.
+func (c *Conversation) rebuild(keepFrom int, summary string) []byte {
+	var b strings.Builder
+	b.WriteByte('[')
+	write := func(raw string) {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(raw)
+	}
+	for i, item := range c.items {
+		if i == keepFrom && summary != "" {
+			msg, _ := sjson.Set(`{"role":"system"}`, "content", summaryPreamble+summary)
+			write(msg)
+		}
+		if i >= keepFrom || c.schema.pinned(item) {
+			write(item.Raw)
+		}
+	}
+	b.WriteByte(']')
+	out, err := sjson.SetRawBytes(append([]byte(nil), c.payload...), c.schema.messages, []byte(b.String()))
+	if err != nil {
+		return c.payload
+	}
+	return out
+}
+
+// Transcript renders the messages before keepFrom, other than system messages, as
+// plain text for a summarization prompt.
+func (c *Conversation) Transcript(keepFrom int) string {
+	var b strings.Builder
+	for i := 0; i < keepFrom && i < len(c.items); i++ {
+		item := c.items[i]
+		if c.schema.pinned(item) {
+			continue
+		}
+		var parts []string
+		collectText(item, &parts, false)
+		text := strings.TrimSpace(strings.Join(parts, "\n"))
+		if text == "" {
+			continue
+		}
+		role := item.Get("role").String()
+		if role == "" {
+			role = item.Get("type").String()
+		}
+		if role == "" {
+			role = "message"
+		}
+		b.WriteString(role)
+		b.WriteString(": ")
+		b.WriteString(text)
+		b.WriteString("\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// summaryPreamble introduces a summary of removed turns to the model.
+const summaryPreamble = "Summary of the earlier part of this conversation, which was condensed to fit the context window:\n\n"
+
+func isSystemRole(m gjson.Result) bool {
+	role := m.Get("role").String()
+	return role == "system" || role == "developer"
+}
+
+func isGeminiUserTurn(m gjson.Result) bool {
+	if role := m.Get("role").String(); role != "" && role != "user" {
+		return false
+	}
+	for _, part := range m.Get("parts").Array() {
+		if part.Get("functionResponse").Exists() || part.Get("function_response").Exists() {
+			return false
+		}
+	}
+	return true
+}
+
+func appendInstructionText(payload []byte, path, summary string) []byte {
+	text := summaryPreamble + summary
+	if existing := gjson.GetBytes(payload, path).String(); existing != "" {
+		text = existing + "\n\n" + text
+	}
+	out, err := sjson.SetBytes(payload, path, text)
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+func addClaudeSystem(payload []byte, summary string) []byte {
+	system := gjson.GetBytes(payload, "system")
+	if !system.IsArray() {
+		return appendInstructionText(payload, "system", summary)
+	}
+	block, _ := sjson.Set(`{"type":"text"}`, "text", summaryPreamble+summary)
+	out, err := sjson.SetRawBytes(payload, "system.-1", []byte(block))
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+func addGeminiSystem(payload []byte, prefix, summary string) []byte {
+	path := prefix + "systemInstruction"
+	if !gjson.GetBytes(payload, path).Exists() && gjson.GetBytes(payload, prefix+"system_instruction").Exists() {
+		path = prefix + "system_instruction"
+	}
+	part, _ := sjson.Set(`{}`, "text", summaryPreamble+summary)
+	out, err := sjson.SetRawBytes(payload, path+".parts.-1", []byte(part))
+	if err != nil {
+		return payload
+	}
+	return out
+}
+
+// CountTokens estimates the input tokens of a whole request in any format.
+func CountTokens(enc tokenizer.Codec, payload []byte) int {
+	return countTokens(enc, gjson.ParseBytes(payload))
+}
+
+// countTokens estimates the tokens of every string and object key in value,
+// charging inline attachments a flat amount.
+func countTokens(enc tokenizer.Codec, value gjson.Result) int {
+	var parts []string
+	attachments := collectText(value, &parts, true)
+	total := attachments * attachmentTokens
+	if len(parts) == 0 || enc == nil {
+		return total
+	}
+	count, err := enc.Count(strings.Join(parts, "\n"))
+	if err != nil {
+		return total + len(strings.Join(parts, "\n"))/4
+	}
+	return total + count
+}
+
+// transcriptSkipKeys hold identifiers and labels that add nothing to a transcript.
+var transcriptSkipKeys = map[string]struct{}{
+	"role": {}, "type": {}, "id": {}, "call_id": {}, "tool_call_id": {}, "tool_use_id": {},
+	"mimeType": {}, "mime_type": {}, "media_type": {}, "signature": {}, "thoughtSignature": {},
+}
+
+// collectText appends the text found in value to parts and returns the number of
+// inline attachments it skipped. When counting, object keys are included too;
+// otherwise values under transcriptSkipKeys are left out.
+func collectText(value gjson.Result, parts *[]string, counting bool) int {
+	switch {
+	case value.IsObject() || value.IsArray():
+		attachments := 0
+		value.ForEach(func(key, child gjson.Result) bool {
+			if key.Type == gjson.String {
+				if counting {
+					*parts = append(*parts, key.String())
+				} else if _, skip := transcriptSkipKeys[key.String()]; skip {
+					return true
+				}
+			}
+			attachments += collectText(child, parts, counting)
+			return true
+		})
+		return attachments
+	case value.Type == gjson.String:
+		text := value.String()
+		if isEncodedData(text) {
+			return 1
+		}
+		if text != "" {
+			*parts = append(*parts, text)
+		}
+	}
+	return 0
+}
+
+// isEncodedData reports whether s looks like a data URL or a base64 payload rather than text.
+func isEncodedData(s string) bool {
+	if strings.HasPrefix(s, "data:") && strings.Contains(s[:min(len(s), 128)], ";base64,") {
+		return true
+	}
+	if len(s) < minAttachmentLength {
+		return false
+	}
+	return !strings.ContainsAny(s, " \n\t")
+}
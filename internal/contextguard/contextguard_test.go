@@ -0,0 +1,375 @@
+package contextguard
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+const openAIConversation = `{"model":"gpt-4o","messages":[
+	{"role":"system","content":"You are terse."},
+	{"role":"user","content":"What is the weather in Paris?"},
+	{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Paris\"}"}}]},
+	{"role":"tool","tool_call_id":"call_1","content":"Sunny, 24C"},
+	{"role":"user","content":"And in Berlin?"},
+	{"role":"assistant","content":"Cloudy, 18C."},
+	{"role":"user","content":"Thanks, which is warmer?"}
+]}`
+
+func newTestCodec(t *testing.T) tokenizer.Codec {
+	t.Helper()
+	enc, err := tokenizer.Get(tokenizer.O200kBase)
+	if err != nil {
+		t.Fatalf("failed to load tokenizer: %v", err)
+	}
+	return enc
+}
+
+func parseTestConversation(t *testing.T, format, payload string) *Conversation {
+	t.Helper()
+	conv := Parse(format, []byte(payload), newTestCodec(t))
+	if conv == nil {
+		t.Fatalf("Parse(%s) returned nil", format)
+	}
+	return conv
+}
+
+func TestTurnStarts(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  string
+		payload string
+		want    []int
+	}{
+		{
+			name:    "openai tool results stay with their call",
+			format:  "openai",
+			payload: openAIConversation,
+			want:    []int{1, 4, 6},
+		},
+		{
+			name:   "openai responses",
+			format: "openai-response",
+			payload: `{"input":[
+				{"role":"user","content":"Look up Paris."},
+				{"type":"function_call","call_id":"c1","name":"weather","arguments":"{}"},
+				{"type":"function_call_output","call_id":"c1","output":"Sunny"},
+				{"type":"message","role":"user","content":"And Berlin?"}
+			]}`,
+			want: []int{3},
+		},
+		{
+			name:   "claude tool results are not turns",
+			format: "claude",
+			payload: `{"messages":[
+				{"role":"user","content":"Look up Paris."},
+				{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"weather","input":{}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"Sunny"}]},
+				{"role":"assistant","content":"Sunny."},
+				{"role":"user","content":"And Berlin?"}
+			]}`,
+			want: []int{4},
+		},
+		{
+			name:   "gemini function responses are not turns",
+			format: "gemini",
+			payload: `{"contents":[
+				{"role":"user","parts":[{"text":"Look up Paris."}]},
+				{"role":"model","parts":[{"functionCall":{"name":"weather","args":{}}}]},
+				{"role":"user","parts":[{"functionResponse":{"name":"weather","response":{"result":"Sunny"}}}]},
+				{"role":"model","parts":[{"text":"Sunny."}]},
+				{"role":"user","parts":[{"text":"And Berlin?"}]}
+			]}`,
+			want: []int{4},
+		},
+		{
+			name:   "gemini cli envelope",
+			format: "gemini-cli",
+			payload: `{"model":"gemini-2.5-pro","request":{"contents":[
+				{"role":"user","parts":[{"text":"Hi"}]},
+				{"role":"model","parts":[{"text":"Hello"}]},
+				{"role":"user","parts":[{"text":"Bye"}]}
+			]}}`,
+			want: []int{2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conv := parseTestConversation(t, tc.format, tc.payload)
+			if got := conv.TurnStarts(); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Fatalf("TurnStarts() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  string
+		payload string
+	}{
+		{name: "unknown format", format: "codex", payload: openAIConversation},
+		{name: "no message array", format: "openai", payload: `{"model":"gpt-4o","prompt":"hi"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if conv := Parse(tc.format, []byte(tc.payload), newTestCodec(t)); conv != nil {
+				t.Fatalf("Parse() = %v, want nil", conv)
+			}
+		})
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	conv := parseTestConversation(t, "openai", openAIConversation)
+
+	testCases := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "everything fits", limit: conv.Tokens(0), want: 1},
+		{name: "first turn must go", limit: conv.Tokens(4), want: 4},
+		{name: "only the last turn fits", limit: conv.Tokens(6), want: 6},
+		{name: "last turn too large", limit: conv.Tokens(6) - 1, want: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := conv.DropOldest(tc.limit); got != tc.want {
+				t.Fatalf("DropOldest(%d) = %d, want %d", tc.limit, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeepLast(t *testing.T) {
+	conv := parseTestConversation(t, "openai", openAIConversation)
+
+	testCases := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{name: "last message", n: 1, want: 6},
+		{name: "cut moves back to a turn start", n: 2, want: 4},
+		{name: "cut on a turn start", n: 3, want: 4},
+		{name: "most of the conversation", n: 5, want: 1},
+		{name: "whole conversation", n: 7, want: 0},
+		{name: "more than the conversation", n: 20, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := conv.KeepLast(tc.n); got != tc.want {
+				t.Fatalf("KeepLast(%d) = %d, want %d", tc.n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTrimKeepsSystemMessages(t *testing.T) {
+	conv := parseTestConversation(t, "openai", openAIConversation)
+
+	out := conv.Trim(4)
+	var roles []string
+	for _, msg := range gjson.GetBytes(out, "messages").Array() {
+		roles = append(roles, msg.Get("role").String())
+	}
+	if want := []string{"system", "user", "assistant", "user"}; fmt.Sprint(roles) != fmt.Sprint(want) {
+		t.Fatalf("Trim(4) roles = %v, want %v", roles, want)
+	}
+	if got := conv.Removed(4); got != 3 {
+		t.Fatalf("Removed(4) = %d, want 3", got)
+	}
+	if gjson.GetBytes(out, "model").String() != "gpt-4o" {
+		t.Fatalf("Trim() dropped the other request fields: %s", out)
+	}
+	if after, before := CountTokens(newTestCodec(t), out), CountTokens(newTestCodec(t), []byte(openAIConversation)); after >= before {
+		t.Fatalf("CountTokens() after trim = %d, before = %d", after, before)
+	}
+}
+
+func TestSummarized(t *testing.T) {
+	testCases := []struct {
+		name        string
+		format      string
+		payload     string
+		keepFrom    int
+		summaryPath string
+		wantKept    int
+	}{
+		{
+			name:        "openai inserts a system message",
+			format:      "openai",
+			payload:     openAIConversation,
+			keepFrom:    4,
+			summaryPath: "messages.1.content",
+			wantKept:    5,
+		},
+		{
+			name:        "openai responses appends to instructions",
+			format:      "openai-response",
+			payload:     `{"instructions":"Be terse.","input":[{"role":"user","content":"One"},{"role":"assistant","content":"Two"},{"role":"user","content":"Three"}]}`,
+			keepFrom:    2,
+			summaryPath: "instructions",
+			wantKept:    1,
+		},
+		{
+			name:        "claude string system",
+			format:      "claude",
+			payload:     `{"system":"Be terse.","messages":[{"role":"user","content":"One"},{"role":"assistant","content":"Two"},{"role":"user","content":"Three"}]}`,
+			keepFrom:    2,
+			summaryPath: "system",
+			wantKept:    1,
+		},
+		{
+			name:        "claude system blocks",
+			format:      "claude",
+			payload:     `{"system":[{"type":"text","text":"Be terse."}],"messages":[{"role":"user","content":"One"},{"role":"assistant","content":"Two"},{"role":"user","content":"Three"}]}`,
+			keepFrom:    2,
+			summaryPath: "system.1.text",
+			wantKept:    1,
+		},
+		{
+			name:        "gemini system instruction",
+			format:      "gemini",
+			payload:     `{"contents":[{"role":"user","parts":[{"text":"One"}]},{"role":"model","parts":[{"text":"Two"}]},{"role":"user","parts":[{"text":"Three"}]}]}`,
+			keepFrom:    2,
+			summaryPath: "systemInstruction.parts.0.text",
+			wantKept:    1,
+		},
+		{
+			name:        "gemini cli system instruction",
+			format:      "gemini-cli",
+			payload:     `{"request":{"systemInstruction":{"parts":[{"text":"Be terse."}]},"contents":[{"role":"user","parts":[{"text":"One"}]},{"role":"model","parts":[{"text":"Two"}]},{"role":"user","parts":[{"text":"Three"}]}]}}`,
+			keepFrom:    2,
+			summaryPath: "request.systemInstruction.parts.1.text",
+			wantKept:    1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conv := parseTestConversation(t, tc.format, tc.payload)
+			out := conv.Summarized(tc.keepFrom, "The user said One.")
+
+			summary := gjson.GetBytes(out, tc.summaryPath).String()
+			if !strings.Contains(summary, summaryPreamble+"The user said One.") {
+				t.Fatalf("%s = %q, want the summary", tc.summaryPath, summary)
+			}
+			if got := len(gjson.GetBytes(out, conv.schema.messages).Array()); got != tc.wantKept {
+				t.Fatalf("Summarized() kept %d messages, want %d", got, tc.wantKept)
+			}
+		})
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	conv := parseTestConversation(t, "openai", openAIConversation)
+
+	got := conv.Transcript(4)
+	for _, want := range []string{"user: What is the weather in Paris?", "weather", "tool: Sunny, 24C"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Transcript(4) = %q, want it to contain %q", got, want)
+		}
+	}
+	for _, unwanted := range []string{"You are terse.", "call_1", "Berlin"} {
+		if strings.Contains(got, unwanted) {
+			t.Fatalf("Transcript(4) = %q, want it without %q", got, unwanted)
+		}
+	}
+}
+
+func TestCountTokensChargesAttachments(t *testing.T) {
+	enc := newTestCodec(t)
+	image := strings.Repeat("QUJD", 10000)
+
+	testCases := []struct {
+		name    string
+		payload string
+		min     int
+		max     int
+	}{
+		{
+			name:    "data url",
+			payload: `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + image + `"}}]}]}`,
+			min:     attachmentTokens,
+			max:     attachmentTokens + 50,
+		},
+		{
+			name:    "raw base64",
+			payload: `{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/png","data":"` + image + `"}}]}]}`,
+			min:     attachmentTokens,
+			max:     attachmentTokens + 50,
+		},
+		{
+			name:    "plain text",
+			payload: `{"messages":[{"role":"user","content":"hello there"}]}`,
+			min:     1,
+			max:     50,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := CountTokens(enc, []byte(tc.payload)); got < tc.min || got > tc.max {
+				t.Fatalf("CountTokens() = %d, want between %d and %d", got, tc.min, tc.max)
+			}
+		})
+	}
+}
+
+func TestInputLimit(t *testing.T) {
+	testCases := []struct {
+		name      string
+		info      *registry.ModelInfo
+		maxOutput int
+		want      int
+	}{
+		{name: "unknown model", info: nil, want: 0},
+		{name: "no published limits", info: &registry.ModelInfo{ID: "m"}, want: 0},
+		{name: "input limit wins", info: &registry.ModelInfo{InputTokenLimit: 1000, ContextLength: 5000}, maxOutput: 500, want: 1000},
+		{name: "context minus output", info: &registry.ModelInfo{ContextLength: 5000}, maxOutput: 500, want: 4500},
+		{name: "output fills the context", info: &registry.ModelInfo{ContextLength: 5000}, maxOutput: 5000, want: 0},
+		{name: "negative output", info: &registry.ModelInfo{ContextLength: 5000}, maxOutput: -1, want: 5000},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := InputLimit(tc.info, tc.maxOutput); got != tc.want {
+				t.Fatalf("InputLimit() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMaxOutputTokens(t *testing.T) {
+	testCases := []struct {
+		name    string
+		payload string
+		want    int
+	}{
+		{name: "openai completion tokens", payload: `{"max_completion_tokens":100,"max_tokens":50}`, want: 100},
+		{name: "openai max tokens", payload: `{"max_tokens":50}`, want: 50},
+		{name: "responses", payload: `{"max_output_tokens":70}`, want: 70},
+		{name: "gemini", payload: `{"generationConfig":{"maxOutputTokens":80}}`, want: 80},
+		{name: "gemini cli", payload: `{"request":{"generationConfig":{"maxOutputTokens":90}}}`, want: 90},
+		{name: "not set", payload: `{"messages":[]}`, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := MaxOutputTokens([]byte(tc.payload)); got != tc.want {
+				t.Fatalf("MaxOutputTokens() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
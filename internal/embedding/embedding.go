@@ -0,0 +1,245 @@
+// Package embedding converts embedding requests and responses between the OpenAI
+// embeddings schema, the Gemini batchEmbedContents schema and the Vertex AI predict
+// schema. Gemini batchEmbedContents is the pivot format for Google providers.
+package embedding
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ToGemini converts a request in the from format into a Gemini batchEmbedContents body.
+func ToGemini(from sdktranslator.Format, model string, payload []byte) ([]byte, error) {
+	modelRef := "models/" + model
+	switch from {
+	case sdktranslator.FormatGemini:
+		requests := gjson.GetBytes(payload, "requests")
+		if !requests.IsArray() || len(requests.Array()) == 0 {
+			return nil, fmt.Errorf("requests must be a non-empty array")
+		}
+		out := payload
+		for i := range requests.Array() {
+			out, _ = sjson.SetBytes(out, fmt.Sprintf("requests.%d.model", i), modelRef)
+		}
+		return out, nil
+	case sdktranslator.FormatOpenAI:
+		inputs, err := openAIInputs(payload)
+		if err != nil {
+			return nil, err
+		}
+		dimensions := gjson.GetBytes(payload, "dimensions").Int()
+		out := []byte(`{"requests":[]}`)
+		for _, text := range inputs {
+			item := []byte(`{"model":"","content":{"parts":[{"text":""}]}}`)
+			item, _ = sjson.SetBytes(item, "model", modelRef)
+			item, _ = sjson.SetBytes(item, "content.parts.0.text", text)
+			if dimensions > 0 {
+				item, _ = sjson.SetBytes(item, "outputDimensionality", dimensions)
+			}
+			out, _ = sjson.SetRawBytes(out, "requests.-1", item)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("embeddings are not supported for %s requests", from)
+	}
+}
+
+// FromGemini converts a Gemini batchEmbedContents response into the to format.
+// originalRequest is the client request, used for the OpenAI encoding_format.
+func FromGemini(to sdktranslator.Format, model string, originalRequest, data []byte) []byte {
+	if to != sdktranslator.FormatOpenAI {
+		return data
+	}
+	base64Output := gjson.GetBytes(originalRequest, "encoding_format").String() == "base64"
+	out := []byte(`{"object":"list","data":[],"model":"","usage":{"prompt_tokens":0,"total_tokens":0}}`)
+	out, _ = sjson.SetBytes(out, "model", model)
+	for i, item := range gjson.GetBytes(data, "embeddings").Array() {
+		entry := []byte(`{"object":"embedding","index":0,"embedding":[]}`)
+		entry, _ = sjson.SetBytes(entry, "index", i)
+		values := item.Get("values")
+		if base64Output {
+			entry, _ = sjson.SetBytes(entry, "embedding", encodeBase64(values))
+		} else if values.IsArray() {
+			entry, _ = sjson.SetRawBytes(entry, "embedding", []byte(values.Raw))
+		}
+		out, _ = sjson.SetRawBytes(out, "data.-1", entry)
+	}
+	if tokens := gjson.GetBytes(data, "usageMetadata.promptTokenCount").Int(); tokens > 0 {
+		out, _ = sjson.SetBytes(out, "usage.prompt_tokens", tokens)
+		out, _ = sjson.SetBytes(out, "usage.total_tokens", tokens)
+	}
+	return out
+}
+
+// ToOpenAI converts a request in the from format into an OpenAI embeddings body.
+func ToOpenAI(from sdktranslator.Format, model string, payload []byte) ([]byte, error) {
+	switch from {
+	case sdktranslator.FormatOpenAI:
+		out, _ := sjson.SetBytes(payload, "model", model)
+		return out, nil
+	case sdktranslator.FormatGemini:
+		requests := gjson.GetBytes(payload, "requests").Array()
+		if len(requests) == 0 {
+			return nil, fmt.Errorf("requests must be a non-empty array")
+		}
+		inputs := make([]string, 0, len(requests))
+		for _, request := range requests {
+			inputs = append(inputs, contentText(request.Get("content")))
+		}
+		out := []byte(`{"model":"","input":[],"encoding_format":"float"}`)
+		out, _ = sjson.SetBytes(out, "model", model)
+		out, _ = sjson.SetBytes(out, "input", inputs)
+		if dimensions := requests[0].Get("outputDimensionality").Int(); dimensions > 0 {
+			out, _ = sjson.SetBytes(out, "dimensions", dimensions)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("embeddings are not supported for %s requests", from)
+	}
+}
+
+// FromOpenAI converts an OpenAI embeddings response into the to format.
+func FromOpenAI(to sdktranslator.Format, data []byte) []byte {
+	if to != sdktranslator.FormatGemini {
+		return data
+	}
+	items := gjson.GetBytes(data, "data").Array()
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Get("index").Int() < items[j].Get("index").Int() })
+	out := []byte(`{"embeddings":[]}`)
+	for _, item := range items {
+		entry := []byte(`{"values":[]}`)
+		if values := item.Get("embedding"); values.IsArray() {
+			entry, _ = sjson.SetRawBytes(entry, "values", []byte(values.Raw))
+		} else if values.Type == gjson.String {
+			entry, _ = sjson.SetBytes(entry, "values", decodeBase64(values.String()))
+		}
+		out, _ = sjson.SetRawBytes(out, "embeddings.-1", entry)
+	}
+	return out
+}
+
+// GeminiToVertex converts a Gemini batchEmbedContents body into a Vertex AI predict body.
+func GeminiToVertex(body []byte) []byte {
+	out := []byte(`{"instances":[]}`)
+	requests := gjson.GetBytes(body, "requests").Array()
+	for _, request := range requests {
+		instance := []byte(`{"content":""}`)
+		instance, _ = sjson.SetBytes(instance, "content", contentText(request.Get("content")))
+		if taskType := request.Get("taskType").String(); taskType != "" {
+			instance, _ = sjson.SetBytes(instance, "task_type", taskType)
+		}
+		if title := request.Get("title").String(); title != "" {
+			instance, _ = sjson.SetBytes(instance, "title", title)
+		}
+		out, _ = sjson.SetRawBytes(out, "instances.-1", instance)
+	}
+	if len(requests) > 0 {
+		if dimensions := requests[0].Get("outputDimensionality").Int(); dimensions > 0 {
+			out, _ = sjson.SetBytes(out, "parameters.outputDimensionality", dimensions)
+		}
+	}
+	return out
+}
+
+// VertexToGemini converts a Vertex AI predict response into a Gemini batchEmbedContents
+// response and returns the number of input tokens Vertex reported.
+func VertexToGemini(data []byte) ([]byte, int64) {
+	out := []byte(`{"embeddings":[]}`)
+	var tokens int64
+	for _, prediction := range gjson.GetBytes(data, "predictions").Array() {
+		entry := []byte(`{"values":[]}`)
+		if values := prediction.Get("embeddings.values"); values.IsArray() {
+			entry, _ = sjson.SetRawBytes(entry, "values", []byte(values.Raw))
+		}
+		tokens += prediction.Get("embeddings.statistics.token_count").Int()
+		out, _ = sjson.SetRawBytes(out, "embeddings.-1", entry)
+	}
+	if tokens > 0 {
+		out, _ = sjson.SetBytes(out, "usageMetadata.promptTokenCount", tokens)
+	}
+	return out, tokens
+}
+
+// SingleToBatch wraps a Gemini embedContent request into a batchEmbedContents request.
+func SingleToBatch(payload []byte) []byte {
+	out := []byte(`{"requests":[]}`)
+	out, _ = sjson.SetRawBytes(out, "requests.-1", payload)
+	return out
+}
+
+// BatchToSingle unwraps the first embedding of a batchEmbedContents response into an
+// embedContent response.
+func BatchToSingle(data []byte) []byte {
+	out := []byte(`{"embedding":{"values":[]}}`)
+	if values := gjson.GetBytes(data, "embeddings.0.values"); values.IsArray() {
+		out, _ = sjson.SetRawBytes(out, "embedding.values", []byte(values.Raw))
+	}
+	return out
+}
+
+// openAIInputs extracts the text inputs of an OpenAI embeddings request.
+// Pre-tokenized inputs cannot be forwarded to non-OpenAI providers.
+func openAIInputs(payload []byte) ([]string, error) {
+	input := gjson.GetBytes(payload, "input")
+	switch {
+	case input.Type == gjson.String:
+		return []string{input.String()}, nil
+	case input.IsArray():
+		items := input.Array()
+		if len(items) == 0 {
+			return nil, fmt.Errorf("input must not be empty")
+		}
+		inputs := make([]string, 0, len(items))
+		for _, item := range items {
+			if item.Type != gjson.String {
+				return nil, fmt.Errorf("token array inputs are not supported for this model")
+			}
+			inputs = append(inputs, item.String())
+		}
+		return inputs, nil
+	default:
+		return nil, fmt.Errorf("input is required")
+	}
+}
+
+// contentText joins the text parts of a Gemini content object.
+func contentText(content gjson.Result) string {
+	var parts []string
+	for _, part := range content.Get("parts").Array() {
+		if text := part.Get("text"); text.Exists() {
+			parts = append(parts, text.String())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// encodeBase64 renders embedding values as base64 little-endian float32, matching
+// OpenAI's encoding_format=base64.
+func encodeBase64(values gjson.Result) string {
+	items := values.Array()
+	buf := make([]byte, 4*len(items))
+	for i, v := range items {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v.Float())))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func decodeBase64(encoded string) []float32 {
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return []float32{}
+	}
+	values := make([]float32, 0, len(buf)/4)
+	for i := 0; i+4 <= len(buf); i += 4 {
+		values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(buf[i:])))
+	}
+	return values
+}
@@ -0,0 +1,161 @@
+package embedding
+
+import (
+	"testing"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestToGemini(t *testing.T) {
+	testCases := []struct {
+		name    string
+		from    sdktranslator.Format
+		payload string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "openai string input",
+			from:    sdktranslator.FormatOpenAI,
+			payload: `{"model":"text-embedding-004","input":"hello"}`,
+			want:    `{"requests":[{"model":"models/text-embedding-004","content":{"parts":[{"text":"hello"}]}}]}`,
+		},
+		{
+			name:    "openai batch with dimensions",
+			from:    sdktranslator.FormatOpenAI,
+			payload: `{"input":["a","b"],"dimensions":256}`,
+			want:    `{"requests":[{"model":"models/text-embedding-004","content":{"parts":[{"text":"a"}]},"outputDimensionality":256},{"model":"models/text-embedding-004","content":{"parts":[{"text":"b"}]},"outputDimensionality":256}]}`,
+		},
+		{
+			name:    "openai token arrays are rejected",
+			from:    sdktranslator.FormatOpenAI,
+			payload: `{"input":[[1,2,3]]}`,
+			wantErr: true,
+		},
+		{
+			name:    "openai empty input",
+			from:    sdktranslator.FormatOpenAI,
+			payload: `{"input":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "gemini requests get the routed model",
+			from:    sdktranslator.FormatGemini,
+			payload: `{"requests":[{"model":"models/alias","content":{"parts":[{"text":"x"}]},"taskType":"RETRIEVAL_QUERY"}]}`,
+			want:    `{"requests":[{"model":"models/text-embedding-004","content":{"parts":[{"text":"x"}]},"taskType":"RETRIEVAL_QUERY"}]}`,
+		},
+		{
+			name:    "claude has no embeddings",
+			from:    sdktranslator.FormatClaude,
+			payload: `{"input":"x"}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := ToGemini(tc.from, "text-embedding-004", []byte(tc.payload))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ToGemini() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && string(out) != tc.want {
+				t.Fatalf("ToGemini() = %s, want %s", out, tc.want)
+			}
+		})
+	}
+}
+
+func TestFromGeminiToOpenAI(t *testing.T) {
+	data := []byte(`{"embeddings":[{"values":[0.5,-1]},{"values":[0.25,2]}],"usageMetadata":{"promptTokenCount":7}}`)
+	testCases := []struct {
+		name    string
+		request string
+		check   func(t *testing.T, out []byte)
+	}{
+		{
+			name:    "float encoding",
+			request: `{"input":["a","b"]}`,
+			check: func(t *testing.T, out []byte) {
+				if got := gjson.GetBytes(out, "data.1.embedding").Raw; got != `[0.25,2]` {
+					t.Fatalf("data.1.embedding = %s", got)
+				}
+			},
+		},
+		{
+			name:    "base64 encoding round trips through FromOpenAI",
+			request: `{"input":["a","b"],"encoding_format":"base64"}`,
+			check: func(t *testing.T, out []byte) {
+				if gjson.GetBytes(out, "data.0.embedding").Type != gjson.String {
+					t.Fatalf("data.0.embedding = %s, want a base64 string", gjson.GetBytes(out, "data.0.embedding").Raw)
+				}
+				gemini := FromOpenAI(sdktranslator.FormatGemini, out)
+				if got := gjson.GetBytes(gemini, "embeddings.#.values").Raw; got != `[[0.5,-1],[0.25,2]]` {
+					t.Fatalf("decoded values = %s", got)
+				}
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := FromGemini(sdktranslator.FormatOpenAI, "text-embedding-004", []byte(tc.request), data)
+			if gjson.GetBytes(out, "object").String() != "list" || gjson.GetBytes(out, "model").String() != "text-embedding-004" {
+				t.Fatalf("FromGemini() = %s", out)
+			}
+			if got := gjson.GetBytes(out, "data.#.index").Raw; got != `[0,1]` {
+				t.Fatalf("indexes = %s", got)
+			}
+			if got := gjson.GetBytes(out, "usage.total_tokens").Int(); got != 7 {
+				t.Fatalf("usage.total_tokens = %d, want 7", got)
+			}
+			tc.check(t, out)
+		})
+	}
+	if out := FromGemini(sdktranslator.FormatGemini, "m", nil, data); string(out) != string(data) {
+		t.Fatalf("FromGemini() to gemini changed the response: %s", out)
+	}
+}
+
+func TestToOpenAIFromGemini(t *testing.T) {
+	payload := []byte(`{"requests":[{"content":{"parts":[{"text":"line 1"},{"text":"line 2"}]},"outputDimensionality":64},{"content":{"parts":[{"text":"b"}]}}]}`)
+	out, err := ToOpenAI(sdktranslator.FormatGemini, "text-embedding-3-small", payload)
+	if err != nil {
+		t.Fatalf("ToOpenAI() error = %v", err)
+	}
+	want := `{"model":"text-embedding-3-small","input":["line 1\nline 2","b"],"encoding_format":"float","dimensions":64}`
+	if string(out) != want {
+		t.Fatalf("ToOpenAI() = %s, want %s", out, want)
+	}
+
+	// OpenAI indexes may arrive out of order.
+	response := []byte(`{"data":[{"index":1,"embedding":[2]},{"index":0,"embedding":[1]}]}`)
+	if got := string(FromOpenAI(sdktranslator.FormatGemini, response)); got != `{"embeddings":[{"values":[1]},{"values":[2]}]}` {
+		t.Fatalf("FromOpenAI() = %s", got)
+	}
+}
+
+func TestVertexConversion(t *testing.T) {
+	gemini := []byte(`{"requests":[{"content":{"parts":[{"text":"doc"}]},"taskType":"RETRIEVAL_DOCUMENT","title":"Title","outputDimensionality":128},{"content":{"parts":[{"text":"query"}]}}]}`)
+	want := `{"instances":[{"content":"doc","task_type":"RETRIEVAL_DOCUMENT","title":"Title"},{"content":"query"}],"parameters":{"outputDimensionality":128}}`
+	if got := string(GeminiToVertex(gemini)); got != want {
+		t.Fatalf("GeminiToVertex() = %s, want %s", got, want)
+	}
+
+	vertex := []byte(`{"predictions":[{"embeddings":{"values":[0.1,0.2],"statistics":{"token_count":3}}},{"embeddings":{"values":[0.3],"statistics":{"token_count":2}}}]}`)
+	out, tokens := VertexToGemini(vertex)
+	if tokens != 5 {
+		t.Fatalf("VertexToGemini() tokens = %d, want 5", tokens)
+	}
+	if got := string(out); got != `{"embeddings":[{"values":[0.1,0.2]},{"values":[0.3]}],"usageMetadata":{"promptTokenCount":5}}` {
+		t.Fatalf("VertexToGemini() = %s", got)
+	}
+}
+
+func TestSingleAndBatch(t *testing.T) {
+	single := []byte(`{"content":{"parts":[{"text":"x"}]}}`)
+	if got := string(SingleToBatch(single)); got != `{"requests":[{"content":{"parts":[{"text":"x"}]}}]}` {
+		t.Fatalf("SingleToBatch() = %s", got)
+	}
+	if got := string(BatchToSingle([]byte(`{"embeddings":[{"values":[1,2]}]}`))); got != `{"embedding":{"values":[1,2]}}` {
+		t.Fatalf("BatchToSingle() = %s", got)
+	}
+}
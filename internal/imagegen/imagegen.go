@@ -0,0 +1,187 @@
+// Package imagegen converts OpenAI images API requests and responses to and from
+// Gemini generateContent calls on image-capable models, and keeps generated images
+// that clients asked to receive as URLs.
+//
+// Requests use the OpenAI images/generations JSON body. Edit requests carry their
+// source images inline as an "images" array of {"mime_type","data"} objects (and an
+// optional "mask" object of the same shape); the HTTP handler builds these from the
+// multipart upload.
+package imagegen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// MaxImages bounds the n parameter of a single request.
+const MaxImages = 10
+
+// maskInstruction tells Gemini how to use an OpenAI edit mask, which it has no native support for.
+const maskInstruction = "The last image is a mask: only change the areas where the mask is transparent and keep everything else unchanged."
+
+// supportedAspectRatios lists the aspect ratios accepted by Gemini imageConfig.
+var supportedAspectRatios = []struct {
+	name  string
+	ratio float64
+}{
+	{"1:1", 1}, {"2:3", 2.0 / 3}, {"3:2", 3.0 / 2}, {"3:4", 3.0 / 4}, {"4:3", 4.0 / 3},
+	{"4:5", 4.0 / 5}, {"5:4", 5.0 / 4}, {"9:16", 9.0 / 16}, {"16:9", 16.0 / 9}, {"21:9", 21.0 / 9},
+}
+
+// IsEdit reports whether payload is an edit request, i.e. carries source images.
+func IsEdit(payload []byte) bool {
+	return len(gjson.GetBytes(payload, "images").Array()) > 0
+}
+
+// Count returns the number of images requested, clamped to [1, MaxImages].
+func Count(payload []byte) int {
+	n := int(gjson.GetBytes(payload, "n").Int())
+	if n < 1 {
+		return 1
+	}
+	if n > MaxImages {
+		return MaxImages
+	}
+	return n
+}
+
+// ToGemini converts an OpenAI images request into a Gemini generateContent body that
+// produces one image.
+func ToGemini(payload []byte) ([]byte, error) {
+	prompt := strings.TrimSpace(gjson.GetBytes(payload, "prompt").String())
+	if prompt == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+	out := []byte(`{"contents":[{"role":"user","parts":[]}],"generationConfig":{"responseModalities":["IMAGE","TEXT"]}}`)
+	for _, image := range gjson.GetBytes(payload, "images").Array() {
+		if out = appendInlineImage(out, image); out == nil {
+			return nil, fmt.Errorf("images must carry base64 data")
+		}
+	}
+	if mask := gjson.GetBytes(payload, "mask"); mask.Exists() {
+		if out = appendInlineImage(out, mask); out == nil {
+			return nil, fmt.Errorf("mask must carry base64 data")
+		}
+		prompt = prompt + "\n\n" + maskInstruction
+	}
+	textPart, _ := sjson.SetBytes([]byte(`{"text":""}`), "text", prompt)
+	out, _ = sjson.SetRawBytes(out, "contents.0.parts.-1", textPart)
+	if ratio := AspectRatio(gjson.GetBytes(payload, "size").String()); ratio != "" {
+		out, _ = sjson.SetBytes(out, "generationConfig.imageConfig.aspectRatio", ratio)
+	}
+	return out, nil
+}
+
+// FromGemini merges the generateContent responses of one request into an OpenAI
+// images response with b64_json data. It fails when no response contains an image,
+// returning the model's text, if any, as the reason.
+func FromGemini(created int64, responses [][]byte) ([]byte, error) {
+	out := []byte(`{"created":0,"data":[]}`)
+	out, _ = sjson.SetBytes(out, "created", created)
+	var inputTokens, outputTokens, totalTokens int64
+	var texts []string
+	images := 0
+	for _, data := range responses {
+		root := gjson.ParseBytes(data)
+		var revised []string
+		for _, part := range root.Get("candidates.0.content.parts").Array() {
+			inline := part.Get("inlineData")
+			if !inline.Exists() {
+				inline = part.Get("inline_data")
+			}
+			if inline.Exists() && inline.Get("data").String() != "" {
+				entry := []byte(`{"b64_json":""}`)
+				entry, _ = sjson.SetBytes(entry, "b64_json", inline.Get("data").String())
+				if text := strings.TrimSpace(strings.Join(revised, "\n")); text != "" {
+					entry, _ = sjson.SetBytes(entry, "revised_prompt", text)
+				}
+				out, _ = sjson.SetRawBytes(out, "data.-1", entry)
+				images++
+				continue
+			}
+			if text := part.Get("text").String(); text != "" && !part.Get("thought").Bool() {
+				revised = append(revised, text)
+				texts = append(texts, text)
+			}
+		}
+		usage := root.Get("usageMetadata")
+		prompt, candidates := usage.Get("promptTokenCount").Int(), usage.Get("candidatesTokenCount").Int()
+		inputTokens += prompt
+		outputTokens += candidates
+		if total := usage.Get("totalTokenCount").Int(); total > 0 {
+			totalTokens += total
+		} else {
+			totalTokens += prompt + candidates
+		}
+	}
+	if images == 0 {
+		reason := strings.TrimSpace(strings.Join(texts, "\n"))
+		if reason == "" {
+			reason = "the model did not return an image"
+		}
+		return nil, fmt.Errorf("%s", reason)
+	}
+	if totalTokens > 0 {
+		usage := []byte(`{"input_tokens":0,"output_tokens":0,"total_tokens":0}`)
+		usage, _ = sjson.SetBytes(usage, "input_tokens", inputTokens)
+		usage, _ = sjson.SetBytes(usage, "output_tokens", outputTokens)
+		usage, _ = sjson.SetBytes(usage, "total_tokens", totalTokens)
+		out, _ = sjson.SetRawBytes(out, "usage", usage)
+	}
+	return out, nil
+}
+
+// AspectRatio maps an OpenAI size such as "1792x1024" to the closest aspect ratio
+// Gemini supports. Ratios given directly (e.g. "16:9") are passed through when
+// supported. It returns "" for empty, "auto" or unparsable sizes.
+func AspectRatio(size string) string {
+	size = strings.ToLower(strings.TrimSpace(size))
+	if size == "" || size == "auto" {
+		return ""
+	}
+	for _, candidate := range supportedAspectRatios {
+		if size == candidate.name {
+			return candidate.name
+		}
+	}
+	w, h, ok := strings.Cut(size, "x")
+	if !ok {
+		return ""
+	}
+	width, errW := strconv.ParseFloat(w, 64)
+	height, errH := strconv.ParseFloat(h, 64)
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return ""
+	}
+	target := width / height
+	best, bestDiff := "", math.MaxFloat64
+	for _, candidate := range supportedAspectRatios {
+		if diff := math.Abs(math.Log(target / candidate.ratio)); diff < bestDiff {
+			best, bestDiff = candidate.name, diff
+		}
+	}
+	return best
+}
+
+// appendInlineImage appends an {"mime_type","data"} image as an inlineData part.
+// It returns nil when the image carries no data.
+func appendInlineImage(out []byte, image gjson.Result) []byte {
+	data := image.Get("data").String()
+	if data == "" {
+		return nil
+	}
+	mimeType := image.Get("mime_type").String()
+	if mimeType == "" {
+		mimeType = "image/png"
+	}
+	part := []byte(`{"inlineData":{"mime_type":"","data":""}}`)
+	part, _ = sjson.SetBytes(part, "inlineData.mime_type", mimeType)
+	part, _ = sjson.SetBytes(part, "inlineData.data", data)
+	out, _ = sjson.SetRawBytes(out, "contents.0.parts.-1", part)
+	return out
+}
@@ -0,0 +1,235 @@
+package imagegen
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestToGemini(t *testing.T) {
+	testCases := []struct {
+		name      string
+		payload   string
+		wantErr   bool
+		wantParts []string
+		wantRatio string
+	}{
+		{
+			name:      "generation with size",
+			payload:   `{"prompt":"a red fox","size":"1792x1024"}`,
+			wantParts: []string{"text:a red fox"},
+			wantRatio: "16:9",
+		},
+		{
+			name:      "edit with source images and mask",
+			payload:   `{"prompt":"add a hat","images":[{"mime_type":"image/jpeg","data":"SU1H"},{"data":"SU1HMg=="}],"mask":{"data":"TUFTSw=="}}`,
+			wantParts: []string{"image/jpeg:SU1H", "image/png:SU1HMg==", "image/png:TUFTSw==", "text:add a hat\n\n" + maskInstruction},
+		},
+		{
+			name:    "missing prompt",
+			payload: `{"prompt":"  "}`,
+			wantErr: true,
+		},
+		{
+			name:    "image without data",
+			payload: `{"prompt":"edit","images":[{"mime_type":"image/png"}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := ToGemini([]byte(tc.payload))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ToGemini() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			var parts []string
+			for _, part := range gjson.GetBytes(out, "contents.0.parts").Array() {
+				if inline := part.Get("inlineData"); inline.Exists() {
+					parts = append(parts, inline.Get("mime_type").String()+":"+inline.Get("data").String())
+				} else {
+					parts = append(parts, "text:"+part.Get("text").String())
+				}
+			}
+			if strings.Join(parts, "|") != strings.Join(tc.wantParts, "|") {
+				t.Fatalf("parts = %q, want %q", parts, tc.wantParts)
+			}
+			if got := gjson.GetBytes(out, "generationConfig.imageConfig.aspectRatio").String(); got != tc.wantRatio {
+				t.Fatalf("aspectRatio = %q, want %q", got, tc.wantRatio)
+			}
+			if got := gjson.GetBytes(out, "generationConfig.responseModalities.0").String(); got != "IMAGE" {
+				t.Fatalf("responseModalities = %s, want IMAGE first", gjson.GetBytes(out, "generationConfig.responseModalities").Raw)
+			}
+		})
+	}
+}
+
+func TestFromGemini(t *testing.T) {
+	imageResponse := `{"candidates":[{"content":{"parts":[{"text":"thinking","thought":true},{"text":"A fox in snow"},{"inlineData":{"mimeType":"image/png","data":"UE5H"}}]}}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":1290}}`
+	textOnly := `{"candidates":[{"content":{"parts":[{"text":"I can't draw that."}]}}]}`
+	testCases := []struct {
+		name        string
+		responses   []string
+		wantErr     string
+		wantImages  []string
+		wantRevised string
+		wantTotal   int64
+	}{
+		{
+			name:        "image with revised prompt and usage",
+			responses:   []string{imageResponse},
+			wantImages:  []string{"UE5H"},
+			wantRevised: "A fox in snow",
+			wantTotal:   1300,
+		},
+		{
+			name:        "several responses are merged",
+			responses:   []string{imageResponse, `{"candidates":[{"content":{"parts":[{"inline_data":{"data":"U0VDT05E"}}]}}],"usageMetadata":{"totalTokenCount":5}}`},
+			wantImages:  []string{"UE5H", "U0VDT05E"},
+			wantRevised: "A fox in snow",
+			wantTotal:   1305,
+		},
+		{
+			name:      "text only explains the failure",
+			responses: []string{textOnly},
+			wantErr:   "I can't draw that.",
+		},
+		{
+			name:      "empty response",
+			responses: []string{`{}`},
+			wantErr:   "the model did not return an image",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			responses := make([][]byte, 0, len(tc.responses))
+			for _, response := range tc.responses {
+				responses = append(responses, []byte(response))
+			}
+			out, err := FromGemini(1700000000, responses)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("FromGemini() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromGemini() error = %v", err)
+			}
+			var images []string
+			for _, entry := range gjson.GetBytes(out, "data").Array() {
+				images = append(images, entry.Get("b64_json").String())
+			}
+			if strings.Join(images, ",") != strings.Join(tc.wantImages, ",") {
+				t.Fatalf("images = %v, want %v", images, tc.wantImages)
+			}
+			if got := gjson.GetBytes(out, "data.0.revised_prompt").String(); got != tc.wantRevised {
+				t.Fatalf("revised_prompt = %q, want %q", got, tc.wantRevised)
+			}
+			if got := gjson.GetBytes(out, "usage.total_tokens").Int(); got != tc.wantTotal {
+				t.Fatalf("usage.total_tokens = %d, want %d", got, tc.wantTotal)
+			}
+			if got := gjson.GetBytes(out, "created").Int(); got != 1700000000 {
+				t.Fatalf("created = %d", got)
+			}
+		})
+	}
+}
+
+func TestAspectRatio(t *testing.T) {
+	testCases := []struct {
+		size string
+		want string
+	}{
+		{size: "1024x1024", want: "1:1"},
+		{size: "1792x1024", want: "16:9"},
+		{size: "1024x1792", want: "9:16"},
+		{size: "1536x1024", want: "3:2"},
+		{size: "2560x1080", want: "21:9"},
+		{size: "4:3", want: "4:3"},
+		{size: "auto", want: ""},
+		{size: "", want: ""},
+		{size: "7:3", want: ""},
+		{size: "0x1024", want: ""},
+		{size: "large", want: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.size, func(t *testing.T) {
+			if got := AspectRatio(tc.size); got != tc.want {
+				t.Fatalf("AspectRatio(%q) = %q, want %q", tc.size, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	testCases := []struct {
+		payload string
+		want    int
+	}{
+		{payload: `{}`, want: 1},
+		{payload: `{"n":0}`, want: 1},
+		{payload: `{"n":3}`, want: 3},
+		{payload: `{"n":50}`, want: MaxImages},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.payload, func(t *testing.T) {
+			if got := Count([]byte(tc.payload)); got != tc.want {
+				t.Fatalf("Count(%s) = %d, want %d", tc.payload, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoreEviction(t *testing.T) {
+	testCases := []struct {
+		name       string
+		maxEntries int
+		maxBytes   int
+		ttl        time.Duration
+		sizes      []int
+		age        time.Duration
+		wantKept   []bool
+		wantPutErr bool
+	}{
+		{name: "entry limit evicts the oldest", maxEntries: 2, sizes: []int{1, 1, 1}, wantKept: []bool{false, true, true}},
+		{name: "byte limit evicts the oldest", maxBytes: 10, sizes: []int{4, 4, 4}, wantKept: []bool{false, true, true}},
+		{name: "expired images are gone", ttl: time.Minute, sizes: []int{1, 1}, age: 2 * time.Minute, wantKept: []bool{false, false}},
+		{name: "image larger than the store", maxBytes: 10, sizes: []int{11}, wantPutErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewStore(tc.maxEntries, tc.maxBytes, tc.ttl)
+			var ids []string
+			for _, size := range tc.sizes {
+				id, err := store.Put(make([]byte, size), "image/png")
+				if (err != nil) != tc.wantPutErr {
+					t.Fatalf("Put() error = %v, wantErr %v", err, tc.wantPutErr)
+				}
+				ids = append(ids, id)
+			}
+			if tc.age > 0 {
+				store.mu.Lock()
+				for elem := store.order.Front(); elem != nil; elem = elem.Next() {
+					entry := elem.Value.(storeEntry)
+					entry.image.CreatedAt = entry.image.CreatedAt.Add(-tc.age)
+					elem.Value = entry
+				}
+				store.mu.Unlock()
+			}
+			for i, want := range tc.wantKept {
+				image, ok := store.Get(ids[i])
+				if ok != want {
+					t.Fatalf("Get(image %d) found %v, want %v", i, ok, want)
+				}
+				if ok && (len(image.Data) != tc.sizes[i] || image.MimeType != "image/png") {
+					t.Fatalf("Get(image %d) = %d bytes of %s", i, len(image.Data), image.MimeType)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package imagegen
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults for the process-wide image store.
+const (
+	DefaultStoreTTL        = time.Hour
+	DefaultStoreMaxEntries = 256
+	DefaultStoreMaxBytes   = 256 << 20
+)
+
+// Image is a generated image kept for URL delivery.
+type Image struct {
+	Data      []byte
+	MimeType  string
+	CreatedAt time.Time
+}
+
+// Store keeps generated images in memory for a limited time so they can be served
+// from an unguessable URL. The oldest images are evicted once the store holds too
+// many images or too many bytes.
+type Store struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int
+	bytes      int
+}
+
+type storeEntry struct {
+	id    string
+	image Image
+}
+
+// NewStore creates an image store. Zero values use the package defaults.
+func NewStore(maxEntries, maxBytes int, ttl time.Duration) *Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultStoreMaxEntries
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultStoreMaxBytes
+	}
+	if ttl <= 0 {
+		ttl = DefaultStoreTTL
+	}
+	return &Store{entries: make(map[string]*list.Element), order: list.New(), ttl: ttl, maxEntries: maxEntries, maxBytes: maxBytes}
+}
+
+var defaultStore = NewStore(0, 0, 0)
+
+// DefaultStore returns the process-wide image store.
+func DefaultStore() *Store { return defaultStore }
+
+// TTL returns how long images stay available.
+func (s *Store) TTL() time.Duration { return s.ttl }
+
+// ErrImageTooLarge is returned by Put for an image larger than the whole store.
+var ErrImageTooLarge = errors.New("image exceeds the image store size limit")
+
+// Put stores an image and returns its id.
+func (s *Store) Put(data []byte, mimeType string) (string, error) {
+	if len(data) > s.maxBytes {
+		return "", ErrImageTooLarge
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(now)
+	s.entries[id] = s.order.PushFront(storeEntry{id: id, image: Image{Data: data, MimeType: mimeType, CreatedAt: now}})
+	s.bytes += len(data)
+	for s.order.Len() > s.maxEntries || s.bytes > s.maxBytes {
+		s.removeLocked(s.order.Back())
+	}
+	return id, nil
+}
+
+// Get returns the image with id if it exists and has not expired.
+func (s *Store) Get(id string) (Image, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(time.Now())
+	elem, ok := s.entries[id]
+	if !ok {
+		return Image{}, false
+	}
+	return elem.Value.(storeEntry).image, true
+}
+
+// pruneLocked drops expired images; the list is ordered newest first.
+func (s *Store) pruneLocked(now time.Time) {
+	for oldest := s.order.Back(); oldest != nil; oldest = s.order.Back() {
+		entry := oldest.Value.(storeEntry)
+		if now.Sub(entry.image.CreatedAt) <= s.ttl {
+			return
+		}
+		s.removeLocked(oldest)
+	}
+}
+
+func (s *Store) removeLocked(elem *list.Element) {
+	entry := elem.Value.(storeEntry)
+	s.order.Remove(elem)
+	delete(s.entries, entry.id)
+	s.bytes -= len(entry.image.Data)
+}
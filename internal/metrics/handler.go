@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contentType is the Prometheus text exposition format, which OpenMetrics scrapers also accept.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler serves the metrics exposition. It responds with 404 while collection is
+// disabled and with 401 to scrapers that fail the check in authorized.
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled.Load() {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if !authorized(c) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		var buf bytes.Buffer
+		Write(&buf)
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+	}
+}
+
+// authorized reports whether the scraper presented the configured bearer token,
+// or connects from loopback when no token is configured. The credential metrics
+// carry auth IDs, which often embed account e-mail addresses.
+//
+// The loopback check uses the TCP peer address rather than ClientIP, which trusts
+// a client-supplied X-Forwarded-For header.
+func authorized(c *gin.Context) bool {
+	sourceMu.RLock()
+	token := bearerToken
+	sourceMu.RUnlock()
+	if token == "" {
+		return isLoopback(c.Request.RemoteAddr)
+	}
+	provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(provided)), []byte(token)) == 1
+}
+
+// isLoopback reports whether remoteAddr, in "IP:port" form, is a loopback address.
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Write renders every metric family to w.
+func Write(w io.Writer) {
+	requestsTotal.write(w)
+	requestDuration.write(w)
+	upstreamRequestsTotal.write(w)
+	upstreamDuration.write(w)
+	usageRecordsTotal.write(w)
+	tokensTotal.write(w)
+	writeAuthMetrics(w)
+	writeWebsocketMetrics(w)
+}
+
+func writeAuthMetrics(w io.Writer) {
+	sourceMu.RLock()
+	source := authSource
+	sourceMu.RUnlock()
+	if source == nil {
+		return
+	}
+	auths := source()
+	sort.Slice(auths, func(i, j int) bool { return auths[i].ID < auths[j].ID })
+
+	now := time.Now()
+	labels := []string{"auth", "provider"}
+	var status, unavailable, disabled, quotaExceeded, backoff, retryIn []gaugeSample
+	for _, auth := range auths {
+		if auth == nil {
+			continue
+		}
+		base := []string{auth.ID, auth.Provider}
+		status = append(status, gaugeSample{labelValues: append(append([]string(nil), base...), string(auth.Status)), value: 1})
+		unavailable = append(unavailable, gaugeSample{labelValues: base, value: boolValue(auth.Unavailable)})
+		disabled = append(disabled, gaugeSample{labelValues: base, value: boolValue(auth.Disabled)})
+		quotaExceeded = append(quotaExceeded, gaugeSample{labelValues: base, value: boolValue(auth.Quota.Exceeded)})
+		backoff = append(backoff, gaugeSample{labelValues: base, value: float64(auth.Quota.BackoffLevel)})
+		wait := 0.0
+		if auth.NextRetryAfter.After(now) {
+			wait = auth.NextRetryAfter.Sub(now).Seconds()
+		}
+		retryIn = append(retryIn, gaugeSample{labelValues: base, value: wait})
+	}
+	writeGauge(w, "cliproxy_auth_status", "Credential lifecycle status (1 for the current status).", []string{"auth", "provider", "status"}, status)
+	writeGauge(w, "cliproxy_auth_unavailable", "Whether the credential is temporarily unavailable.", labels, unavailable)
+	writeGauge(w, "cliproxy_auth_disabled", "Whether the credential is disabled.", labels, disabled)
+	writeGauge(w, "cliproxy_auth_quota_exceeded", "Whether the credential recently exceeded its quota.", labels, quotaExceeded)
+	writeGauge(w, "cliproxy_auth_backoff_level", "Progressive quota cooldown level of the credential.", labels, backoff)
+	writeGauge(w, "cliproxy_auth_next_retry_seconds", "Seconds until the credential may be retried.", labels, retryIn)
+}
+
+func writeWebsocketMetrics(w io.Writer) {
+	sourceMu.RLock()
+	source := wsSessionSource
+	sourceMu.RUnlock()
+	if source == nil {
+		return
+	}
+	writeGauge(w, "cliproxy_wsrelay_sessions", "Connected ws-relay provider sessions.", nil, []gaugeSample{{value: float64(source())}})
+}
+
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlerAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetEnabled(true)
+	t.Cleanup(func() {
+		SetEnabled(false)
+		SetBearerToken("")
+	})
+
+	testCases := []struct {
+		name       string
+		token      string
+		remoteAddr string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "loopback without token",
+			remoteAddr: "127.0.0.1:51234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "ipv6 loopback without token",
+			remoteAddr: "[::1]:51234",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "remote client without token",
+			remoteAddr: "203.0.113.7:51234",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "remote client spoofing forwarded headers",
+			remoteAddr: "203.0.113.7:51234",
+			headers:    map[string]string{"X-Forwarded-For": "127.0.0.1", "X-Real-IP": "127.0.0.1"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "valid bearer token",
+			token:      "scrape-secret",
+			remoteAddr: "203.0.113.7:51234",
+			headers:    map[string]string{"Authorization": "Bearer scrape-secret"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong bearer token",
+			token:      "scrape-secret",
+			remoteAddr: "203.0.113.7:51234",
+			headers:    map[string]string{"Authorization": "Bearer guess"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "token required from loopback too",
+			token:      "scrape-secret",
+			remoteAddr: "127.0.0.1:51234",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetBearerToken(tc.token)
+			engine := gin.New()
+			engine.GET("/metrics", Handler())
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("GET /metrics status = %d, want %d", rr.Code, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestObserveRequestProviderLabel(t *testing.T) {
+	SetEnabled(true)
+	t.Cleanup(func() { SetEnabled(false) })
+
+	ObserveRequest("openai", "metrics-test-provider", "unregistered-model", http.StatusOK, 0)
+
+	var buf strings.Builder
+	requestsTotal.write(&buf)
+	want := `cliproxy_requests_total{handler="openai",provider="metrics-test-provider",model="other",status="200"} 1`
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("requests_total output = %s, want a line %s", buf.String(), want)
+	}
+}
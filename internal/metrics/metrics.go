@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+var enabled atomic.Bool
+
+var (
+	requestsTotal = newCounterVec(
+		"cliproxy_requests_total",
+		"Client requests handled by the proxy.",
+		"handler", "provider", "model", "status",
+	)
+	requestDuration = newHistogramVec(
+		"cliproxy_request_duration_seconds",
+		"Client request latency, including the full stream duration for streaming requests.",
+		defaultDurationBuckets,
+		"handler", "model",
+	)
+	upstreamRequestsTotal = newCounterVec(
+		"cliproxy_upstream_requests_total",
+		"Provider attempts dispatched by the auth manager.",
+		"provider", "model", "status",
+	)
+	upstreamDuration = newHistogramVec(
+		"cliproxy_upstream_request_duration_seconds",
+		"Provider attempt latency, including the full stream duration for streaming requests.",
+		defaultDurationBuckets,
+		"provider", "model",
+	)
+	tokensTotal = newCounterVec(
+		"cliproxy_tokens_total",
+		"Tokens reported by upstream providers.",
+		"provider", "model", "type",
+	)
+	usageRecordsTotal = newCounterVec(
+		"cliproxy_usage_records_total",
+		"Usage records emitted by provider executors.",
+		"provider", "model", "result",
+	)
+)
+
+var (
+	sourceMu        sync.RWMutex
+	authSource      func() []*coreauth.Auth
+	wsSessionSource func() int
+	bearerToken     string
+)
+
+// otherModel replaces model labels the registry does not know, so clients cannot
+// grow the label space with arbitrary model names.
+const otherModel = "other"
+
+func init() {
+	coreusage.RegisterPlugin(usagePlugin{})
+}
+
+// SetEnabled toggles metric collection and the /metrics endpoint.
+func SetEnabled(value bool) { enabled.Store(value) }
+
+// Enabled reports whether metric collection is active.
+func Enabled() bool { return enabled.Load() }
+
+// SetBearerToken sets the token /metrics requires in the Authorization header.
+// With no token, only loopback clients may scrape.
+func SetBearerToken(token string) {
+	sourceMu.Lock()
+	bearerToken = token
+	sourceMu.Unlock()
+}
+
+// SetAuthSource registers the function used to read credential state at scrape time.
+func SetAuthSource(source func() []*coreauth.Auth) {
+	sourceMu.Lock()
+	authSource = source
+	sourceMu.Unlock()
+}
+
+// SetWebsocketSessionSource registers the function reporting connected ws-relay sessions.
+func SetWebsocketSessionSource(source func() int) {
+	sourceMu.Lock()
+	wsSessionSource = source
+	sourceMu.Unlock()
+}
+
+// ObserveRequest records a completed client request.
+//
+// Parameters:
+//   - handler: The API handler type (openai, claude, gemini, ...)
+//   - provider: The provider of the credential that served the request, or empty
+//     when the request failed before one was selected
+//   - model: The model requested by the client
+//   - status: The HTTP status returned to the client
+//   - duration: Time spent serving the request
+func ObserveRequest(handler, provider, model string, status int, duration time.Duration) {
+	if !enabled.Load() {
+		return
+	}
+	model = modelLabel(model)
+	requestsTotal.add(1, handler, provider, model, strconv.Itoa(status))
+	requestDuration.observe(duration.Seconds(), handler, model)
+}
+
+// usagePlugin converts usage records into token counters.
+type usagePlugin struct{}
+
+// HandleUsage implements coreusage.Plugin.
+func (usagePlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	if !enabled.Load() {
+		return
+	}
+	result := "success"
+	if record.Failed {
+		result = "failure"
+	}
+	model := modelLabel(record.Model)
+	usageRecordsTotal.add(1, record.Provider, model, result)
+	detail := record.Detail
+	for _, entry := range []struct {
+		kind  string
+		value int64
+	}{
+		{"input", detail.InputTokens},
+		{"output", detail.OutputTokens},
+		{"reasoning", detail.ReasoningTokens},
+		{"cached", detail.CachedTokens},
+	} {
+		if entry.value > 0 {
+			tokensTotal.add(float64(entry.value), record.Provider, model, entry.kind)
+		}
+	}
+}
+
+// NewExecutionHook returns a coreauth.ExecutionHook that records upstream attempt
+// counts and latency by provider, model and status.
+func NewExecutionHook() coreauth.ExecutionHook { return executionHook{} }
+
+type executionHook struct{}
+
+// startedAtKey stores the attempt start time on the Execution.
+type startedAtKey struct{}
+
+// Enabled lets the auth manager skip the hook while metrics are disabled.
+func (executionHook) Enabled() bool { return enabled.Load() }
+
+func (executionHook) BeforeExecute(_ context.Context, exec *coreauth.Execution) {
+	if exec == nil {
+		return
+	}
+	exec.SetValue(startedAtKey{}, time.Now())
+}
+
+func (executionHook) AfterExecute(_ context.Context, exec *coreauth.Execution, _ cliproxyexecutor.Response, err error) {
+	if exec == nil {
+		return
+	}
+	started, ok := exec.Value(startedAtKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	status := http.StatusOK
+	if err != nil {
+		status = statusFromError(err)
+	}
+	model := modelLabel(exec.Request.Model)
+	upstreamRequestsTotal.add(1, exec.Provider, model, strconv.Itoa(status))
+	upstreamDuration.observe(time.Since(started).Seconds(), exec.Provider, model)
+}
+
+func (executionHook) OnStreamChunk(context.Context, *coreauth.Execution, cliproxyexecutor.StreamChunk) {
+}
+
+// modelLabel returns model when the registry knows it and otherModel otherwise.
+func modelLabel(model string) string {
+	if model == "" || registry.GetGlobalRegistry().GetModelInfo(model) == nil {
+		return otherModel
+	}
+	return model
+}
+
+func statusFromError(err error) int {
+	var se interface{ StatusCode() int }
+	if errors.As(err, &se) && se != nil {
+		if code := se.StatusCode(); code > 0 {
+			return code
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		return 499
+	}
+	return http.StatusInternalServerError
+}
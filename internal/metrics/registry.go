@@ -0,0 +1,207 @@
+// Package metrics exposes proxy, upstream and credential health signals in the
+// Prometheus text exposition format. It intentionally implements the small subset
+// of the format it needs instead of depending on a client library.
+package metrics
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultDurationBuckets covers fast failures through long streaming completions.
+var defaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// labelSeparator joins label values into map keys; it cannot appear in valid UTF-8 text.
+const labelSeparator = "\xff"
+
+// counterVec is a monotonically increasing value partitioned by label values.
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]*counterSeries)}
+}
+
+func (c *counterVec) add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	key := strings.Join(labelValues, labelSeparator)
+	c.mu.Lock()
+	series, ok := c.values[key]
+	if !ok {
+		series = &counterSeries{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = series
+	}
+	series.value += delta
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		series := c.values[key]
+		writeSample(w, c.name, c.labels, series.labelValues, nil, series.value)
+	}
+}
+
+// histogramVec tracks observation distributions partitioned by label values.
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramSeries)}
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+	h.mu.Lock()
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = series
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			series.counts[i]++
+		}
+	}
+	series.sum += value
+	series.count++
+	h.mu.Unlock()
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		series := h.values[key]
+		for i, upper := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labels, series.labelValues, []string{"le", formatFloat(upper)}, float64(series.counts[i]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, series.labelValues, []string{"le", "+Inf"}, float64(series.count))
+		writeSample(w, h.name+"_sum", h.labels, series.labelValues, nil, series.sum)
+		writeSample(w, h.name+"_count", h.labels, series.labelValues, nil, float64(series.count))
+	}
+}
+
+// gaugeSample is a single point-in-time gauge value collected at scrape time.
+type gaugeSample struct {
+	labelValues []string
+	value       float64
+}
+
+func writeGauge(w io.Writer, name, help string, labels []string, samples []gaugeSample) {
+	writeHeader(w, name, help, "gauge")
+	for _, sample := range samples {
+		writeSample(w, name, labels, sample.labelValues, nil, sample.value)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	_, _ = io.WriteString(w, "# HELP "+name+" "+escapeHelp(help)+"\n")
+	_, _ = io.WriteString(w, "# TYPE "+name+" "+kind+"\n")
+}
+
+func writeSample(w io.Writer, name string, labels, labelValues, extra []string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 || len(extra) > 0 {
+		b.WriteByte('{')
+		first := true
+		for i, label := range labels {
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			v := ""
+			if i < len(labelValues) {
+				v = labelValues[i]
+			}
+			b.WriteString(label)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(v))
+			b.WriteByte('"')
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			b.WriteString(extra[i])
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(extra[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+	_, _ = io.WriteString(w, b.String())
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(v string) string { return labelValueReplacer.Replace(v) }
+
+func escapeHelp(v string) string { return helpReplacer.Replace(v) }
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net/url"
+	"strings"
 )
 
 // GenerateRandomState generates a cryptographically secure random state parameter
@@ -19,3 +21,64 @@ func GenerateRandomState() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// OAuthCallback holds the parameters an OAuth provider appends to its redirect URL.
+type OAuthCallback struct {
+	Code  string
+	State string
+	Error string
+}
+
+// ParseOAuthCallback extracts the authorization result from text a user copied out
+// of the browser during a headless login. It accepts the full redirect URL, its
+// query string alone, or a bare "code" / "code#state" value as shown by some
+// providers' manual-copy pages. State is empty when the pasted text carries none.
+//
+// Parameters:
+//   - raw: The pasted redirect URL or code
+//
+// Returns:
+//   - *OAuthCallback: The parsed code, state and error
+//   - error: An error if no authorization code or error could be found
+func ParseOAuthCallback(raw string) (*OAuthCallback, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("no redirect URL provided")
+	}
+	query := ""
+	switch {
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect URL: %w", err)
+		}
+		query = u.RawQuery
+		if query == "" && u.Fragment != "" {
+			query = u.Fragment
+		}
+		if query == "" {
+			return nil, fmt.Errorf("redirect URL has no authorization code")
+		}
+	case strings.HasPrefix(raw, "?"):
+		query = raw[1:]
+	case strings.Contains(raw, "code=") || strings.Contains(raw, "error="):
+		query = raw
+	}
+	if query == "" {
+		code, state, _ := strings.Cut(raw, "#")
+		return &OAuthCallback{Code: code, State: state}, nil
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URL query: %w", err)
+	}
+	result := &OAuthCallback{
+		Code:  values.Get("code"),
+		State: values.Get("state"),
+		Error: values.Get("error"),
+	}
+	if result.Error == "" && result.Code == "" {
+		return nil, fmt.Errorf("redirect URL has no authorization code")
+	}
+	return result, nil
+}
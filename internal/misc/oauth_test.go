@@ -0,0 +1,96 @@
+package misc
+
+import "testing"
+
+func TestParseOAuthCallback(t *testing.T) {
+	testCases := []struct {
+		name    string
+		raw     string
+		want    OAuthCallback
+		wantErr bool
+	}{
+		{
+			name: "full redirect url",
+			raw:  "http://localhost:54545/callback?code=abc123&state=xyz",
+			want: OAuthCallback{Code: "abc123", State: "xyz"},
+		},
+		{
+			name: "surrounding whitespace",
+			raw:  "  http://localhost:1455/auth/callback?code=abc123&state=xyz\n",
+			want: OAuthCallback{Code: "abc123", State: "xyz"},
+		},
+		{
+			name: "escaped values",
+			raw:  "https://console.anthropic.com/oauth/code/callback?code=a%2Fb%3Dc&state=s%20t",
+			want: OAuthCallback{Code: "a/b=c", State: "s t"},
+		},
+		{
+			name: "parameters in the fragment",
+			raw:  "http://localhost:8085/oauth2callback#code=abc123&state=xyz",
+			want: OAuthCallback{Code: "abc123", State: "xyz"},
+		},
+		{
+			name: "provider error",
+			raw:  "http://localhost:54545/callback?error=access_denied&state=xyz",
+			want: OAuthCallback{State: "xyz", Error: "access_denied"},
+		},
+		{
+			name: "query string only",
+			raw:  "?code=abc123&state=xyz",
+			want: OAuthCallback{Code: "abc123", State: "xyz"},
+		},
+		{
+			name: "query string without question mark",
+			raw:  "code=abc123&state=xyz",
+			want: OAuthCallback{Code: "abc123", State: "xyz"},
+		},
+		{
+			name: "bare code",
+			raw:  "abc123",
+			want: OAuthCallback{Code: "abc123"},
+		},
+		{
+			name: "code with state",
+			raw:  "abc123#xyz",
+			want: OAuthCallback{Code: "abc123", State: "xyz"},
+		},
+		{
+			name:    "empty",
+			raw:     "   ",
+			wantErr: true,
+		},
+		{
+			name:    "redirect url without parameters",
+			raw:     "http://localhost:54545/callback",
+			wantErr: true,
+		},
+		{
+			name:    "redirect url without code",
+			raw:     "http://localhost:54545/callback?state=xyz",
+			wantErr: true,
+		},
+		{
+			name:    "malformed query",
+			raw:     "?code=%zz",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseOAuthCallback(tc.raw)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseOAuthCallback(%q) = %+v, want an error", tc.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOAuthCallback(%q) error = %v", tc.raw, err)
+			}
+			if *got != tc.want {
+				t.Fatalf("ParseOAuthCallback(%q) = %+v, want %+v", tc.raw, *got, tc.want)
+			}
+		})
+	}
+}
@@ -549,6 +549,13 @@ func (r *ModelRegistry) ClientSupportsModel(clientID, modelID string) bool {
 	return false
 }
 
+// ClientModels returns the IDs of the models registered for the client, in registration order.
+func (r *ModelRegistry) ClientModels(clientID string) []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]string(nil), r.clientModels[strings.TrimSpace(clientID)]...)
+}
+
 // GetAvailableModels returns all models that have at least one available client
 // Parameters:
 //   - handlerType: The handler type to filter models for (e.g., "openai", "claude", "gemini")
@@ -0,0 +1,111 @@
+package responses
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileBackend stores each response as a JSON file inside a directory.
+type FileBackend struct {
+	dir string
+}
+
+// NewFileBackend creates a file backend rooted at dir, creating it if needed.
+func NewFileBackend(dir string) (*FileBackend, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("responses file backend: directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("responses file backend: create directory: %w", err)
+	}
+	return &FileBackend{dir: dir}, nil
+}
+
+// Dir returns the backing directory.
+func (b *FileBackend) Dir() string { return b.dir }
+
+// Save implements Backend.
+func (b *FileBackend) Save(_ context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("responses file backend: marshal: %w", err)
+	}
+	path := b.path(record.ID)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("responses file backend: write: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("responses file backend: rename: %w", err)
+	}
+	return nil
+}
+
+// Load implements Backend.
+func (b *FileBackend) Load(_ context.Context, id string) (Record, bool, error) {
+	data, err := os.ReadFile(b.path(id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Record{}, false, nil
+		}
+		return Record{}, false, fmt.Errorf("responses file backend: read: %w", err)
+	}
+	var record Record
+	if err = json.Unmarshal(data, &record); err != nil {
+		return Record{}, false, fmt.Errorf("responses file backend: decode %s: %w", id, err)
+	}
+	return record, record.ID == id, nil
+}
+
+// Delete implements Backend.
+func (b *FileBackend) Delete(_ context.Context, id string) error {
+	if err := os.Remove(b.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("responses file backend: delete: %w", err)
+	}
+	return nil
+}
+
+// Prune implements Backend using file modification times.
+func (b *FileBackend) Prune(ctx context.Context, before time.Time) error {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return fmt.Errorf("responses file backend: list: %w", err)
+	}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		_ = os.Remove(filepath.Join(b.dir, entry.Name()))
+	}
+	return nil
+}
+
+// path maps a response id to a file name, hashing ids that are not filename-safe.
+func (b *FileBackend) path(id string) string {
+	safe := id != "" && len(id) <= 128 && strings.IndexFunc(id, func(r rune) bool {
+		return !(r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) < 0
+	name := id
+	if !safe {
+		sum := sha256.Sum256([]byte(id))
+		name = "h_" + hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(b.dir, name+".json")
+}
@@ -0,0 +1,268 @@
+// Package responses stores completed OpenAI Responses API turns so clients can
+// chain requests with previous_response_id and fetch or delete stored responses.
+package responses
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults applied when the store is configured with zero values.
+const (
+	DefaultMaxEntries = 10000
+	DefaultTTL        = 30 * 24 * time.Hour
+	// maxChainDepth bounds previous_response_id walks to guard against cycles.
+	maxChainDepth = 1000
+	pruneInterval = time.Hour
+)
+
+// ErrNotFound is returned when a response id is unknown, expired or owned by another client.
+var ErrNotFound = errors.New("response not found")
+
+// Record is one stored response turn. Only the turn's own input is kept; earlier
+// turns are reached through PreviousID. Owner holds util.HashAPIKey of the client
+// key that created the turn, never the key itself.
+type Record struct {
+	ID         string          `json:"id"`
+	PreviousID string          `json:"previous_response_id,omitempty"`
+	Owner      string          `json:"owner,omitempty"`
+	Model      string          `json:"model,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Input      json.RawMessage `json:"input"`
+	Output     json.RawMessage `json:"output"`
+	Response   json.RawMessage `json:"response"`
+}
+
+// Backend persists records beyond the in-memory cache.
+type Backend interface {
+	// Save stores or replaces a record.
+	Save(ctx context.Context, record Record) error
+	// Load returns the record with id; ok is false when it does not exist.
+	Load(ctx context.Context, id string) (record Record, ok bool, err error)
+	// Delete removes the record with id. Deleting a missing record is not an error.
+	Delete(ctx context.Context, id string) error
+	// Prune removes records created before the given time.
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// Store keeps recent records in an LRU cache, optionally backed by a Backend.
+type Store struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	maxEntries int
+	ttl        time.Duration
+	backend    Backend
+	disabled   bool
+	lastPrune  time.Time
+}
+
+// NewStore creates an in-memory store. Zero values use the package defaults.
+func NewStore(maxEntries int, ttl time.Duration) *Store {
+	s := &Store{entries: make(map[string]*list.Element), order: list.New()}
+	s.Configure(maxEntries, ttl)
+	return s
+}
+
+var defaultStore = func() *Store {
+	s := NewStore(0, 0)
+	s.disabled = true
+	return s
+}()
+
+// DefaultStore returns the process-wide response store. It records nothing until
+// enabled with SetDisabled(false).
+func DefaultStore() *Store { return defaultStore }
+
+// Configure updates the cache size and retention.
+func (s *Store) Configure(maxEntries int, ttl time.Duration) {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	s.mu.Lock()
+	s.maxEntries = maxEntries
+	s.ttl = ttl
+	s.evictLocked()
+	s.mu.Unlock()
+}
+
+// SetDisabled turns recording off; lookups still fail with ErrNotFound.
+func (s *Store) SetDisabled(disabled bool) {
+	s.mu.Lock()
+	s.disabled = disabled
+	s.mu.Unlock()
+}
+
+// Enabled reports whether responses are recorded.
+func (s *Store) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.disabled
+}
+
+// SetBackend attaches a persistent backend; nil keeps records in memory only.
+func (s *Store) SetBackend(backend Backend) {
+	s.mu.Lock()
+	s.backend = backend
+	s.mu.Unlock()
+}
+
+// Put records a completed response.
+func (s *Store) Put(ctx context.Context, record Record) error {
+	if record.ID == "" {
+		return errors.New("responses: record id is required")
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	if s.disabled {
+		s.mu.Unlock()
+		return nil
+	}
+	s.insertLocked(record)
+	backend := s.backend
+	prune := backend != nil && time.Since(s.lastPrune) >= pruneInterval
+	if prune {
+		s.lastPrune = time.Now()
+	}
+	ttl := s.ttl
+	s.mu.Unlock()
+
+	if backend == nil {
+		return nil
+	}
+	if prune {
+		go func() {
+			if err := backend.Prune(context.Background(), time.Now().Add(-ttl)); err != nil {
+				log.Warnf("responses: prune backend: %v", err)
+			}
+		}()
+	}
+	return backend.Save(ctx, record)
+}
+
+// Get returns the record with id when it exists and belongs to owner, the client's
+// API key; ownership is checked against the stored hash of that key.
+func (s *Store) Get(ctx context.Context, id, owner string) (Record, error) {
+	s.mu.Lock()
+	if s.disabled {
+		s.mu.Unlock()
+		return Record{}, ErrNotFound
+	}
+	elem, ok := s.entries[id]
+	var record Record
+	if ok {
+		record = elem.Value.(Record)
+		s.order.MoveToFront(elem)
+	}
+	backend := s.backend
+	ttl := s.ttl
+	s.mu.Unlock()
+
+	if !ok && backend != nil {
+		loaded, found, err := backend.Load(ctx, id)
+		if err != nil {
+			return Record{}, err
+		}
+		if found {
+			record, ok = loaded, true
+			s.mu.Lock()
+			s.insertLocked(record)
+			s.mu.Unlock()
+		}
+	}
+	if !ok || time.Since(record.CreatedAt) > ttl {
+		return Record{}, ErrNotFound
+	}
+	if record.Owner != "" && record.Owner != util.HashAPIKey(owner) {
+		return Record{}, ErrNotFound
+	}
+	return record, nil
+}
+
+// Delete removes the record with id when it belongs to owner.
+func (s *Store) Delete(ctx context.Context, id, owner string) error {
+	if _, err := s.Get(ctx, id, owner); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if elem, ok := s.entries[id]; ok {
+		s.order.Remove(elem)
+		delete(s.entries, id)
+	}
+	backend := s.backend
+	s.mu.Unlock()
+	if backend != nil {
+		return backend.Delete(ctx, id)
+	}
+	return nil
+}
+
+// History returns the input and output items of previousID and every earlier turn
+// in conversation order, ready to prepend to a new request's input.
+func (s *Store) History(ctx context.Context, previousID, owner string) ([]json.RawMessage, error) {
+	var turns []Record
+	seen := make(map[string]struct{})
+	for id := previousID; id != ""; {
+		if _, dup := seen[id]; dup || len(turns) >= maxChainDepth {
+			break
+		}
+		seen[id] = struct{}{}
+		record, err := s.Get(ctx, id, owner)
+		if err != nil {
+			if id == previousID {
+				return nil, err
+			}
+			// An older link expired; keep the part of the conversation still available.
+			break
+		}
+		turns = append(turns, record)
+		id = record.PreviousID
+	}
+	var items []json.RawMessage
+	for i := len(turns) - 1; i >= 0; i-- {
+		items = appendItems(items, turns[i].Input)
+		items = appendItems(items, turns[i].Output)
+	}
+	return items, nil
+}
+
+func appendItems(items []json.RawMessage, raw json.RawMessage) []json.RawMessage {
+	if len(raw) == 0 {
+		return items
+	}
+	var decoded []json.RawMessage
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return items
+	}
+	return append(items, decoded...)
+}
+
+func (s *Store) insertLocked(record Record) {
+	if elem, ok := s.entries[record.ID]; ok {
+		elem.Value = record
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.entries[record.ID] = s.order.PushFront(record)
+	s.evictLocked()
+}
+
+func (s *Store) evictLocked() {
+	for s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(Record).ID)
+	}
+}
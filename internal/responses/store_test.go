@@ -0,0 +1,236 @@
+package responses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+const testOwnerKey = "sk-responses-owner"
+
+func testRecord(id, previousID, input, output string) Record {
+	return Record{
+		ID:         id,
+		PreviousID: previousID,
+		Owner:      util.HashAPIKey(testOwnerKey),
+		Input:      json.RawMessage(`[` + input + `]`),
+		Output:     json.RawMessage(`[` + output + `]`),
+		Response:   json.RawMessage(`{"id":"` + id + `"}`),
+	}
+}
+
+func TestStoreOwnership(t *testing.T) {
+	store := NewStore(0, 0)
+	ctx := context.Background()
+	if err := store.Put(ctx, testRecord("resp_owned", "", `"hi"`, `"hello"`)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	shared := testRecord("resp_shared", "", `"hi"`, `"hello"`)
+	shared.Owner = ""
+	if err := store.Put(ctx, shared); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		id      string
+		key     string
+		wantErr error
+	}{
+		{name: "owner", id: "resp_owned", key: testOwnerKey},
+		{name: "other client", id: "resp_owned", key: "sk-other", wantErr: ErrNotFound},
+		{name: "unauthenticated client", id: "resp_owned", key: "", wantErr: ErrNotFound},
+		{name: "stored hash is not a key", id: "resp_owned", key: util.HashAPIKey(testOwnerKey), wantErr: ErrNotFound},
+		{name: "record without owner", id: "resp_shared", key: "sk-other"},
+		{name: "unknown id", id: "resp_missing", key: testOwnerKey, wantErr: ErrNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.Get(ctx, tc.id, tc.key)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Get(%s) error = %v, want %v", tc.id, err, tc.wantErr)
+			}
+		})
+	}
+
+	if err := store.Delete(ctx, "resp_owned", "sk-other"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete() by another client error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "resp_owned", testOwnerKey); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, "resp_owned", testOwnerKey); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreHistory(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name       string
+		records    []Record
+		previousID string
+		want       string
+		wantErr    error
+	}{
+		{
+			name: "chain in conversation order",
+			records: []Record{
+				testRecord("resp_1", "", `"q1"`, `"a1"`),
+				testRecord("resp_2", "resp_1", `"q2"`, `"a2"`),
+				testRecord("resp_3", "resp_2", `"q3"`, `"a3"`),
+			},
+			previousID: "resp_3",
+			want:       `"q1" "a1" "q2" "a2" "q3" "a3"`,
+		},
+		{
+			name: "expired older turn keeps the rest",
+			records: []Record{
+				testRecord("resp_2", "resp_1", `"q2"`, `"a2"`),
+				testRecord("resp_3", "resp_2", `"q3"`, `"a3"`),
+			},
+			previousID: "resp_3",
+			want:       `"q2" "a2" "q3" "a3"`,
+		},
+		{
+			name: "cycle stops the walk",
+			records: []Record{
+				testRecord("resp_1", "resp_2", `"q1"`, `"a1"`),
+				testRecord("resp_2", "resp_1", `"q2"`, `"a2"`),
+			},
+			previousID: "resp_2",
+			want:       `"q1" "a1" "q2" "a2"`,
+		},
+		{
+			name:       "unknown previous response",
+			previousID: "resp_missing",
+			wantErr:    ErrNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewStore(0, 0)
+			for _, record := range tc.records {
+				if err := store.Put(ctx, record); err != nil {
+					t.Fatalf("Put() error = %v", err)
+				}
+			}
+			items, err := store.History(ctx, tc.previousID, testOwnerKey)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("History() error = %v, want %v", err, tc.wantErr)
+			}
+			got := make([]string, 0, len(items))
+			for _, item := range items {
+				got = append(got, string(item))
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Fatalf("History() = %s, want %s", strings.Join(got, " "), tc.want)
+			}
+		})
+	}
+}
+
+func TestStoreEvictionAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(2, time.Hour)
+	for _, id := range []string{"resp_1", "resp_2"} {
+		if err := store.Put(ctx, testRecord(id, "", `"q"`, `"a"`)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	// Reading resp_1 makes resp_2 the least recently used entry.
+	if _, err := store.Get(ctx, "resp_1", testOwnerKey); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	expired := testRecord("resp_old", "", `"q"`, `"a"`)
+	expired.CreatedAt = time.Now().Add(-2 * time.Hour)
+	if err := store.Put(ctx, expired); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	testCases := []struct {
+		id    string
+		found bool
+	}{
+		{id: "resp_1", found: true},
+		{id: "resp_2", found: false},
+		{id: "resp_old", found: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			_, err := store.Get(ctx, tc.id, testOwnerKey)
+			if (err == nil) != tc.found {
+				t.Fatalf("Get(%s) error = %v, want found %v", tc.id, err, tc.found)
+			}
+		})
+	}
+}
+
+func TestDisabledStoreKeepsNothing(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(0, 0)
+	store.SetDisabled(true)
+	if err := store.Put(ctx, testRecord("resp_1", "", `"q"`, `"a"`)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	store.SetDisabled(false)
+	if _, err := store.Get(ctx, "resp_1", testOwnerKey); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileBackendSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	backend, err := NewFileBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFileBackend() error = %v", err)
+	}
+	first := NewStore(0, 0)
+	first.SetBackend(backend)
+	ids := []string{"resp_1", "resp/../unsafe id"}
+	for _, id := range ids {
+		if err = first.Put(ctx, testRecord(id, "", `"q"`, `"a"`)); err != nil {
+			t.Fatalf("Put(%s) error = %v", id, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != len(ids) {
+		t.Fatalf("backend files = %v (%v), want %d", entries, err, len(ids))
+	}
+	for _, entry := range entries {
+		data, errRead := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if errRead != nil {
+			t.Fatalf("read %s: %v", entry.Name(), errRead)
+		}
+		if strings.Contains(string(data), testOwnerKey) {
+			t.Fatalf("%s stores the client key: %s", entry.Name(), data)
+		}
+	}
+
+	second := NewStore(0, 0)
+	second.SetBackend(backend)
+	for _, id := range ids {
+		record, errGet := second.Get(ctx, id, testOwnerKey)
+		if errGet != nil {
+			t.Fatalf("Get(%s) after restart error = %v", id, errGet)
+		}
+		if record.ID != id {
+			t.Fatalf("Get(%s) returned %s", id, record.ID)
+		}
+	}
+	if err = second.Delete(ctx, "resp_1", testOwnerKey); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, found, _ := backend.Load(ctx, "resp_1"); found {
+		t.Fatalf("deleted record is still in the backend")
+	}
+}
@@ -293,6 +293,11 @@ func (e *AIStudioExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.A
 	return cliproxyexecutor.Response{Payload: []byte(translated)}, nil
 }
 
+// GenerateImage serves OpenAI images requests with Gemini image models.
+func (e *AIStudioExecutor) GenerateImage(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return generateGeminiImages(ctx, e.Execute, auth, req)
+}
+
 func (e *AIStudioExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
 	_ = ctx
 	return auth, nil
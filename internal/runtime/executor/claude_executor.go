@@ -70,6 +70,9 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 
 	// Ensure max_tokens > thinking.budget_tokens when thinking is enabled
 	body = ensureMaxTokensForThinking(req.Model, body)
+	if errFormat := checkStructuredOutputThinking(from, to, req.Payload, body); errFormat != nil {
+		return resp, errFormat
+	}
 
 	// Extract betas from body and convert to header
 	var extraBetas []string
@@ -128,6 +131,7 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 			return resp, err
 		}
 		recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+		recordRateLimits(ctx, auth, req.Model, parseAnthropicRateLimits(httpResp.Header, time.Now()))
 
 		if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 {
 			break // Success
@@ -202,6 +206,9 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 
 	// Ensure max_tokens > thinking.budget_tokens when thinking is enabled
 	body = ensureMaxTokensForThinking(req.Model, body)
+	if errFormat := checkStructuredOutputThinking(from, to, req.Payload, body); errFormat != nil {
+		return nil, errFormat
+	}
 
 	// Extract betas from body and convert to header
 	var extraBetas []string
@@ -260,6 +267,7 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 			return nil, err
 		}
 		recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+		recordRateLimits(ctx, auth, req.Model, parseAnthropicRateLimits(httpResp.Header, time.Now()))
 
 		if httpResp.StatusCode >= 200 && httpResp.StatusCode < 300 {
 			break // Success
@@ -515,6 +523,25 @@ func (e *ClaudeExecutor) injectThinkingConfig(modelName string, body []byte) []b
 	return body
 }
 
+// checkStructuredOutputThinking rejects a translated request whose response_format
+// became a forced tool call while extended thinking is enabled, since Claude does
+// not accept that combination and dropping thinking would change the request.
+func checkStructuredOutputThinking(from, to sdktranslator.Format, original, body []byte) error {
+	if from == to || gjson.GetBytes(body, "thinking.type").String() != "enabled" {
+		return nil
+	}
+	switch gjson.GetBytes(original, "response_format.type").String() {
+	case "json_schema", "json_object":
+	default:
+		return nil
+	}
+	switch gjson.GetBytes(body, "tool_choice.type").String() {
+	case "any", "tool":
+		return statusErr{code: http.StatusBadRequest, msg: "response_format cannot be combined with reasoning on Claude models unless tool_choice is auto"}
+	}
+	return nil
+}
+
 // ensureMaxTokensForThinking ensures max_tokens > thinking.budget_tokens when thinking is enabled.
 // Anthropic API requires this constraint; violating it returns a 400 error.
 // This function should be called after all thinking configuration is finalized.
@@ -379,7 +379,7 @@ func (e *CrossProviderExecutor) countTokensWithClaude(ctx context.Context, auth
 	body = extractSystemToTopLevel(body)
 
 	// Use Claude tokenizer
-	enc, err := tokenizerForModel(modelForCounting)
+	enc, err := util.TokenizerForModel(modelForCounting)
 	if err != nil {
 		return cliproxyexecutor.Response{}, fmt.Errorf("cross-provider executor: tokenizer init failed: %w", err)
 	}
@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/imagegen"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// executeFunc is the non-streaming Execute method of an executor.
+type executeFunc func(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error)
+
+// generateGeminiImages serves an OpenAI images request with a Gemini image model.
+// Gemini returns one image per call, so n calls are issued concurrently through
+// execute, which records upstream logs and usage for each of them.
+func generateGeminiImages(ctx context.Context, execute executeFunc, auth *cliproxyauth.Auth, req cliproxyexecutor.Request) (cliproxyexecutor.Response, error) {
+	body, err := imagegen.ToGemini(req.Payload)
+	if err != nil {
+		return cliproxyexecutor.Response{}, statusErr{code: http.StatusBadRequest, msg: err.Error()}
+	}
+	geminiReq := cliproxyexecutor.Request{Model: req.Model, Payload: body, Metadata: req.Metadata}
+	geminiOpts := cliproxyexecutor.Options{OriginalRequest: body, SourceFormat: sdktranslator.FromString("gemini")}
+
+	n := imagegen.Count(req.Payload)
+	results := make([][]byte, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, errExec := execute(ctx, auth, geminiReq, geminiOpts)
+			results[i], errs[i] = resp.Payload, errExec
+		}(i)
+	}
+	wg.Wait()
+	for _, errExec := range errs {
+		if errExec != nil {
+			return cliproxyexecutor.Response{}, errExec
+		}
+	}
+	out, err := imagegen.FromGemini(time.Now().Unix(), results)
+	if err != nil {
+		return cliproxyexecutor.Response{}, statusErr{code: http.StatusBadRequest, msg: err.Error()}
+	}
+	return cliproxyexecutor.Response{Payload: out}, nil
+}
+
+// buildOpenAIImageEditForm rebuilds the multipart body of an OpenAI images/edits
+// request from the inline images of payload. It returns the body and its content type.
+func buildOpenAIImageEditForm(model string, payload []byte) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.WriteField("model", model); err != nil {
+		return nil, "", err
+	}
+	var errField error
+	gjson.ParseBytes(payload).ForEach(func(key, value gjson.Result) bool {
+		switch key.String() {
+		case "model", "images", "mask":
+			return true
+		}
+		if value.IsObject() || value.IsArray() {
+			return true
+		}
+		errField = writer.WriteField(key.String(), value.String())
+		return errField == nil
+	})
+	if errField != nil {
+		return nil, "", errField
+	}
+	images := gjson.GetBytes(payload, "images").Array()
+	field := "image"
+	if len(images) > 1 {
+		field = "image[]"
+	}
+	for i, image := range images {
+		if err := writeImagePart(writer, field, fmt.Sprintf("image-%d", i), image); err != nil {
+			return nil, "", err
+		}
+	}
+	if mask := gjson.GetBytes(payload, "mask"); mask.Exists() {
+		if err := writeImagePart(writer, "mask", "mask", mask); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+func writeImagePart(writer *multipart.Writer, field, name string, image gjson.Result) error {
+	data, err := base64.StdEncoding.DecodeString(image.Get("data").String())
+	if err != nil {
+		return statusErr{code: http.StatusBadRequest, msg: fmt.Sprintf("invalid %s data: %v", field, err)}
+	}
+	mimeType := image.Get("mime_type").String()
+	if mimeType == "" {
+		mimeType = "image/png"
+	}
+	switch mimeType {
+	case "image/jpeg":
+		name += ".jpg"
+	case "image/webp":
+		name += ".webp"
+	default:
+		name += ".png"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, name))
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
@@ -15,6 +15,7 @@ import (
 )
 
 // newProxyAwareHTTPClient creates an HTTP client with proper proxy configuration priority:
+// 0. Use the HTTP client supplied by an execution hook, then a forced RoundTripper from context
 // 1. Use auth.ProxyURL if configured (highest priority)
 // 2. Use cfg.ProxyURL if auth proxy is not configured
 // 3. Use RoundTripper from context if neither are configured
@@ -28,11 +29,24 @@ import (
 // Returns:
 //   - *http.Client: An HTTP client with configured proxy or transport
 func newProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
+	// Priority 0: Execution hooks may replace the outbound client entirely
+	if ctx != nil {
+		if override := cliproxyauth.HTTPClientFromContext(ctx); override != nil {
+			return override
+		}
+	}
+
 	httpClient := &http.Client{}
 	if timeout > 0 {
 		httpClient.Timeout = timeout
 	}
 
+	// Transports such as cassette record/replay must see every exchange, whatever proxy is configured
+	if rt := cliproxyauth.ForcedRoundTripperFromContext(ctx); rt != nil {
+		httpClient.Transport = rt
+		return httpClient
+	}
+
 	// Priority 1: Use auth.ProxyURL if configured
 	var proxyURL string
 	if auth != nil {
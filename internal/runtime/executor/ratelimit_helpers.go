@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// recordRateLimits reports the windows parsed from an upstream response to the
+// auth manager so selection can steer away from nearly exhausted credentials.
+func recordRateLimits(ctx context.Context, auth *cliproxyauth.Auth, model string, windows []cliproxyauth.RateLimitWindow) {
+	if auth == nil || len(windows) == 0 {
+		return
+	}
+	cliproxyauth.RecordRateLimits(ctx, auth.ID, model, windows)
+}
+
+// anthropicRateLimitNames lists the per-model limits reported as
+// anthropic-ratelimit-<name>-{limit,remaining,reset}.
+var anthropicRateLimitNames = []string{"requests", "tokens", "input-tokens", "output-tokens"}
+
+// parseAnthropicRateLimits reads the anthropic-ratelimit-* headers. Subscription
+// (OAuth) accounts report unified windows as a utilisation share instead.
+func parseAnthropicRateLimits(h http.Header, now time.Time) []cliproxyauth.RateLimitWindow {
+	var windows []cliproxyauth.RateLimitWindow
+	for _, name := range anthropicRateLimitNames {
+		prefix := "Anthropic-Ratelimit-" + name + "-"
+		limit, okLimit := parseHeaderInt(h.Get(prefix + "Limit"))
+		remaining, okRemaining := parseHeaderInt(h.Get(prefix + "Remaining"))
+		if !okLimit || !okRemaining {
+			continue
+		}
+		unit := cliproxyauth.RateLimitUnitTokens
+		if name == "requests" {
+			unit = cliproxyauth.RateLimitUnitRequests
+		}
+		windows = append(windows, cliproxyauth.RateLimitWindow{
+			Name:      name,
+			Unit:      unit,
+			Limit:     limit,
+			Remaining: remaining,
+			ResetAt:   parseResetTime(h.Get(prefix+"Reset"), now),
+		})
+	}
+	for _, name := range []string{"5h", "7d"} {
+		prefix := "Anthropic-Ratelimit-Unified-" + name + "-"
+		utilization, ok := parseHeaderFloat(h.Get(prefix + "Utilization"))
+		if !ok {
+			continue
+		}
+		windows = append(windows, percentWindow(name, utilization, 0, parseResetTime(h.Get(prefix+"Reset"), now)))
+	}
+	if strings.EqualFold(strings.TrimSpace(h.Get("Anthropic-Ratelimit-Unified-Status")), "rejected") {
+		windows = append(windows, percentWindow("unified", 1, 0, parseResetTime(h.Get("Anthropic-Ratelimit-Unified-Reset"), now)))
+	}
+	return windows
+}
+
+// parseOpenAIRateLimits reads the x-ratelimit-{limit,remaining,reset}-{requests,tokens}
+// headers sent by OpenAI and most OpenAI-compatible upstreams.
+func parseOpenAIRateLimits(h http.Header, now time.Time) []cliproxyauth.RateLimitWindow {
+	var windows []cliproxyauth.RateLimitWindow
+	for _, name := range []string{"requests", "tokens"} {
+		limit, okLimit := parseHeaderInt(h.Get("X-Ratelimit-Limit-" + name))
+		remaining, okRemaining := parseHeaderInt(h.Get("X-Ratelimit-Remaining-" + name))
+		if !okLimit || !okRemaining {
+			continue
+		}
+		windows = append(windows, cliproxyauth.RateLimitWindow{
+			Name:      name,
+			Unit:      name,
+			Limit:     limit,
+			Remaining: remaining,
+			ResetAt:   parseResetTime(h.Get("X-Ratelimit-Reset-"+name), now),
+		})
+	}
+	return windows
+}
+
+// parseCodexRateLimits reads the Codex usage windows (x-codex-primary-* and
+// x-codex-secondary-*), which cover the whole ChatGPT account.
+func parseCodexRateLimits(h http.Header, now time.Time) []cliproxyauth.RateLimitWindow {
+	var windows []cliproxyauth.RateLimitWindow
+	for _, name := range []string{"primary", "secondary"} {
+		prefix := "X-Codex-" + name + "-"
+		used, ok := parseHeaderFloat(h.Get(prefix + "Used-Percent"))
+		if !ok {
+			continue
+		}
+		var resetAt time.Time
+		if seconds, okAfter := parseHeaderFloat(firstHeader(h, prefix+"Reset-After-Seconds", prefix+"Resets-In-Seconds")); okAfter {
+			resetAt = now.Add(time.Duration(seconds * float64(time.Second)))
+		} else {
+			resetAt = parseResetTime(h.Get(prefix+"Reset-At"), now)
+		}
+		minutes, _ := parseHeaderInt(h.Get(prefix + "Window-Minutes"))
+		windows = append(windows, percentWindow(name, used/100, int(minutes), resetAt))
+	}
+	return windows
+}
+
+// percentWindow builds a window from a used share between 0 and 1. Shares outside
+// that range, such as an overage reported above 1, are clamped; NaN counts as unused.
+func percentWindow(name string, used float64, windowMinutes int, resetAt time.Time) cliproxyauth.RateLimitWindow {
+	switch {
+	case used > 1:
+		used = 1
+	case !(used > 0):
+		used = 0
+	}
+	remaining := int64(math.Round((1 - used) * 100))
+	return cliproxyauth.RateLimitWindow{
+		Name:          name,
+		Unit:          cliproxyauth.RateLimitUnitPercent,
+		Limit:         100,
+		Remaining:     remaining,
+		WindowMinutes: windowMinutes,
+		ResetAt:       resetAt,
+	}
+}
+
+func firstHeader(h http.Header, keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(h.Get(key)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func parseHeaderInt(raw string) (int64, bool) {
+	value, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	return value, err == nil
+}
+
+func parseHeaderFloat(raw string) (float64, bool) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	return value, err == nil
+}
+
+// parseResetTime accepts an RFC 3339 timestamp, a Go duration such as "6m0s" or
+// "20ms", a number of seconds, or a Unix timestamp.
+func parseResetTime(raw string, now time.Time) time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return now.Add(d)
+	}
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		// Values this large are Unix timestamps rather than relative delays.
+		if seconds > 1e9 {
+			return time.Unix(int64(seconds), 0)
+		}
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	return time.Time{}
+}
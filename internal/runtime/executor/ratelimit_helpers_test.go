@@ -0,0 +1,226 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func testHeader(pairs ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		h.Set(pairs[i], pairs[i+1])
+	}
+	return h
+}
+
+// formatWindows renders windows compactly so expectations read as one line each.
+func formatWindows(windows []cliproxyauth.RateLimitWindow) string {
+	parts := make([]string, 0, len(windows))
+	for _, w := range windows {
+		reset := "-"
+		if !w.ResetAt.IsZero() {
+			reset = w.ResetAt.UTC().Format(time.RFC3339Nano)
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %d/%d %dm %s", w.Name, w.Unit, w.Remaining, w.Limit, w.WindowMinutes, reset))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestParseResetTime(t *testing.T) {
+	testCases := []struct {
+		name string
+		raw  string
+		want time.Time
+	}{
+		{name: "empty", raw: "", want: time.Time{}},
+		{name: "rfc3339", raw: "2025-06-01T12:05:00Z", want: testNow.Add(5 * time.Minute)},
+		{name: "rfc3339 with offset", raw: "2025-06-01T14:05:00+02:00", want: testNow.Add(5 * time.Minute)},
+		{name: "duration", raw: "6m0s", want: testNow.Add(6 * time.Minute)},
+		{name: "short duration", raw: "20ms", want: testNow.Add(20 * time.Millisecond)},
+		{name: "seconds", raw: "30", want: testNow.Add(30 * time.Second)},
+		{name: "fractional seconds", raw: " 1.5 ", want: testNow.Add(1500 * time.Millisecond)},
+		{name: "unix timestamp", raw: "1748779500", want: time.Unix(1748779500, 0)},
+		{name: "garbage", raw: "soon", want: time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseResetTime(tc.raw, testNow); !got.Equal(tc.want) {
+				t.Fatalf("parseResetTime(%q) = %v, want %v", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseAnthropicRateLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{
+			name: "api key limits",
+			header: testHeader(
+				"anthropic-ratelimit-requests-limit", "50",
+				"anthropic-ratelimit-requests-remaining", "49",
+				"anthropic-ratelimit-requests-reset", "2025-06-01T12:01:00Z",
+				"anthropic-ratelimit-input-tokens-limit", "40000",
+				"anthropic-ratelimit-input-tokens-remaining", "1000",
+			),
+			want: "requests requests 49/50 0m 2025-06-01T12:01:00Z; input-tokens tokens 1000/40000 0m -",
+		},
+		{
+			name: "incomplete pair is skipped",
+			header: testHeader(
+				"anthropic-ratelimit-tokens-limit", "40000",
+				"anthropic-ratelimit-output-tokens-remaining", "10",
+			),
+			want: "",
+		},
+		{
+			name: "subscription windows",
+			header: testHeader(
+				"anthropic-ratelimit-unified-5h-utilization", "0.25",
+				"anthropic-ratelimit-unified-5h-reset", "1748781000",
+				"anthropic-ratelimit-unified-7d-utilization", "1.2",
+			),
+			want: "5h percent 75/100 0m 2025-06-01T12:30:00Z; 7d percent 0/100 0m -",
+		},
+		{
+			name: "rejected subscription",
+			header: testHeader(
+				"anthropic-ratelimit-unified-status", "Rejected",
+				"anthropic-ratelimit-unified-reset", "3600",
+			),
+			want: "unified percent 0/100 0m 2025-06-01T13:00:00Z",
+		},
+		{
+			name:   "allowed subscription",
+			header: testHeader("anthropic-ratelimit-unified-status", "allowed"),
+			want:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatWindows(parseAnthropicRateLimits(tc.header, testNow)); got != tc.want {
+				t.Fatalf("parseAnthropicRateLimits() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseOpenAIRateLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{
+			name: "requests and tokens",
+			header: testHeader(
+				"x-ratelimit-limit-requests", "500",
+				"x-ratelimit-remaining-requests", "499",
+				"x-ratelimit-reset-requests", "120ms",
+				"x-ratelimit-limit-tokens", "30000",
+				"x-ratelimit-remaining-tokens", "29000",
+				"x-ratelimit-reset-tokens", "2s",
+			),
+			want: "requests requests 499/500 0m 2025-06-01T12:00:00.12Z; tokens tokens 29000/30000 0m 2025-06-01T12:00:02Z",
+		},
+		{
+			name:   "non-numeric values are skipped",
+			header: testHeader("x-ratelimit-limit-requests", "unlimited", "x-ratelimit-remaining-requests", "10"),
+			want:   "",
+		},
+		{
+			name:   "no headers",
+			header: http.Header{},
+			want:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatWindows(parseOpenAIRateLimits(tc.header, testNow)); got != tc.want {
+				t.Fatalf("parseOpenAIRateLimits() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseCodexRateLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{
+			name: "primary and secondary",
+			header: testHeader(
+				"x-codex-primary-used-percent", "40",
+				"x-codex-primary-window-minutes", "300",
+				"x-codex-primary-reset-after-seconds", "600",
+				"x-codex-secondary-used-percent", "12.4",
+				"x-codex-secondary-window-minutes", "10080",
+				"x-codex-secondary-reset-at", "1748782800",
+			),
+			want: "primary percent 60/100 300m 2025-06-01T12:10:00Z; secondary percent 88/100 10080m 2025-06-01T13:00:00Z",
+		},
+		{
+			name:   "legacy resets-in header",
+			header: testHeader("x-codex-primary-used-percent", "100", "x-codex-primary-resets-in-seconds", "60"),
+			want:   "primary percent 0/100 0m 2025-06-01T12:01:00Z",
+		},
+		{
+			name:   "overage is clamped",
+			header: testHeader("x-codex-secondary-used-percent", "130"),
+			want:   "secondary percent 0/100 0m -",
+		},
+		{
+			name:   "missing usage",
+			header: testHeader("x-codex-primary-window-minutes", "300"),
+			want:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatWindows(parseCodexRateLimits(tc.header, testNow)); got != tc.want {
+				t.Fatalf("parseCodexRateLimits() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPercentWindow(t *testing.T) {
+	testCases := []struct {
+		name          string
+		used          float64
+		wantRemaining int64
+	}{
+		{name: "unused", used: 0, wantRemaining: 100},
+		{name: "partly used", used: 0.333, wantRemaining: 67},
+		{name: "exhausted", used: 1, wantRemaining: 0},
+		{name: "overage", used: 1.5, wantRemaining: 0},
+		{name: "negative", used: -0.2, wantRemaining: 100},
+		{name: "not a number", used: math.NaN(), wantRemaining: 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := percentWindow("5h", tc.used, 300, time.Time{})
+			if w.Remaining != tc.wantRemaining || w.Limit != 100 || w.Unit != cliproxyauth.RateLimitUnitPercent {
+				t.Fatalf("percentWindow(%v) = %+v, want %d/100 percent", tc.used, w, tc.wantRemaining)
+			}
+		})
+	}
+}
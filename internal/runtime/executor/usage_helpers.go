@@ -114,6 +114,9 @@ func apiKeyFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
 	}
+	if key, ok := usage.ClientAPIKeyFromContext(ctx); ok {
+		return key
+	}
 	ginCtx, ok := ctx.Value("gin").(*gin.Context)
 	if !ok || ginCtx == nil {
 		return ""
@@ -582,3 +585,21 @@ func jsonPayload(line []byte) []byte {
 	}
 	return trimmed
 }
+
+// parseOpenAIImageUsage reads the usage block of an OpenAI images response, which
+// reports input_tokens/output_tokens rather than prompt/completion tokens.
+func parseOpenAIImageUsage(data []byte) usage.Detail {
+	usageNode := gjson.GetBytes(data, "usage")
+	if !usageNode.Exists() {
+		return usage.Detail{}
+	}
+	detail := usage.Detail{
+		InputTokens:  usageNode.Get("input_tokens").Int(),
+		OutputTokens: usageNode.Get("output_tokens").Int(),
+		TotalTokens:  usageNode.Get("total_tokens").Int(),
+	}
+	if detail.TotalTokens == 0 {
+		detail.TotalTokens = detail.InputTokens + detail.OutputTokens
+	}
+	return detail
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+// UsageRecordStore returns a usage.RecordStore that shares the Postgres connection
+// so usage statistics persist alongside configuration and auth records.
+func (s *PostgresStore) UsageRecordStore() usage.RecordStore {
+	return &postgresUsageStore{store: s}
+}
+
+type postgresUsageStore struct {
+	store *PostgresStore
+}
+
+// Append implements usage.RecordStore.
+func (u *postgresUsageStore) Append(ctx context.Context, record usage.StoredRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("postgres store: marshal usage record: %w", err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (requested_at, content) VALUES ($1, $2)", u.store.fullTableName(u.store.cfg.UsageTable))
+	if _, err = u.store.db.ExecContext(ctx, query, record.Timestamp.UTC(), json.RawMessage(data)); err != nil {
+		return fmt.Errorf("postgres store: insert usage record: %w", err)
+	}
+	return nil
+}
+
+// Load implements usage.RecordStore.
+func (u *postgresUsageStore) Load(ctx context.Context, since time.Time, fn func(usage.StoredRecord)) error {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE requested_at >= $1 ORDER BY id", u.store.fullTableName(u.store.cfg.UsageTable))
+	rows, err := u.store.db.QueryContext(ctx, query, since.UTC())
+	if err != nil {
+		return fmt.Errorf("postgres store: query usage records: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	for rows.Next() {
+		var payload []byte
+		if err = rows.Scan(&payload); err != nil {
+			return fmt.Errorf("postgres store: scan usage record: %w", err)
+		}
+		var record usage.StoredRecord
+		if errUnmarshal := json.Unmarshal(payload, &record); errUnmarshal != nil {
+			log.Warnf("postgres store: skipping malformed usage record: %v", errUnmarshal)
+			continue
+		}
+		fn(record)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("postgres store: iterate usage records: %w", err)
+	}
+	return nil
+}
+
+// Prune implements usage.RecordStore.
+func (u *postgresUsageStore) Prune(ctx context.Context, before time.Time) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE requested_at < $1", u.store.fullTableName(u.store.cfg.UsageTable))
+	if _, err := u.store.db.ExecContext(ctx, query, before.UTC()); err != nil {
+		return fmt.Errorf("postgres store: prune usage records: %w", err)
+	}
+	return nil
+}
+
+// Close implements usage.RecordStore. The connection is owned by PostgresStore.
+func (u *postgresUsageStore) Close() error { return nil }
@@ -340,5 +340,76 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 		}
 	}
 
+	// Structured output: Claude has no response_format, so the requested schema becomes the
+	// input schema of a tool the model is forced to call. The response translator unwraps the
+	// tool input back into message content.
+	toolName := structuredOutputToolName(root)
+	if tool, ok := structuredOutputTool(root.Get("response_format"), toolName); ok {
+		if gjson.Get(out, "tools").IsArray() {
+			out, _ = sjson.SetRaw(out, "tools.-1", tool)
+			// Keep an explicit tool_choice from the client. Otherwise require a tool call, so
+			// the model either calls one of the client's tools or answers through the
+			// structured output tool.
+			if !root.Get("tool_choice").Exists() {
+				out, _ = sjson.Set(out, "tool_choice", map[string]interface{}{"type": "any"})
+			}
+		} else {
+			out, _ = sjson.SetRaw(out, "tools", "["+tool+"]")
+			out, _ = sjson.Set(out, "tool_choice", map[string]interface{}{"type": "tool", "name": toolName})
+		}
+		// Thinking is kept even though Claude does not allow it with a forced tool choice;
+		// the executor rejects that combination instead of silently dropping thinking.
+	}
+
 	return []byte(out)
 }
+
+// structuredOutputToolBaseName is the preferred name of the tool carrying structured output.
+const structuredOutputToolBaseName = "json_response"
+
+// structuredOutputToolName returns the name of the structured output tool for the
+// request: json_response, suffixed with a number when a client tool already uses it.
+// The response translator derives the same name from the original request.
+func structuredOutputToolName(root gjson.Result) string {
+	used := make(map[string]bool)
+	root.Get("tools").ForEach(func(_, tool gjson.Result) bool {
+		used[tool.Get("function.name").String()] = true
+		return true
+	})
+	name := structuredOutputToolBaseName
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s_%d", structuredOutputToolBaseName, i)
+	}
+	return name
+}
+
+// structuredOutputTool builds the Claude tool named name for an OpenAI response_format
+// of type json_schema or json_object. It reports false for any other format.
+func structuredOutputTool(responseFormat gjson.Result, name string) (string, bool) {
+	schema := `{"type":"object"}`
+	description := "Respond to the user by calling this tool. Its input is the complete reply as a JSON object."
+	switch responseFormat.Get("type").String() {
+	case "json_object":
+	case "json_schema":
+		js := responseFormat.Get("json_schema")
+		if s := js.Get("schema"); s.IsObject() {
+			schema = s.Raw
+			if t := s.Get("type"); !t.Exists() {
+				schema, _ = sjson.Set(schema, "type", "object")
+			}
+		}
+		if schemaName := js.Get("name").String(); schemaName != "" {
+			description += " The reply must match the " + schemaName + " schema."
+		}
+		if desc := js.Get("description").String(); desc != "" {
+			description += " " + desc
+		}
+	default:
+		return "", false
+	}
+	tool := `{"name":"","description":"","input_schema":{}}`
+	tool, _ = sjson.Set(tool, "name", name)
+	tool, _ = sjson.Set(tool, "description", description)
+	tool, _ = sjson.SetRaw(tool, "input_schema", schema)
+	return tool, true
+}
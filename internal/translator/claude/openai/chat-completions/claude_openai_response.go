@@ -27,6 +27,11 @@ type ConvertAnthropicResponseToOpenAIParams struct {
 	FinishReason string
 	// Tool calls accumulator for streaming
 	ToolCallsAccumulator map[int]*ToolCallAccumulator
+	// StructuredBlocks marks content blocks carrying structured output, which are
+	// streamed as message content instead of tool calls
+	StructuredBlocks map[int]bool
+	// ToolCallsEmitted records whether a client tool call was forwarded
+	ToolCallsEmitted bool
 }
 
 // ToolCallAccumulator holds the state for accumulating tool call data
@@ -114,6 +119,15 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 				toolName := contentBlock.Get("name").String()
 				index := int(root.Get("index").Int())
 
+				// Structured output tool input is forwarded as content deltas
+				if structuredName := structuredOutputToolFor(originalRequestRawJSON); structuredName != "" && toolName == structuredName {
+					if (*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredBlocks == nil {
+						(*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredBlocks = make(map[int]bool)
+					}
+					(*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredBlocks[index] = true
+					return []string{}
+				}
+
 				if (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator == nil {
 					(*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator = make(map[int]*ToolCallAccumulator)
 				}
@@ -152,6 +166,13 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 				// Tool use input delta - accumulate arguments for tool calls
 				if partialJSON := delta.Get("partial_json"); partialJSON.Exists() {
 					index := int(root.Get("index").Int())
+					if (*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredBlocks[index] {
+						if partialJSON.String() == "" {
+							return []string{}
+						}
+						template, _ = sjson.Set(template, "choices.0.delta.content", partialJSON.String())
+						return []string{template}
+					}
 					if (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator != nil {
 						if accumulator, exists := (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator[index]; exists {
 							accumulator.Arguments.WriteString(partialJSON.String())
@@ -193,6 +214,7 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 
 				// Clean up the accumulator for this index
 				delete((*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator, index)
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsEmitted = true
 
 				return []string{template}
 			}
@@ -203,7 +225,12 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 		// Handle message-level changes including stop reason and usage
 		if delta := root.Get("delta"); delta.Exists() {
 			if stopReason := delta.Get("stop_reason"); stopReason.Exists() {
-				(*param).(*ConvertAnthropicResponseToOpenAIParams).FinishReason = mapAnthropicStopReasonToOpenAI(stopReason.String())
+				reason := stopReason.String()
+				// A reply delivered through the structured output tool is a normal completion
+				if reason == "tool_use" && len((*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredBlocks) > 0 && !(*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsEmitted {
+					reason = "end_turn"
+				}
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).FinishReason = mapAnthropicStopReasonToOpenAI(reason)
 				template, _ = sjson.Set(template, "choices.0.finish_reason", (*param).(*ConvertAnthropicResponseToOpenAIParams).FinishReason)
 			}
 		}
@@ -302,6 +329,10 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 	toolCallsMap := make(map[int]map[string]interface{})
 	// Track tool call arguments accumulation
 	toolCallArgsMap := make(map[int]strings.Builder)
+	// Structured output tool input, returned as message content
+	structuredName := structuredOutputToolFor(originalRequestRawJSON)
+	structuredBlocks := make(map[int]bool)
+	var structuredParts []string
 
 	for _, chunk := range chunks {
 		root := gjson.ParseBytes(chunk)
@@ -329,6 +360,10 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 				} else if blockType == "tool_use" {
 					// Initialize tool call tracking for this index
 					index := int(root.Get("index").Int())
+					if structuredName != "" && contentBlock.Get("name").String() == structuredName {
+						structuredBlocks[index] = true
+						continue
+					}
 					toolCallsMap[index] = map[string]interface{}{
 						"id":   contentBlock.Get("id").String(),
 						"type": "function",
@@ -361,6 +396,10 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 					// Accumulate tool call arguments
 					if partialJSON := delta.Get("partial_json"); partialJSON.Exists() {
 						index := int(root.Get("index").Int())
+						if structuredBlocks[index] {
+							structuredParts = append(structuredParts, partialJSON.String())
+							continue
+						}
 						if builder, exists := toolCallArgsMap[index]; exists {
 							builder.WriteString(partialJSON.String())
 							toolCallArgsMap[index] = builder
@@ -407,6 +446,16 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 
 	// Set message content by combining all text parts
 	messageContent := strings.Join(contentParts, "")
+	if len(structuredBlocks) > 0 {
+		// The structured output tool input is the reply; any surrounding text is not valid JSON
+		messageContent = strings.Join(structuredParts, "")
+		if messageContent == "" {
+			messageContent = "{}"
+		}
+		if stopReason == "tool_use" {
+			stopReason = "end_turn"
+		}
+	}
 	out, _ = sjson.Set(out, "choices.0.message.content", messageContent)
 
 	// Add reasoning content if available (following OpenAI reasoning format)
@@ -456,3 +505,15 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 
 	return out
 }
+
+// structuredOutputToolFor returns the name of the structured output tool the request
+// translator added for the OpenAI request, or "" when no JSON output was requested.
+func structuredOutputToolFor(originalRequestRawJSON []byte) string {
+	root := gjson.ParseBytes(originalRequestRawJSON)
+	switch root.Get("response_format.type").String() {
+	case "json_schema", "json_object":
+		return structuredOutputToolName(root)
+	default:
+		return ""
+	}
+}
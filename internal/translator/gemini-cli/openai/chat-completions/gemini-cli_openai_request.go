@@ -136,6 +136,9 @@ func ConvertOpenAIRequestToGeminiCLI(modelName string, inputRawJSON []byte, _ bo
 		}
 	}
 
+	// response_format json_object/json_schema -> request.generationConfig.responseMimeType/responseSchema
+	out = common.AttachResponseFormat(out, rawJSON, "request.generationConfig")
+
 	// messages -> systemInstruction + contents
 	messages := gjson.GetBytes(rawJSON, "messages")
 	if messages.IsArray() {
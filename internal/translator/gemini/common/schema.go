@@ -0,0 +1,275 @@
+package common
+
+import (
+	"encoding/json"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxSchemaRefDepth bounds $ref expansion so recursive schemas terminate.
+const maxSchemaRefDepth = 8
+
+// passthroughSchemaKeys lists the OpenAPI schema keywords Gemini accepts as-is.
+var passthroughSchemaKeys = map[string]struct{}{
+	"description":      {},
+	"title":            {},
+	"nullable":         {},
+	"minItems":         {},
+	"maxItems":         {},
+	"minProperties":    {},
+	"maxProperties":    {},
+	"minLength":        {},
+	"maxLength":        {},
+	"pattern":          {},
+	"minimum":          {},
+	"maximum":          {},
+	"default":          {},
+	"example":          {},
+	"propertyOrdering": {},
+}
+
+// supportedSchemaFormats lists the formats Gemini accepts per schema type.
+var supportedSchemaFormats = map[string][]string{
+	"string":  {"enum", "date-time"},
+	"number":  {"float", "double"},
+	"integer": {"int32", "int64"},
+}
+
+// AttachResponseFormat maps an OpenAI response_format onto a Gemini generation config.
+// json_object requests JSON output, and json_schema additionally sets responseSchema
+// with the schema reduced to the subset Gemini accepts.
+// The caller must provide the generation config path (e.g. "generationConfig" or "request.generationConfig").
+func AttachResponseFormat(out, rawJSON []byte, path string) []byte {
+	rf := gjson.GetBytes(rawJSON, "response_format")
+	switch rf.Get("type").String() {
+	case "json_object":
+		out, _ = sjson.SetBytes(out, path+".responseMimeType", "application/json")
+	case "json_schema":
+		out, _ = sjson.SetBytes(out, path+".responseMimeType", "application/json")
+		if schema := rf.Get("json_schema.schema"); schema.IsObject() {
+			if sanitized, err := json.Marshal(SanitizeResponseSchema(schema.Raw)); err == nil {
+				out, _ = sjson.SetRawBytes(out, path+".responseSchema", sanitized)
+			}
+		}
+	}
+	return out
+}
+
+// SanitizeResponseSchema converts a JSON Schema document into the OpenAPI subset
+// accepted by Gemini responseSchema. Local $ref pointers are inlined, oneOf becomes
+// anyOf, allOf members are merged, type unions with null become nullable, and
+// unsupported keywords such as $schema, additionalProperties and strict are dropped.
+func SanitizeResponseSchema(rawSchema string) map[string]any {
+	var root map[string]any
+	if err := json.Unmarshal([]byte(rawSchema), &root); err != nil {
+		return map[string]any{"type": "object"}
+	}
+	defs := map[string]any{}
+	for _, key := range []string{"definitions", "$defs"} {
+		if m, ok := root[key].(map[string]any); ok {
+			for name, def := range m {
+				defs["#/"+key+"/"+name] = def
+			}
+		}
+	}
+	return sanitizeSchemaNode(root, defs, 0)
+}
+
+func sanitizeSchemaNode(node map[string]any, defs map[string]any, depth int) map[string]any {
+	out := map[string]any{}
+	if ref, ok := node["$ref"].(string); ok {
+		target, found := defs[ref].(map[string]any)
+		if !found || depth >= maxSchemaRefDepth {
+			out["type"] = "object"
+		} else {
+			out = sanitizeSchemaNode(target, defs, depth+1)
+		}
+		if desc, okDesc := node["description"].(string); okDesc {
+			out["description"] = desc
+		}
+		return out
+	}
+
+	for key, value := range node {
+		switch key {
+		case "type":
+			switch t := value.(type) {
+			case string:
+				out["type"] = t
+			case []any:
+				for _, item := range t {
+					if s, _ := item.(string); s == "null" {
+						out["nullable"] = true
+					} else if s != "" && out["type"] == nil {
+						out["type"] = s
+					}
+				}
+			}
+		case "properties":
+			if props, ok := value.(map[string]any); ok {
+				sanitized := make(map[string]any, len(props))
+				for name, prop := range props {
+					if m, okProp := prop.(map[string]any); okProp {
+						sanitized[name] = sanitizeSchemaNode(m, defs, depth)
+					}
+				}
+				out["properties"] = sanitized
+			}
+		case "items":
+			switch items := value.(type) {
+			case map[string]any:
+				out["items"] = sanitizeSchemaNode(items, defs, depth)
+			case []any:
+				if len(items) > 0 {
+					if m, ok := items[0].(map[string]any); ok {
+						out["items"] = sanitizeSchemaNode(m, defs, depth)
+					}
+				}
+			}
+		case "anyOf", "oneOf":
+			var variants []any
+			for _, item := range asSlice(value) {
+				m, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				if t, _ := m["type"].(string); t == "null" {
+					out["nullable"] = true
+					continue
+				}
+				variants = append(variants, sanitizeSchemaNode(m, defs, depth))
+			}
+			if len(variants) > 0 {
+				existing, _ := out["anyOf"].([]any)
+				out["anyOf"] = append(existing, variants...)
+			}
+		case "const":
+			if s, ok := value.(string); ok {
+				out["enum"] = []any{s}
+				if out["type"] == nil {
+					out["type"] = "string"
+				}
+			}
+		case "enum":
+			if values := asSlice(value); len(values) > 0 && allStrings(values) {
+				out["enum"] = values
+			}
+		case "format":
+			if s, ok := value.(string); ok {
+				out["format"] = s
+			}
+		case "required":
+			if names := asSlice(value); len(names) > 0 {
+				out["required"] = names
+			}
+		default:
+			if _, ok := passthroughSchemaKeys[key]; ok {
+				out[key] = value
+			}
+		}
+	}
+
+	for _, item := range asSlice(node["allOf"]) {
+		if m, ok := item.(map[string]any); ok {
+			mergeSchema(out, sanitizeSchemaNode(m, defs, depth))
+		}
+	}
+
+	// A single anyOf variant is just the schema itself.
+	if variants, ok := out["anyOf"].([]any); ok && len(variants) == 1 {
+		delete(out, "anyOf")
+		mergeSchema(out, variants[0].(map[string]any))
+	}
+	if format, ok := out["format"].(string); ok {
+		t, _ := out["type"].(string)
+		if !containsString(supportedSchemaFormats[t], format) {
+			delete(out, "format")
+		}
+	}
+	if _, ok := out["enum"]; ok && out["type"] == nil {
+		out["type"] = "string"
+	}
+	if required := asSlice(out["required"]); required != nil {
+		props, _ := out["properties"].(map[string]any)
+		kept := make([]any, 0, len(required))
+		for _, name := range required {
+			if s, okName := name.(string); okName {
+				if _, exists := props[s]; exists {
+					kept = append(kept, s)
+				}
+			}
+		}
+		if len(kept) > 0 {
+			out["required"] = kept
+		} else {
+			delete(out, "required")
+		}
+	}
+	return out
+}
+
+// mergeSchema folds src into dst: properties and required are unioned and other
+// keywords are only copied when dst does not define them.
+func mergeSchema(dst, src map[string]any) {
+	for key, value := range src {
+		switch key {
+		case "properties":
+			props, _ := dst["properties"].(map[string]any)
+			if props == nil {
+				props = map[string]any{}
+			}
+			for name, prop := range value.(map[string]any) {
+				if _, exists := props[name]; !exists {
+					props[name] = prop
+				}
+			}
+			dst["properties"] = props
+		case "required":
+			existing := asSlice(dst["required"])
+			for _, name := range asSlice(value) {
+				if s, ok := name.(string); ok && !containsString(anyStrings(existing), s) {
+					existing = append(existing, s)
+				}
+			}
+			dst["required"] = existing
+		default:
+			if _, exists := dst[key]; !exists {
+				dst[key] = value
+			}
+		}
+	}
+}
+
+func asSlice(value any) []any {
+	s, _ := value.([]any)
+	return s
+}
+
+func allStrings(values []any) bool {
+	for _, v := range values {
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func anyStrings(values []any) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
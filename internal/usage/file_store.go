@@ -0,0 +1,181 @@
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FileRecordStore persists usage records as JSON Lines in a local file.
+// Records are appended as they arrive; pruning rewrites the file atomically.
+type FileRecordStore struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewFileRecordStore creates a file-backed record store at path, creating parent
+// directories as needed.
+func NewFileRecordStore(path string) (*FileRecordStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("usage file store: path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("usage file store: create directory: %w", err)
+	}
+	return &FileRecordStore{path: path}, nil
+}
+
+// Path returns the backing file path.
+func (s *FileRecordStore) Path() string { return s.path }
+
+// Append implements RecordStore.
+func (s *FileRecordStore) Append(_ context.Context, record StoredRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("usage file store: marshal record: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		file, errOpen := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if errOpen != nil {
+			return fmt.Errorf("usage file store: open: %w", errOpen)
+		}
+		s.file = file
+	}
+	if _, err = s.file.Write(data); err != nil {
+		return fmt.Errorf("usage file store: write: %w", err)
+	}
+	return nil
+}
+
+// Load implements RecordStore.
+func (s *FileRecordStore) Load(ctx context.Context, since time.Time, fn func(StoredRecord)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scan(ctx, func(record StoredRecord) {
+		if !since.IsZero() && record.Timestamp.Before(since) {
+			return
+		}
+		fn(record)
+	})
+}
+
+// Prune implements RecordStore.
+func (s *FileRecordStore) Prune(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("usage file store: create temp file: %w", err)
+	}
+	writer := bufio.NewWriter(tmp)
+	dropped := 0
+	var writeErr error
+	errScan := s.scan(ctx, func(record StoredRecord) {
+		if writeErr != nil {
+			return
+		}
+		if record.Timestamp.Before(before) {
+			dropped++
+			return
+		}
+		data, errMarshal := json.Marshal(record)
+		if errMarshal != nil {
+			writeErr = errMarshal
+			return
+		}
+		data = append(data, '\n')
+		_, writeErr = writer.Write(data)
+	})
+	if errScan == nil {
+		errScan = writeErr
+	}
+	if errScan == nil {
+		errScan = writer.Flush()
+	}
+	if errClose := tmp.Close(); errScan == nil {
+		errScan = errClose
+	}
+	if errScan != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("usage file store: prune: %w", errScan)
+	}
+	if dropped == 0 {
+		_ = os.Remove(tmpPath)
+		return nil
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("usage file store: replace file: %w", err)
+	}
+	log.Debugf("usage file store: pruned %d expired record(s)", dropped)
+	return nil
+}
+
+// Close implements RecordStore.
+func (s *FileRecordStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// scan reads every record from the backing file. Callers must hold s.mu.
+func (s *FileRecordStore) scan(ctx context.Context, fn func(StoredRecord)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("usage file store: open: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if ctx != nil {
+			if errCtx := ctx.Err(); errCtx != nil {
+				return errCtx
+			}
+		}
+		raw := scanner.Bytes()
+		if len(raw) == 0 {
+			continue
+		}
+		var record StoredRecord
+		if errUnmarshal := json.Unmarshal(raw, &record); errUnmarshal != nil {
+			log.Warnf("usage file store: skipping malformed line %d in %s: %v", line, s.path, errUnmarshal)
+			continue
+		}
+		fn(record)
+	}
+	return scanner.Err()
+}
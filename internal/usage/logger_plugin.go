@@ -90,9 +90,14 @@ type modelStats struct {
 type RequestDetail struct {
 	Timestamp time.Time  `json:"timestamp"`
 	Source    string     `json:"source"`
+	Provider  string     `json:"provider,omitempty"`
 	AuthIndex uint64     `json:"auth_index"`
 	Tokens    TokenStats `json:"tokens"`
 	Failed    bool       `json:"failed"`
+	// RequestedModel is the model the client asked for when a fallback model answered.
+	RequestedModel string `json:"requested_model,omitempty"`
+	// CacheHit is set when the response cache answered without calling the upstream.
+	CacheHit bool `json:"cache_hit,omitempty"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -157,22 +162,51 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 	if !statisticsEnabled.Load() {
 		return
 	}
-	timestamp := record.RequestedAt
-	if timestamp.IsZero() {
-		timestamp = time.Now()
+	if record.APIKey != "" {
+		s.adoptRestored(record.APIKey)
 	}
-	detail := normaliseDetail(record.Detail)
-	totalTokens := detail.TotalTokens
-	statsKey := record.APIKey
-	if statsKey == "" {
-		statsKey = resolveAPIIdentifier(ctx, record)
+	s.apply(resolveStoredRecord(ctx, record))
+}
+
+// adoptRestored moves statistics restored from the persistent store, which are
+// kept under the key's fingerprint, to the raw key once the client is seen again.
+func (s *RequestStatistics) adoptRestored(apiKey string) {
+	fingerprint := apiKeyFingerprint(apiKey)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	restored, ok := s.apis[fingerprint]
+	if !ok {
+		return
 	}
-	failed := record.Failed
-	if !failed {
-		failed = !resolveSuccess(ctx)
+	delete(s.apis, fingerprint)
+	current, ok := s.apis[apiKey]
+	if !ok {
+		s.apis[apiKey] = restored
+		return
+	}
+	current.TotalRequests += restored.TotalRequests
+	current.TotalTokens += restored.TotalTokens
+	for model, restoredModel := range restored.Models {
+		currentModel, okModel := current.Models[model]
+		if !okModel {
+			current.Models[model] = restoredModel
+			continue
+		}
+		currentModel.TotalRequests += restoredModel.TotalRequests
+		currentModel.TotalTokens += restoredModel.TotalTokens
+		currentModel.Details = append(restoredModel.Details, currentModel.Details...)
 	}
+}
+
+// apply folds a resolved record into the aggregates.
+func (s *RequestStatistics) apply(entry StoredRecord) {
+	timestamp := entry.Timestamp
+	detail := entry.Tokens
+	totalTokens := detail.TotalTokens
+	statsKey := entry.APIKey
+	failed := entry.Failed
 	success := !failed
-	modelName := record.Model
+	modelName := entry.Model
 	if modelName == "" {
 		modelName = "unknown"
 	}
@@ -196,11 +230,14 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		s.apis[statsKey] = stats
 	}
 	s.updateAPIStats(stats, modelName, RequestDetail{
-		Timestamp: timestamp,
-		Source:    record.Source,
-		AuthIndex: record.AuthIndex,
-		Tokens:    detail,
-		Failed:    failed,
+		Timestamp:      timestamp,
+		Source:         entry.Source,
+		Provider:       entry.Provider,
+		AuthIndex:      entry.AuthIndex,
+		Tokens:         detail,
+		Failed:         failed,
+		RequestedModel: entry.RequestedModel,
+		CacheHit:       entry.CacheHit,
 	})
 
 	s.requestsByDay[dayKey]++
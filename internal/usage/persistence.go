@@ -0,0 +1,232 @@
+package usage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+// StoredRecord is the durable representation of a usage record.
+// Request-scoped values such as the API identifier and success flag are resolved
+// before storage because the originating gin context is gone when records are replayed.
+// Client API keys are stored as their fingerprint (see apiKeyFingerprint), never raw.
+type StoredRecord struct {
+	Timestamp time.Time  `json:"timestamp"`
+	APIKey    string     `json:"api_key"`
+	Provider  string     `json:"provider,omitempty"`
+	Model     string     `json:"model"`
+	Source    string     `json:"source,omitempty"`
+	AuthID    string     `json:"auth_id,omitempty"`
+	AuthIndex uint64     `json:"auth_index"`
+	Tokens    TokenStats `json:"tokens"`
+	Failed    bool       `json:"failed"`
+	// RequestedModel is set when a fallback model answered instead of the requested one.
+	RequestedModel string `json:"requested_model,omitempty"`
+	// CacheHit is set when the response cache answered without calling the upstream.
+	CacheHit bool `json:"cache_hit,omitempty"`
+}
+
+// RecordStore persists usage records so statistics survive restarts.
+type RecordStore interface {
+	// Append durably stores a single record.
+	Append(ctx context.Context, record StoredRecord) error
+	// Load invokes fn for every record with a timestamp at or after since, in insertion order.
+	Load(ctx context.Context, since time.Time, fn func(StoredRecord)) error
+	// Prune removes records older than before.
+	Prune(ctx context.Context, before time.Time) error
+	// Close releases resources held by the store.
+	Close() error
+}
+
+// PersistencePlugin writes every usage record to a RecordStore.
+// It implements coreusage.Plugin and runs alongside LoggerPlugin.
+type PersistencePlugin struct {
+	store     RecordStore
+	retention time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// retentionSweepInterval controls how often expired records are pruned.
+const retentionSweepInterval = time.Hour
+
+var (
+	persistenceMu     sync.Mutex
+	persistenceActive *PersistencePlugin
+)
+
+// EnablePersistence replays stored records into the shared statistics store,
+// prunes records outside the retention window, and registers a plugin that
+// appends new records to store. A non-positive retention keeps records forever.
+// Calling it again replaces the previously configured store.
+//
+// Parameters:
+//   - ctx: The context used for the initial load and prune
+//   - store: The durable backend receiving usage records
+//   - retention: How long records are kept
+//
+// Returns:
+//   - *PersistencePlugin: The registered plugin
+//   - error: An error if the stored records could not be loaded
+func EnablePersistence(ctx context.Context, store RecordStore, retention time.Duration) (*PersistencePlugin, error) {
+	if store == nil {
+		return nil, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var since time.Time
+	if retention > 0 {
+		since = time.Now().Add(-retention)
+		if errPrune := store.Prune(ctx, since); errPrune != nil {
+			log.Warnf("usage persistence: failed to prune expired records: %v", errPrune)
+		}
+	}
+	restored := 0
+	stats := GetRequestStatistics()
+	errLoad := store.Load(ctx, since, func(record StoredRecord) {
+		stats.apply(record)
+		restored++
+	})
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	log.Infof("usage persistence: restored %d record(s)", restored)
+
+	plugin := &PersistencePlugin{
+		store:     store,
+		retention: retention,
+		stop:      make(chan struct{}),
+	}
+
+	persistenceMu.Lock()
+	previous := persistenceActive
+	persistenceActive = plugin
+	persistenceMu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	} else {
+		coreusage.RegisterPlugin(persistenceForwarder{})
+	}
+	if retention > 0 {
+		go plugin.sweep()
+	}
+	return plugin, nil
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (p *PersistencePlugin) HandleUsage(ctx context.Context, record coreusage.Record) {
+	if p == nil || p.store == nil {
+		return
+	}
+	if !statisticsEnabled.Load() {
+		return
+	}
+	entry := resolveStoredRecord(ctx, record)
+	if record.APIKey != "" {
+		entry.APIKey = apiKeyFingerprint(record.APIKey)
+	}
+	if errAppend := p.store.Append(ctx, entry); errAppend != nil {
+		log.Warnf("usage persistence: failed to store record: %v", errAppend)
+	}
+}
+
+// Close stops the retention sweeper and closes the underlying store.
+func (p *PersistencePlugin) Close() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		persistenceMu.Lock()
+		if persistenceActive == p {
+			persistenceActive = nil
+		}
+		persistenceMu.Unlock()
+		if p.store != nil {
+			if errClose := p.store.Close(); errClose != nil {
+				log.Warnf("usage persistence: failed to close store: %v", errClose)
+			}
+		}
+	})
+}
+
+func (p *PersistencePlugin) sweep() {
+	ticker := time.NewTicker(retentionSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if errPrune := p.store.Prune(context.Background(), time.Now().Add(-p.retention)); errPrune != nil {
+				log.Warnf("usage persistence: failed to prune expired records: %v", errPrune)
+			}
+		}
+	}
+}
+
+// persistenceForwarder is registered once with the usage manager and delegates
+// to the currently active persistence plugin, so the store can be swapped without
+// registering duplicate plugins.
+type persistenceForwarder struct{}
+
+func (persistenceForwarder) HandleUsage(ctx context.Context, record coreusage.Record) {
+	persistenceMu.Lock()
+	plugin := persistenceActive
+	persistenceMu.Unlock()
+	if plugin != nil {
+		plugin.HandleUsage(ctx, record)
+	}
+}
+
+// resolveStoredRecord converts a runtime record into its durable form, resolving
+// request-scoped fields from the context.
+func resolveStoredRecord(ctx context.Context, record coreusage.Record) StoredRecord {
+	timestamp := record.RequestedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	statsKey := record.APIKey
+	if statsKey == "" {
+		statsKey = resolveAPIIdentifier(ctx, record)
+	}
+	failed := record.Failed
+	if !failed {
+		failed = !resolveSuccess(ctx)
+	}
+	requestedModel := record.RequestedModel
+	if requestedModel == record.Model {
+		requestedModel = ""
+	}
+	return StoredRecord{
+		Timestamp:      timestamp,
+		APIKey:         statsKey,
+		Provider:       record.Provider,
+		Model:          record.Model,
+		Source:         record.Source,
+		AuthID:         record.AuthID,
+		AuthIndex:      record.AuthIndex,
+		Tokens:         normaliseDetail(record.Detail),
+		Failed:         failed,
+		RequestedModel: requestedModel,
+		CacheHit:       record.CacheHit,
+	}
+}
+
+// apiKeyFingerprintPrefix marks statistics keys that are fingerprints of client API keys.
+const apiKeyFingerprintPrefix = "sha256:"
+
+// apiKeyFingerprint returns a stable, non-reversible identifier for a client API
+// key. Unlike the display mask it never maps two keys to the same value in practice.
+func apiKeyFingerprint(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return apiKeyFingerprintPrefix + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,105 @@
+package usage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// memoryRecordStore keeps appended records in memory.
+type memoryRecordStore struct {
+	records []StoredRecord
+}
+
+func (s *memoryRecordStore) Append(_ context.Context, record StoredRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *memoryRecordStore) Load(_ context.Context, _ time.Time, fn func(StoredRecord)) error {
+	for _, record := range s.records {
+		fn(record)
+	}
+	return nil
+}
+
+func (s *memoryRecordStore) Prune(context.Context, time.Time) error { return nil }
+
+func (s *memoryRecordStore) Close() error { return nil }
+
+func TestPersistedRecordsFingerprintAPIKeys(t *testing.T) {
+	// Both keys share their first and last four characters, so their display masks collide.
+	keys := []string{"sk-abcd1111wxyz", "sk-abcd2222wxyz"}
+	store := &memoryRecordStore{}
+	plugin := &PersistencePlugin{store: store}
+	live := NewRequestStatistics()
+	for _, key := range keys {
+		record := coreusage.Record{APIKey: key, Provider: "claude", Model: "claude-sonnet-4-5", Detail: coreusage.Detail{InputTokens: 10, OutputTokens: 5}}
+		plugin.HandleUsage(context.Background(), record)
+		live.Record(context.Background(), record)
+	}
+
+	for _, key := range keys {
+		if _, ok := live.Snapshot().APIs[key]; !ok {
+			t.Fatalf("live statistics are not keyed by the raw key %s", key)
+		}
+	}
+	if len(store.records) != len(keys) {
+		t.Fatalf("stored %d records, want %d", len(store.records), len(keys))
+	}
+	for i, record := range store.records {
+		if strings.Contains(record.APIKey, "abcd") {
+			t.Fatalf("stored record %d carries the API key: %s", i, record.APIKey)
+		}
+		if record.APIKey != apiKeyFingerprint(keys[i]) {
+			t.Fatalf("stored record %d key = %s, want the fingerprint", i, record.APIKey)
+		}
+	}
+	if store.records[0].APIKey == store.records[1].APIKey {
+		t.Fatalf("distinct keys share the stored key %s", store.records[0].APIKey)
+	}
+}
+
+func TestRestoredStatisticsAdoptRawKey(t *testing.T) {
+	key := "sk-restored-key-0001"
+	stats := NewRequestStatistics()
+	stats.apply(StoredRecord{Timestamp: time.Now().Add(-time.Hour), APIKey: apiKeyFingerprint(key), Model: "gpt-5", Tokens: TokenStats{TotalTokens: 7}})
+	stats.apply(StoredRecord{Timestamp: time.Now().Add(-time.Hour), APIKey: "POST /v1/chat/completions", Model: "gpt-5", Tokens: TokenStats{TotalTokens: 3}})
+
+	testCases := []struct {
+		name        string
+		query       string
+		wantMatched int
+	}{
+		{name: "raw key matches restored records", query: key, wantMatched: 1},
+		{name: "fingerprint matches restored records", query: apiKeyFingerprint(key), wantMatched: 1},
+		{name: "other key", query: "sk-other", wantMatched: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := stats.Query(Query{APIKey: tc.query})
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if result.TotalRequests != int64(tc.wantMatched) {
+				t.Fatalf("Query(%s) matched %d requests, want %d", tc.query, result.TotalRequests, tc.wantMatched)
+			}
+		})
+	}
+
+	stats.Record(context.Background(), coreusage.Record{APIKey: key, Model: "gpt-5", Detail: coreusage.Detail{TotalTokens: 5}})
+	snapshot := stats.Snapshot()
+	if _, ok := snapshot.APIs[apiKeyFingerprint(key)]; ok {
+		t.Fatalf("restored statistics stayed under the fingerprint")
+	}
+	api := snapshot.APIs[key]
+	if api.TotalRequests != 2 || api.TotalTokens != 12 || len(api.Models["gpt-5"].Details) != 2 {
+		t.Fatalf("statistics for the raw key = %+v, want the restored and the new request", api)
+	}
+	if snapshot.TotalRequests != 3 {
+		t.Fatalf("TotalRequests = %d, want 3", snapshot.TotalRequests)
+	}
+}
@@ -0,0 +1,335 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Supported Query.GroupBy values.
+const (
+	GroupByAPIKey    = "api_key"
+	GroupByModel     = "model"
+	GroupByAuthIndex = "auth_index"
+	GroupByProvider  = "provider"
+	GroupBySource    = "source"
+)
+
+// Supported Query.Granularity values.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// Query filters and aggregates recorded request details.
+// Zero values disable the corresponding filter.
+type Query struct {
+	// From includes details at or after this instant.
+	From time.Time
+	// To includes details strictly before this instant.
+	To time.Time
+
+	// APIKey, Model, Provider and Source restrict results to exact matches. APIKey
+	// also matches statistics restored from storage under the key's fingerprint.
+	APIKey   string
+	Model    string
+	Provider string
+	Source   string
+	// AuthIndex restricts results to a single credential index when non-nil.
+	AuthIndex *uint64
+
+	// GroupBy aggregates matching details by one of the GroupBy* dimensions.
+	GroupBy string
+	// Granularity buckets aggregates into hourly or daily time series.
+	Granularity string
+
+	// Offset and Limit paginate the matching details, newest first. Limit 0 omits details.
+	Offset int
+	Limit  int
+}
+
+// QueryResult is the aggregated response to a Query.
+type QueryResult struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+
+	TotalRequests int64      `json:"total_requests"`
+	SuccessCount  int64      `json:"success_count"`
+	FailureCount  int64      `json:"failure_count"`
+	Tokens        TokenStats `json:"tokens"`
+
+	GroupBy     string        `json:"group_by,omitempty"`
+	Granularity string        `json:"granularity,omitempty"`
+	Groups      []QueryGroup  `json:"groups,omitempty"`
+	Timeline    []QueryBucket `json:"timeline,omitempty"`
+
+	Details      []QueryDetail `json:"details,omitempty"`
+	TotalDetails int           `json:"total_details"`
+	Offset       int           `json:"offset"`
+	Limit        int           `json:"limit"`
+}
+
+// QueryGroup aggregates details sharing the same GroupBy value.
+type QueryGroup struct {
+	Key           string        `json:"key"`
+	TotalRequests int64         `json:"total_requests"`
+	SuccessCount  int64         `json:"success_count"`
+	FailureCount  int64         `json:"failure_count"`
+	Tokens        TokenStats    `json:"tokens"`
+	Timeline      []QueryBucket `json:"timeline,omitempty"`
+}
+
+// QueryBucket aggregates details within a single time bucket.
+type QueryBucket struct {
+	Start         time.Time `json:"start"`
+	TotalRequests int64     `json:"total_requests"`
+	FailureCount  int64     `json:"failure_count"`
+	TotalTokens   int64     `json:"total_tokens"`
+}
+
+// QueryDetail is a RequestDetail annotated with the API key and model it was recorded under.
+type QueryDetail struct {
+	APIKey string `json:"api_key"`
+	Model  string `json:"model"`
+	RequestDetail
+}
+
+// Validate reports whether the query uses supported dimensions.
+func (q Query) Validate() error {
+	switch q.GroupBy {
+	case "", GroupByAPIKey, GroupByModel, GroupByAuthIndex, GroupByProvider, GroupBySource:
+	default:
+		return fmt.Errorf("unsupported group_by %q", q.GroupBy)
+	}
+	switch q.Granularity {
+	case "", GranularityHour, GranularityDay:
+	default:
+		return fmt.Errorf("unsupported granularity %q", q.Granularity)
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		return fmt.Errorf("offset and limit must be non-negative")
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	return nil
+}
+
+// Query aggregates the recorded details matching q.
+func (s *RequestStatistics) Query(q Query) (QueryResult, error) {
+	result := QueryResult{
+		GroupBy:     q.GroupBy,
+		Granularity: q.Granularity,
+		Offset:      q.Offset,
+		Limit:       q.Limit,
+	}
+	if err := q.Validate(); err != nil {
+		return result, err
+	}
+	if !q.From.IsZero() {
+		from := q.From
+		result.From = &from
+	}
+	if !q.To.IsZero() {
+		to := q.To
+		result.To = &to
+	}
+	if s == nil {
+		return result, nil
+	}
+
+	groups := make(map[string]*queryGroupAccumulator)
+	overall := newQueryGroupAccumulator("")
+	var matched []QueryDetail
+
+	s.mu.RLock()
+	var fingerprint string
+	if q.APIKey != "" {
+		fingerprint = apiKeyFingerprint(q.APIKey)
+	}
+	for apiKey, stats := range s.apis {
+		if q.APIKey != "" && q.APIKey != apiKey && fingerprint != apiKey {
+			continue
+		}
+		for model, modelStatsValue := range stats.Models {
+			if q.Model != "" && q.Model != model {
+				continue
+			}
+			for _, detail := range modelStatsValue.Details {
+				if !q.matches(detail) {
+					continue
+				}
+				overall.add(detail, q.Granularity)
+				if q.GroupBy != "" {
+					key := groupKey(q.GroupBy, apiKey, model, detail)
+					group, ok := groups[key]
+					if !ok {
+						group = newQueryGroupAccumulator(key)
+						groups[key] = group
+					}
+					group.add(detail, q.Granularity)
+				}
+				if q.Limit > 0 {
+					matched = append(matched, QueryDetail{APIKey: apiKey, Model: model, RequestDetail: detail})
+				} else {
+					result.TotalDetails++
+				}
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	result.TotalRequests = overall.requests
+	result.SuccessCount = overall.requests - overall.failures
+	result.FailureCount = overall.failures
+	result.Tokens = overall.tokens
+	result.Timeline = overall.timeline()
+
+	if len(groups) > 0 {
+		result.Groups = make([]QueryGroup, 0, len(groups))
+		for _, group := range groups {
+			result.Groups = append(result.Groups, group.snapshot())
+		}
+		sort.Slice(result.Groups, func(i, j int) bool {
+			if result.Groups[i].TotalRequests != result.Groups[j].TotalRequests {
+				return result.Groups[i].TotalRequests > result.Groups[j].TotalRequests
+			}
+			return result.Groups[i].Key < result.Groups[j].Key
+		})
+	}
+
+	if q.Limit > 0 {
+		result.TotalDetails = len(matched)
+		sort.SliceStable(matched, func(i, j int) bool {
+			return matched[i].Timestamp.After(matched[j].Timestamp)
+		})
+		if q.Offset < len(matched) {
+			end := q.Offset + q.Limit
+			if end > len(matched) {
+				end = len(matched)
+			}
+			result.Details = matched[q.Offset:end]
+		}
+	}
+	return result, nil
+}
+
+func (q Query) matches(detail RequestDetail) bool {
+	if !q.From.IsZero() && detail.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !detail.Timestamp.Before(q.To) {
+		return false
+	}
+	if q.Provider != "" && q.Provider != detail.Provider {
+		return false
+	}
+	if q.Source != "" && q.Source != detail.Source {
+		return false
+	}
+	if q.AuthIndex != nil && *q.AuthIndex != detail.AuthIndex {
+		return false
+	}
+	return true
+}
+
+func groupKey(groupBy, apiKey, model string, detail RequestDetail) string {
+	switch groupBy {
+	case GroupByAPIKey:
+		return apiKey
+	case GroupByModel:
+		return model
+	case GroupByAuthIndex:
+		return strconv.FormatUint(detail.AuthIndex, 10)
+	case GroupByProvider:
+		if detail.Provider == "" {
+			return "unknown"
+		}
+		return detail.Provider
+	case GroupBySource:
+		return detail.Source
+	default:
+		return ""
+	}
+}
+
+// queryGroupAccumulator sums details for a single group and its time buckets.
+type queryGroupAccumulator struct {
+	key      string
+	requests int64
+	failures int64
+	tokens   TokenStats
+	buckets  map[int64]*QueryBucket
+}
+
+func newQueryGroupAccumulator(key string) *queryGroupAccumulator {
+	return &queryGroupAccumulator{key: key}
+}
+
+func (a *queryGroupAccumulator) add(detail RequestDetail, granularity string) {
+	a.requests++
+	if detail.Failed {
+		a.failures++
+	}
+	a.tokens.InputTokens += detail.Tokens.InputTokens
+	a.tokens.OutputTokens += detail.Tokens.OutputTokens
+	a.tokens.ReasoningTokens += detail.Tokens.ReasoningTokens
+	a.tokens.CachedTokens += detail.Tokens.CachedTokens
+	a.tokens.TotalTokens += detail.Tokens.TotalTokens
+
+	if granularity == "" {
+		return
+	}
+	start := bucketStart(detail.Timestamp, granularity)
+	if a.buckets == nil {
+		a.buckets = make(map[int64]*QueryBucket)
+	}
+	bucket, ok := a.buckets[start.Unix()]
+	if !ok {
+		bucket = &QueryBucket{Start: start}
+		a.buckets[start.Unix()] = bucket
+	}
+	bucket.TotalRequests++
+	if detail.Failed {
+		bucket.FailureCount++
+	}
+	bucket.TotalTokens += detail.Tokens.TotalTokens
+}
+
+func (a *queryGroupAccumulator) timeline() []QueryBucket {
+	if len(a.buckets) == 0 {
+		return nil
+	}
+	out := make([]QueryBucket, 0, len(a.buckets))
+	for _, bucket := range a.buckets {
+		out = append(out, *bucket)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+func (a *queryGroupAccumulator) snapshot() QueryGroup {
+	return QueryGroup{
+		Key:           a.key,
+		TotalRequests: a.requests,
+		SuccessCount:  a.requests - a.failures,
+		FailureCount:  a.failures,
+		Tokens:        a.tokens,
+		Timeline:      a.timeline(),
+	}
+}
+
+// bucketStart truncates ts to the start of its hour or local calendar day,
+// matching the keys used by the legacy requests_by_day aggregates.
+func bucketStart(ts time.Time, granularity string) time.Time {
+	ts = ts.Local()
+	switch granularity {
+	case GranularityHour:
+		return time.Date(ts.Year(), ts.Month(), ts.Day(), ts.Hour(), 0, 0, 0, ts.Location())
+	case GranularityDay:
+		return time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, ts.Location())
+	default:
+		return ts
+	}
+}
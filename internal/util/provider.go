@@ -4,6 +4,8 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/url"
 	"strings"
 
@@ -240,6 +242,17 @@ func HideAPIKey(apiKey string) string {
 	return apiKey
 }
 
+// HashAPIKey returns the hex-encoded SHA-256 digest of an API key. It identifies the
+// client that owns stored data without writing the key itself to disk. An empty key
+// hashes to an empty string.
+func HashAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
 // maskAuthorizationHeader masks the Authorization header value while preserving the auth type prefix.
 // Common formats: "Bearer <token>", "Basic <credentials>", "ApiKey <key>", etc.
 // It preserves the prefix (e.g., "Bearer ") and only masks the token/credential part.
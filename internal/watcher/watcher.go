@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authcrypt"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/geminicli"
 	"gopkg.in/yaml.v3"
@@ -1072,6 +1073,10 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 		if err != nil || len(data) == 0 {
 			continue
 		}
+		if data, err = authcrypt.Open(name, data); err != nil {
+			log.Warnf("auth file %s: %v", name, err)
+			continue
+		}
 		var metadata map[string]any
 		if err = json.Unmarshal(data, &metadata); err != nil {
 			continue
@@ -1452,9 +1457,82 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.UsageStatisticsEnabled != newCfg.UsageStatisticsEnabled {
 		changes = append(changes, fmt.Sprintf("usage-statistics-enabled: %t -> %t", oldCfg.UsageStatisticsEnabled, newCfg.UsageStatisticsEnabled))
 	}
+	if oldCfg.UsagePersistence != newCfg.UsagePersistence {
+		changes = append(changes, fmt.Sprintf("usage-persistence: enable=%t path=%s retention-days=%d -> enable=%t path=%s retention-days=%d (restart required)",
+			oldCfg.UsagePersistence.Enable, oldCfg.UsagePersistence.Path, oldCfg.UsagePersistence.RetentionDays,
+			newCfg.UsagePersistence.Enable, newCfg.UsagePersistence.Path, newCfg.UsagePersistence.RetentionDays))
+	}
+	if oldCfg.ResponsesStore != newCfg.ResponsesStore {
+		changes = append(changes, fmt.Sprintf("responses-store: enable=%t max-entries=%d ttl-hours=%d dir=%s -> enable=%t max-entries=%d ttl-hours=%d dir=%s (restart required)",
+			oldCfg.ResponsesStore.Enable, oldCfg.ResponsesStore.MaxEntries, oldCfg.ResponsesStore.TTLHours, oldCfg.ResponsesStore.Dir,
+			newCfg.ResponsesStore.Enable, newCfg.ResponsesStore.MaxEntries, newCfg.ResponsesStore.TTLHours, newCfg.ResponsesStore.Dir))
+	}
+	if oldCfg.Batch != newCfg.Batch {
+		changes = append(changes, fmt.Sprintf("batch: dir=%s concurrency=%d -> dir=%s concurrency=%d (restart required)",
+			oldCfg.Batch.Dir, oldCfg.Batch.Concurrency, newCfg.Batch.Dir, newCfg.Batch.Concurrency))
+	}
+	if oldCfg.Routing.Strategy != newCfg.Routing.Strategy {
+		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", oldCfg.Routing.Strategy, newCfg.Routing.Strategy))
+	}
+	if oldCfg.Routing.SessionAffinity.Enable != newCfg.Routing.SessionAffinity.Enable {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity.enable: %t -> %t", oldCfg.Routing.SessionAffinity.Enable, newCfg.Routing.SessionAffinity.Enable))
+	}
+	if oldCfg.Routing.SessionAffinity.TTLSeconds != newCfg.Routing.SessionAffinity.TTLSeconds {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity.ttl-seconds: %d -> %d", oldCfg.Routing.SessionAffinity.TTLSeconds, newCfg.Routing.SessionAffinity.TTLSeconds))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelFallbacks, newCfg.ModelFallbacks) {
+		changes = append(changes, fmt.Sprintf("model-fallbacks updated (%d -> %d chains)", len(oldCfg.ModelFallbacks), len(newCfg.ModelFallbacks)))
+	}
+	if oldCfg.Hedging.DelayMS != newCfg.Hedging.DelayMS {
+		changes = append(changes, fmt.Sprintf("hedging.delay-ms: %d -> %d", oldCfg.Hedging.DelayMS, newCfg.Hedging.DelayMS))
+	}
+	if !reflect.DeepEqual(oldCfg.Hedging.Models, newCfg.Hedging.Models) {
+		changes = append(changes, fmt.Sprintf("hedging.models: %v -> %v", oldCfg.Hedging.Models, newCfg.Hedging.Models))
+	}
+	if oldCfg.AuthEncryption != newCfg.AuthEncryption {
+		changes = append(changes, fmt.Sprintf("auth-encryption.key-file: %s -> %s (restart required)", oldCfg.AuthEncryption.KeyFile, newCfg.AuthEncryption.KeyFile))
+	}
+	if oldCfg.Cassette != newCfg.Cassette {
+		changes = append(changes, fmt.Sprintf("cassette: mode=%s dir=%s -> mode=%s dir=%s (restart required)",
+			oldCfg.Cassette.Mode, oldCfg.Cassette.Dir, newCfg.Cassette.Mode, newCfg.Cassette.Dir))
+	}
+	if oldCfg.ResponseCache != newCfg.ResponseCache {
+		changes = append(changes, fmt.Sprintf("response-cache: enable=%t ttl-seconds=%d max-entries=%d max-size-mb=%d max-entry-kb=%d -> enable=%t ttl-seconds=%d max-entries=%d max-size-mb=%d max-entry-kb=%d",
+			oldCfg.ResponseCache.Enable, oldCfg.ResponseCache.TTLSeconds, oldCfg.ResponseCache.MaxEntries, oldCfg.ResponseCache.MaxSizeMB, oldCfg.ResponseCache.MaxEntryKB,
+			newCfg.ResponseCache.Enable, newCfg.ResponseCache.TTLSeconds, newCfg.ResponseCache.MaxEntries, newCfg.ResponseCache.MaxSizeMB, newCfg.ResponseCache.MaxEntryKB))
+	}
+	if oldCfg.CircuitBreaker != newCfg.CircuitBreaker {
+		changes = append(changes, fmt.Sprintf("circuit-breaker: disable=%t failure-threshold=%d open-seconds=%d half-open-probes=%d -> disable=%t failure-threshold=%d open-seconds=%d half-open-probes=%d",
+			oldCfg.CircuitBreaker.Disable, oldCfg.CircuitBreaker.FailureThreshold, oldCfg.CircuitBreaker.OpenSeconds, oldCfg.CircuitBreaker.HalfOpenProbes,
+			newCfg.CircuitBreaker.Disable, newCfg.CircuitBreaker.FailureThreshold, newCfg.CircuitBreaker.OpenSeconds, newCfg.CircuitBreaker.HalfOpenProbes))
+	}
+	if !reflect.DeepEqual(oldCfg.HealthCheck, newCfg.HealthCheck) {
+		changes = append(changes, fmt.Sprintf("health-check: enable=%t interval-seconds=%d providers=%d -> enable=%t interval-seconds=%d providers=%d",
+			oldCfg.HealthCheck.Enable, oldCfg.HealthCheck.IntervalSeconds, len(oldCfg.HealthCheck.Providers),
+			newCfg.HealthCheck.Enable, newCfg.HealthCheck.IntervalSeconds, len(newCfg.HealthCheck.Providers)))
+	}
+	if oldCfg.QuotaTracking != newCfg.QuotaTracking {
+		changes = append(changes, fmt.Sprintf("quota-tracking: disable-avoidance=%t min-remaining-percent=%d -> disable-avoidance=%t min-remaining-percent=%d",
+			oldCfg.QuotaTracking.DisableAvoidance, oldCfg.QuotaTracking.MinRemainingPercent,
+			newCfg.QuotaTracking.DisableAvoidance, newCfg.QuotaTracking.MinRemainingPercent))
+	}
+	if oldCfg.Metrics.Enable != newCfg.Metrics.Enable {
+		changes = append(changes, fmt.Sprintf("metrics.enable: %t -> %t", oldCfg.Metrics.Enable, newCfg.Metrics.Enable))
+	}
+	if oldCfg.Metrics.BearerToken != newCfg.Metrics.BearerToken {
+		changes = append(changes, "metrics.bearer-token: updated")
+	}
 	if oldCfg.DisableCooling != newCfg.DisableCooling {
 		changes = append(changes, fmt.Sprintf("disable-cooling: %t -> %t", oldCfg.DisableCooling, newCfg.DisableCooling))
 	}
+	if !reflect.DeepEqual(oldCfg.APIKeyPolicies, newCfg.APIKeyPolicies) {
+		changes = append(changes, fmt.Sprintf("api-key-policies updated (%d -> %d entries)", len(oldCfg.APIKeyPolicies), len(newCfg.APIKeyPolicies)))
+	}
+	if oldCfg.ContextGuard != newCfg.ContextGuard {
+		changes = append(changes, fmt.Sprintf("context-guard: enable=%t strategy=%s keep-last=%d summary-model=%s -> enable=%t strategy=%s keep-last=%d summary-model=%s",
+			oldCfg.ContextGuard.Enable, oldCfg.ContextGuard.Strategy, oldCfg.ContextGuard.KeepLast, oldCfg.ContextGuard.SummaryModel,
+			newCfg.ContextGuard.Enable, newCfg.ContextGuard.Strategy, newCfg.ContextGuard.KeepLast, newCfg.ContextGuard.SummaryModel))
+	}
 	if oldCfg.RequestLog != newCfg.RequestLog {
 		changes = append(changes, fmt.Sprintf("request-log: %t -> %t", oldCfg.RequestLog, newCfg.RequestLog))
 	}
@@ -87,6 +87,16 @@ func (m *Manager) Path() string {
 	return m.path
 }
 
+// SessionCount reports the number of connected websocket sessions.
+func (m *Manager) SessionCount() int {
+	if m == nil {
+		return 0
+	}
+	m.sessMutex.RLock()
+	defer m.sessMutex.RUnlock()
+	return len(m.sessions)
+}
+
 // Handler exposes an http.Handler that upgrades connections to websocket sessions.
 func (m *Manager) Handler() http.Handler {
 	return http.HandlerFunc(m.handleWebsocket)
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+)
+
+// AffinityHeader lets clients name the conversation explicitly so every turn is
+// routed to the same credential.
+const AffinityHeader = "X-Session-Affinity"
+
+// affinityKey derives the session affinity key for a request. Sources are tried in
+// order: the AffinityHeader, Claude metadata.user_id, the OpenAI prompt_cache_key or
+// user field, and finally a hash of the system prompt and first user turn. Keys are
+// scoped by the client API key so different clients never share a pin.
+func affinityKey(ctx context.Context, rawJSON []byte) string {
+	key := ""
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		if v := strings.TrimSpace(ginCtx.GetHeader(AffinityHeader)); v != "" {
+			key = "h:" + v
+		}
+	}
+	if key == "" && len(rawJSON) > 0 {
+		for _, path := range []string{"metadata.user_id", "prompt_cache_key", "user"} {
+			if v := strings.TrimSpace(gjson.GetBytes(rawJSON, path).String()); v != "" {
+				key = "f:" + v
+				break
+			}
+		}
+	}
+	if key == "" {
+		key = leadingMessagesHash(rawJSON)
+	}
+	if key == "" {
+		return ""
+	}
+	return clientAPIKeyFromContext(ctx) + "|" + key
+}
+
+// leadingMessagesHash fingerprints the stable prefix of a conversation across the
+// OpenAI, Claude, Gemini and Responses request shapes: the system prompt and the
+// first conversational turn. Leading system and developer messages belong to the
+// prompt, which many conversations share, so they never stand in for that turn.
+func leadingMessagesHash(rawJSON []byte) string {
+	if len(rawJSON) == 0 {
+		return ""
+	}
+	sum := sha256.New()
+	for _, path := range []string{"system", "instructions", "systemInstruction", "system_instruction", "request.systemInstruction"} {
+		if system := gjson.GetBytes(rawJSON, path); system.Exists() {
+			sum.Write([]byte(system.Raw))
+			break
+		}
+	}
+	var first gjson.Result
+	for _, path := range []string{"messages", "contents", "request.contents", "input"} {
+		list := gjson.GetBytes(rawJSON, path)
+		if !list.IsArray() {
+			continue
+		}
+		list.ForEach(func(_, item gjson.Result) bool {
+			switch item.Get("role").String() {
+			case "system", "developer":
+				sum.Write([]byte(item.Raw))
+				return true
+			}
+			first = item
+			return false
+		})
+		break
+	}
+	if !first.Exists() {
+		if input := gjson.GetBytes(rawJSON, "input"); input.Type == gjson.String {
+			first = input
+		}
+	}
+	if !first.Exists() {
+		return ""
+	}
+	sum.Write([]byte{0})
+	sum.Write([]byte(first.Raw))
+	return "m:" + hex.EncodeToString(sum.Sum(nil)[:16])
+}
+
+// withAffinityKey stores the request's affinity key in the execution metadata.
+func withAffinityKey(ctx context.Context, metadata map[string]any, rawJSON []byte) map[string]any {
+	key := affinityKey(ctx, rawJSON)
+	if key == "" {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]any, 1)
+	}
+	metadata[coreauth.AffinityMetadataKey] = key
+	return metadata
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// apiKeyLimiter tracks per-client-key request rates, daily token consumption and
+// open streams. Limits themselves come from the live SDKConfig on every request so
+// hot reloads apply immediately; only counters are kept here.
+type apiKeyLimiter struct {
+	mu        sync.Mutex
+	state     map[string]*apiKeyUsageState
+	lastPrune time.Time
+}
+
+// apiKeyStatePruneInterval is how often idle per-key counters are dropped.
+const apiKeyStatePruneInterval = 10 * time.Minute
+
+type apiKeyUsageState struct {
+	requests   []time.Time
+	tokenDay   string
+	tokensUsed int64
+	streams    int
+}
+
+var defaultAPIKeyLimiter = newAPIKeyLimiter()
+
+func init() {
+	coreusage.RegisterPlugin(defaultAPIKeyLimiter)
+}
+
+func newAPIKeyLimiter() *apiKeyLimiter {
+	return &apiKeyLimiter{state: make(map[string]*apiKeyUsageState)}
+}
+
+// HandleUsage implements coreusage.Plugin and accumulates daily token usage per client key.
+func (l *apiKeyLimiter) HandleUsage(_ context.Context, record coreusage.Record) {
+	if l == nil || record.APIKey == "" {
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens == 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	if tokens <= 0 {
+		return
+	}
+	ts := record.RequestedAt
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	day := ts.Format("2006-01-02")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state := l.stateFor(record.APIKey)
+	if state.tokenDay != day {
+		if state.tokenDay > day {
+			return
+		}
+		state.tokenDay = day
+		state.tokensUsed = 0
+	}
+	state.tokensUsed += tokens
+}
+
+// admit checks the policy for a request and reserves capacity for it. The returned
+// release function must be called once a streaming request finishes; it is a no-op
+// for non-streaming requests.
+func (l *apiKeyLimiter) admit(policy *config.APIKeyPolicy, model string, stream bool, now time.Time) (func(), *apiKeyLimitError) {
+	noop := func() {}
+	if policy == nil {
+		return noop, nil
+	}
+	if !modelAllowed(policy.AllowedModels, model) {
+		return noop, &apiKeyLimitError{
+			code:    "model_not_allowed",
+			message: fmt.Sprintf("API key is not permitted to use model %s", model),
+			status:  http.StatusForbidden,
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	state := l.stateFor(policy.APIKey)
+
+	if policy.TokensPerDay > 0 {
+		day := now.Format("2006-01-02")
+		if state.tokenDay == day && state.tokensUsed >= policy.TokensPerDay {
+			y, m, d := now.Date()
+			midnight := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+			return noop, &apiKeyLimitError{
+				code:    "tokens_per_day_exceeded",
+				message: fmt.Sprintf("API key exceeded its daily token quota of %d", policy.TokensPerDay),
+				status:  http.StatusTooManyRequests,
+				resetIn: midnight.Sub(now),
+			}
+		}
+	}
+
+	if policy.RequestsPerMinute > 0 {
+		cutoff := now.Add(-time.Minute)
+		kept := state.requests[:0]
+		for _, ts := range state.requests {
+			if ts.After(cutoff) {
+				kept = append(kept, ts)
+			}
+		}
+		state.requests = kept
+		if len(state.requests) >= policy.RequestsPerMinute {
+			return noop, &apiKeyLimitError{
+				code:    "requests_per_minute_exceeded",
+				message: fmt.Sprintf("API key exceeded its limit of %d requests per minute", policy.RequestsPerMinute),
+				status:  http.StatusTooManyRequests,
+				resetIn: state.requests[0].Add(time.Minute).Sub(now),
+			}
+		}
+	}
+
+	if stream && policy.MaxConcurrentStreams > 0 && state.streams >= policy.MaxConcurrentStreams {
+		return noop, &apiKeyLimitError{
+			code:    "concurrent_streams_exceeded",
+			message: fmt.Sprintf("API key exceeded its limit of %d concurrent streams", policy.MaxConcurrentStreams),
+			status:  http.StatusTooManyRequests,
+			resetIn: time.Second,
+		}
+	}
+
+	if policy.RequestsPerMinute > 0 {
+		state.requests = append(state.requests, now)
+	}
+	if !stream {
+		return noop, nil
+	}
+	state.streams++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			if state.streams > 0 {
+				state.streams--
+			}
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// stateFor returns the counters for key. Callers must hold l.mu.
+func (l *apiKeyLimiter) stateFor(key string) *apiKeyUsageState {
+	state, ok := l.state[key]
+	if !ok {
+		state = &apiKeyUsageState{}
+		l.state[key] = state
+	}
+	return state
+}
+
+// prune drops the counters of keys that have no open stream, no request in the
+// last minute and no token usage today. Callers must hold l.mu.
+func (l *apiKeyLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < apiKeyStatePruneInterval {
+		return
+	}
+	l.lastPrune = now
+	today := now.Format("2006-01-02")
+	cutoff := now.Add(-time.Minute)
+	for key, state := range l.state {
+		if state.streams > 0 || state.tokenDay == today {
+			continue
+		}
+		if n := len(state.requests); n > 0 && state.requests[n-1].After(cutoff) {
+			continue
+		}
+		delete(l.state, key)
+	}
+}
+
+func modelAllowed(allowed []string, model string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, entry := range allowed {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" || entry == model {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok && strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyLimitError reports a request rejected by an API key policy.
+type apiKeyLimitError struct {
+	code    string
+	message string
+	status  int
+	resetIn time.Duration
+}
+
+func (e *apiKeyLimitError) Error() string {
+	errorBody := map[string]any{
+		"code":    e.code,
+		"message": e.message,
+	}
+	if e.status == http.StatusTooManyRequests {
+		errorBody["reset_seconds"] = e.resetSeconds()
+	}
+	data, err := json.Marshal(map[string]any{"error": errorBody})
+	if err != nil {
+		return fmt.Sprintf(`{"error":{"code":"%s","message":"%s"}}`, e.code, e.message)
+	}
+	return string(data)
+}
+
+func (e *apiKeyLimitError) StatusCode() int { return e.status }
+
+func (e *apiKeyLimitError) Headers() http.Header {
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	if e.status == http.StatusTooManyRequests {
+		headers.Set("Retry-After", strconv.Itoa(e.resetSeconds()))
+	}
+	return headers
+}
+
+func (e *apiKeyLimitError) resetSeconds() int {
+	seconds := int(math.Ceil(e.resetIn.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// enforceAPIKeyPolicy applies the caller's API key policy, if any, to a request for modelName.
+// The returned release function must be invoked when the request finishes.
+func (h *BaseAPIHandler) enforceAPIKeyPolicy(ctx context.Context, modelName string, stream bool) (func(), *interfaces.ErrorMessage) {
+	policy := h.Cfg.APIKeyPolicy(clientAPIKeyFromContext(ctx))
+	release, errLimit := defaultAPIKeyLimiter.admit(policy, modelName, stream, time.Now())
+	if errLimit != nil {
+		return release, &interfaces.ErrorMessage{StatusCode: errLimit.StatusCode(), Error: errLimit, Addon: errLimit.Headers()}
+	}
+	return release, nil
+}
+
+// withAllowedModels restricts the fallback models the auth manager may try to those
+// the caller's API key policy allows, so a fallback chain cannot reach a model the
+// key could not request directly.
+func (h *BaseAPIHandler) withAllowedModels(ctx context.Context, metadata map[string]any) map[string]any {
+	policy := h.Cfg.APIKeyPolicy(clientAPIKeyFromContext(ctx))
+	if policy == nil || len(policy.AllowedModels) == 0 {
+		return metadata
+	}
+	allowed := append([]string(nil), policy.AllowedModels...)
+	if metadata == nil {
+		metadata = make(map[string]any, 1)
+	}
+	metadata[coreauth.FallbackFilterMetadataKey] = func(model string) bool {
+		return modelAllowed(allowed, model)
+	}
+	return metadata
+}
+
+// clientAPIKeyFromContext returns the client principal attached with
+// coreusage.WithClientAPIKey or stored by the access middleware.
+func clientAPIKeyFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if key, ok := coreusage.ClientAPIKeyFromContext(ctx); ok {
+		return key
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	if v, exists := ginCtx.Get("apiKey"); exists {
+		if key, okKey := v.(string); okKey {
+			return key
+		}
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// batchHandlerTypes maps the endpoints accepted in batches to the handler type whose
+// schema their request bodies use.
+var batchHandlerTypes = map[string]string{
+	"/v1/messages":         constant.Claude,
+	"/v1/chat/completions": constant.OpenAI,
+	"/v1/responses":        constant.OpenaiResponse,
+	"/v1/embeddings":       constant.OpenAI,
+}
+
+// BatchEndpointSupported reports whether requests for endpoint can be batched.
+func BatchEndpointSupported(endpoint string) bool {
+	_, ok := batchHandlerTypes[endpoint]
+	return ok
+}
+
+// ExecuteBatchRequest runs one batch request through the auth manager, as if the
+// job's owner had sent it to the job's endpoint without streaming.
+// It implements batch.Executor.
+func (h *BaseAPIHandler) ExecuteBatchRequest(ctx context.Context, job batch.Job, req batch.Request) batch.Result {
+	handlerType, ok := batchHandlerTypes[job.Endpoint]
+	if !ok {
+		return batch.Result{Type: batch.ResultErrored, StatusCode: http.StatusBadRequest, Error: fmt.Sprintf("unsupported endpoint %s", job.Endpoint)}
+	}
+	body := []byte(req.Body)
+	modelName := gjson.GetBytes(body, "model").String()
+	if modelName == "" {
+		return batch.Result{Type: batch.ResultErrored, StatusCode: http.StatusBadRequest, Error: "model is required"}
+	}
+	body, _ = sjson.DeleteBytes(body, "stream")
+	ownerKey := job.OwnerKey
+	if ownerKey == "" && job.Owner != "" {
+		// Jobs resumed after a restart only know the hash of their owner's key.
+		if ownerKey = h.apiKeyForOwner(job.Owner); ownerKey == "" {
+			return batch.Result{Type: batch.ResultErrored, StatusCode: http.StatusUnauthorized, Error: "the API key that created this batch is no longer configured"}
+		}
+	}
+
+	// Policies and usage attribution apply to the job owner as for live requests.
+	ctx, cancel := context.WithCancel(coreusage.WithClientAPIKey(ctx, ownerKey))
+	defer cancel()
+	var resp []byte
+	var errMsg *interfaces.ErrorMessage
+	for {
+		if job.Endpoint == "/v1/embeddings" {
+			resp, errMsg = h.ExecuteEmbeddingWithAuthManager(ctx, handlerType, modelName, body)
+		} else {
+			resp, errMsg = h.ExecuteWithAuthManager(ctx, handlerType, modelName, body, "")
+		}
+		wait, paced := batchPaceDelay(errMsg)
+		if !paced {
+			break
+		}
+		// Batches run at the pace the owner's key allows instead of failing the overflow.
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if errMsg != nil && ctx.Err() != nil {
+		// The job was canceled or expired while this request waited or ran.
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return batch.Result{Type: batch.ResultExpired}
+		}
+		return batch.Result{Type: batch.ResultCanceled}
+	}
+	if errMsg != nil {
+		result := batch.Result{Type: batch.ResultErrored, StatusCode: errMsg.StatusCode}
+		if errMsg.Error != nil {
+			result.Error = errMsg.Error.Error()
+		}
+		return result
+	}
+	resp = gunzipIfNeeded(resp)
+	if !json.Valid(resp) {
+		return batch.Result{Type: batch.ResultErrored, StatusCode: http.StatusBadGateway, Error: "upstream returned an invalid JSON response"}
+	}
+	return batch.Result{Type: batch.ResultSucceeded, StatusCode: http.StatusOK, Body: resp}
+}
+
+// batchPaceDelay reports how long to wait before retrying a batch request that the
+// owner's per-minute request or concurrency limit rejected. Other errors, including
+// an exhausted daily token quota, end the request.
+func batchPaceDelay(errMsg *interfaces.ErrorMessage) (time.Duration, bool) {
+	if errMsg == nil {
+		return 0, false
+	}
+	var errLimit *apiKeyLimitError
+	if !errors.As(errMsg.Error, &errLimit) {
+		return 0, false
+	}
+	switch errLimit.code {
+	case "requests_per_minute_exceeded", "concurrent_streams_exceeded":
+		return time.Duration(errLimit.resetSeconds()) * time.Second, true
+	}
+	return 0, false
+}
+
+// BatchViewer identifies the caller for batch job and file lookups.
+func (h *BaseAPIHandler) BatchViewer(c *gin.Context) batch.Viewer {
+	key := ClientAPIKey(c)
+	return batch.Viewer{Key: key, Admin: h.isAdminAPIKey(key)}
+}
+
+// apiKeyForOwner returns the configured client API key whose hash is owner, or an
+// empty string when no configured key matches.
+func (h *BaseAPIHandler) apiKeyForOwner(owner string) string {
+	if h.Cfg == nil {
+		return ""
+	}
+	for _, key := range h.Cfg.APIKeys {
+		if util.HashAPIKey(key) == owner {
+			return key
+		}
+	}
+	for _, policy := range h.Cfg.APIKeyPolicies {
+		if util.HashAPIKey(policy.APIKey) == owner {
+			return policy.APIKey
+		}
+	}
+	return ""
+}
+
+// gunzipIfNeeded decompresses gzip bodies some upstreams return without Content-Encoding.
+func gunzipIfNeeded(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return data
+	}
+	defer func() { _ = reader.Close() }()
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return data
+	}
+	return decompressed
+}
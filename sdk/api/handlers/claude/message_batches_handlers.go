@@ -0,0 +1,209 @@
+package claude
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+// customIDPattern matches the custom_id values accepted by the Message Batches API.
+var customIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// CreateMessageBatch handles POST /v1/messages/batches. Each request's params are
+// executed as a non-streaming /v1/messages call in the background.
+func (h *ClaudeCodeAPIHandler) CreateMessageBatch(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	items := gjson.GetBytes(rawJSON, "requests").Array()
+	if len(items) == 0 {
+		writeBatchError(c, http.StatusBadRequest, "invalid_request_error", "requests: must contain at least one request")
+		return
+	}
+	if len(items) > batch.MaxRequests {
+		writeBatchError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests: at most %d requests are allowed", batch.MaxRequests))
+		return
+	}
+	requests := make([]batch.Request, 0, len(items))
+	seen := make(map[string]struct{}, len(items))
+	for i, item := range items {
+		customID := item.Get("custom_id").String()
+		if !customIDPattern.MatchString(customID) {
+			writeBatchError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.custom_id: must be 1-64 letters, digits, underscores or hyphens", i))
+			return
+		}
+		if _, dup := seen[customID]; dup {
+			writeBatchError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.custom_id: duplicate custom_id %q", i, customID))
+			return
+		}
+		seen[customID] = struct{}{}
+		params := item.Get("params")
+		if !params.IsObject() || params.Get("model").String() == "" {
+			writeBatchError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("requests.%d.params: must be a Messages request with a model", i))
+			return
+		}
+		requests = append(requests, batch.Request{CustomID: customID, Body: []byte(params.Raw)})
+	}
+	job, err := batch.DefaultManager().Create(batch.Job{
+		Kind:     batch.KindAnthropic,
+		OwnerKey: handlers.ClientAPIKey(c),
+		Endpoint: "/v1/messages",
+	}, requests)
+	if err != nil {
+		writeBatchError(c, http.StatusInternalServerError, "api_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, batch.AnthropicBatch(job, resultsURL(c, job.ID)))
+}
+
+// ListMessageBatches handles GET /v1/messages/batches, newest first.
+func (h *ClaudeCodeAPIHandler) ListMessageBatches(c *gin.Context) {
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			writeBatchError(c, http.StatusBadRequest, "invalid_request_error", "limit: must be between 1 and 1000")
+			return
+		}
+		limit = parsed
+	}
+	jobs := batch.DefaultManager().List(batch.KindAnthropic, h.BatchViewer(c))
+	var page []batch.Job
+	var hasMore bool
+	if beforeID := c.Query("before_id"); beforeID != "" {
+		end := indexOfJob(jobs, beforeID)
+		start := max(end-limit, 0)
+		page, hasMore = jobs[start:end], start > 0
+	} else {
+		start := 0
+		if afterID := c.Query("after_id"); afterID != "" {
+			start = min(indexOfJob(jobs, afterID)+1, len(jobs))
+		}
+		end := min(start+limit, len(jobs))
+		page, hasMore = jobs[start:end], end < len(jobs)
+	}
+	data := make([]map[string]any, 0, len(page))
+	for _, job := range page {
+		data = append(data, batch.AnthropicBatch(job, resultsURL(c, job.ID)))
+	}
+	resp := gin.H{"data": data, "has_more": hasMore, "first_id": nil, "last_id": nil}
+	if len(page) > 0 {
+		resp["first_id"] = page[0].ID
+		resp["last_id"] = page[len(page)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetMessageBatch handles GET /v1/messages/batches/:id.
+func (h *ClaudeCodeAPIHandler) GetMessageBatch(c *gin.Context) {
+	job, ok := h.lookupMessageBatch(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, batch.AnthropicBatch(job, resultsURL(c, job.ID)))
+}
+
+// MessageBatchResults handles GET /v1/messages/batches/:id/results, streaming the
+// results of an ended batch as JSON Lines.
+func (h *ClaudeCodeAPIHandler) MessageBatchResults(c *gin.Context) {
+	if _, ok := h.lookupMessageBatch(c); !ok {
+		return
+	}
+	job, results, err := batch.DefaultManager().Results(c.Param("id"), h.BatchViewer(c))
+	if err != nil {
+		writeBatchLookupError(c, err)
+		return
+	}
+	if job.Status != batch.StatusEnded {
+		writeBatchError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Message batch %s is still processing; results are available once it has ended.", job.ID))
+		return
+	}
+	var buf bytes.Buffer
+	for _, result := range results {
+		buf.Write(batch.AnthropicResultLine(result))
+		buf.WriteByte('\n')
+	}
+	c.Data(http.StatusOK, "application/x-jsonl", buf.Bytes())
+}
+
+// CancelMessageBatch handles POST /v1/messages/batches/:id/cancel.
+func (h *ClaudeCodeAPIHandler) CancelMessageBatch(c *gin.Context) {
+	if _, ok := h.lookupMessageBatch(c); !ok {
+		return
+	}
+	job, err := batch.DefaultManager().Cancel(c.Param("id"), h.BatchViewer(c))
+	if err != nil {
+		writeBatchLookupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, batch.AnthropicBatch(job, resultsURL(c, job.ID)))
+}
+
+// DeleteMessageBatch handles DELETE /v1/messages/batches/:id.
+func (h *ClaudeCodeAPIHandler) DeleteMessageBatch(c *gin.Context) {
+	if _, ok := h.lookupMessageBatch(c); !ok {
+		return
+	}
+	id := c.Param("id")
+	if err := batch.DefaultManager().Delete(id, h.BatchViewer(c)); err != nil {
+		if errors.Is(err, batch.ErrNotEnded) {
+			writeBatchError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Message batch %s cannot be deleted while it is processing; cancel it first.", id))
+			return
+		}
+		writeBatchLookupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "type": "message_batch_deleted"})
+}
+
+// lookupMessageBatch fetches the message batch named by the :id path parameter,
+// writing a not-found error when it is missing or is an OpenAI batch.
+func (h *ClaudeCodeAPIHandler) lookupMessageBatch(c *gin.Context) (batch.Job, bool) {
+	job, err := batch.DefaultManager().Get(c.Param("id"), h.BatchViewer(c))
+	if err == nil && job.Kind != batch.KindAnthropic {
+		err = batch.ErrNotFound
+	}
+	if err != nil {
+		writeBatchLookupError(c, err)
+		return batch.Job{}, false
+	}
+	return job, true
+}
+
+// indexOfJob returns the position of id in jobs, or len(jobs) when it is absent.
+func indexOfJob(jobs []batch.Job, id string) int {
+	for i, job := range jobs {
+		if job.ID == id {
+			return i
+		}
+	}
+	return len(jobs)
+}
+
+func resultsURL(c *gin.Context, id string) string {
+	return handlers.RequestBaseURL(c) + "/v1/messages/batches/" + id + "/results"
+}
+
+func writeBatchLookupError(c *gin.Context, err error) {
+	if errors.Is(err, batch.ErrNotFound) {
+		writeBatchError(c, http.StatusNotFound, "not_found_error", "Message batch not found.")
+		return
+	}
+	writeBatchError(c, http.StatusInternalServerError, "api_error", err.Error())
+}
+
+func writeBatchError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{Message: message, Type: errType},
+	})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/contextguard"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ContextHeader reports how the context guard shortened a request that did not
+// fit the model's context window, e.g. "drop-oldest; removed=6; tokens=210400->182000".
+const ContextHeader = "X-CLIProxy-Context"
+
+const (
+	contextStrategyReject     = "reject"
+	contextStrategyDropOldest = "drop-oldest"
+	contextStrategyKeepLast   = "keep-last"
+	contextStrategySummarize  = "summarize"
+
+	defaultContextKeepLast = 20
+	// summaryMaxTokens bounds the length of the summary that replaces older turns.
+	summaryMaxTokens = 2048
+)
+
+const summaryInstructions = "Summarize the conversation below so it can replace the original messages. " +
+	"Keep facts, decisions, open questions, file names, code identifiers and anything the user asked to remember. " +
+	"Write in the language of the conversation and reply with the summary only."
+
+// contextGuardSkipKey marks the guard's own summarization request so it is not guarded again.
+type contextGuardSkipKey struct{}
+
+// guardContext checks rawJSON against the context window of model and applies the
+// configured strategy when it does not fit. It returns the payload to send.
+func (h *BaseAPIHandler) guardContext(ctx context.Context, handlerType, model string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	if h.Cfg == nil || !h.Cfg.ContextGuard.Enable {
+		return rawJSON, nil
+	}
+	if skip, _ := ctx.Value(contextGuardSkipKey{}).(bool); skip {
+		return rawJSON, nil
+	}
+	limit := contextguard.InputLimit(registry.GetGlobalRegistry().GetModelInfo(model), contextguard.MaxOutputTokens(rawJSON))
+	// Every token covers at least one byte, so smaller payloads always fit.
+	if limit <= 0 || len(rawJSON) <= limit {
+		return rawJSON, nil
+	}
+	enc, err := util.TokenizerForModel(model)
+	if err != nil {
+		log.Debugf("context guard: no tokenizer for %s: %v", model, err)
+		return rawJSON, nil
+	}
+
+	cfg := h.Cfg.ContextGuard
+	strategy := strings.ToLower(strings.TrimSpace(cfg.Strategy))
+	conv := contextguard.Parse(handlerType, rawJSON, enc)
+	if conv == nil {
+		if tokens := contextguard.CountTokens(enc, rawJSON); tokens > limit {
+			return nil, contextLengthErrorMessage(model, tokens, limit, "")
+		}
+		return rawJSON, nil
+	}
+	tokens := conv.Tokens(0)
+	if tokens <= limit {
+		return rawJSON, nil
+	}
+
+	keepLast := cfg.KeepLast
+	if keepLast <= 0 {
+		keepLast = defaultContextKeepLast
+	}
+	switch strategy {
+	case contextStrategyDropOldest:
+		return applyContextCut(ctx, conv, conv.DropOldest(limit), strategy, model, tokens, limit)
+	case contextStrategyKeepLast:
+		return applyContextCut(ctx, conv, conv.KeepLast(keepLast), strategy, model, tokens, limit)
+	case contextStrategySummarize:
+		cut := conv.KeepLast(keepLast)
+		if cut <= 0 {
+			return nil, contextLengthErrorMessage(model, tokens, limit, strategy)
+		}
+		summary, errSummary := h.summarizeTurns(ctx, cfg.SummaryModel, conv.Transcript(cut))
+		if errSummary != nil {
+			log.Warnf("context guard: summarizing older turns for %s failed, dropping them instead: %v", model, errSummary)
+			return applyContextCut(ctx, conv, conv.DropOldest(limit), contextStrategyDropOldest, model, tokens, limit)
+		}
+		payload := conv.Summarized(cut, summary)
+		after := contextguard.CountTokens(enc, payload)
+		if after > limit {
+			return nil, contextLengthErrorMessage(model, after, limit, strategy)
+		}
+		setContextHeader(ctx, fmt.Sprintf("%s; summarized=%d; tokens=%d->%d", strategy, conv.Removed(cut), tokens, after))
+		return payload, nil
+	default:
+		if strategy != "" && strategy != contextStrategyReject {
+			log.Warnf("context guard: unknown strategy %q, rejecting request", cfg.Strategy)
+		}
+		return nil, contextLengthErrorMessage(model, tokens, limit, "")
+	}
+}
+
+// applyContextCut removes the messages before cut, or rejects the request when the
+// result would still not fit.
+func applyContextCut(ctx context.Context, conv *contextguard.Conversation, cut int, strategy, model string, tokens, limit int) ([]byte, *interfaces.ErrorMessage) {
+	if cut <= 0 || conv.Tokens(cut) > limit {
+		return nil, contextLengthErrorMessage(model, tokens, limit, strategy)
+	}
+	setContextHeader(ctx, fmt.Sprintf("%s; removed=%d; tokens=%d->%d", strategy, conv.Removed(cut), tokens, conv.Tokens(cut)))
+	return conv.Trim(cut), nil
+}
+
+// summarizeTurns asks summaryModel, through the regular execution path, to condense transcript.
+// The summary is the proxy's own request: it runs on a context detached from the
+// caller's values, so it is not counted against the client key's limits, is not
+// restricted by its allowed models and does not wait for one of its concurrency
+// slots while the caller holds another. It is still cancelled with the caller.
+func (h *BaseAPIHandler) summarizeTurns(ctx context.Context, summaryModel, transcript string) (string, error) {
+	summaryModel = strings.TrimSpace(summaryModel)
+	if summaryModel == "" {
+		return "", fmt.Errorf("context-guard.summary-model is not set")
+	}
+	if transcript == "" {
+		return "", fmt.Errorf("no text to summarize")
+	}
+	payload := []byte(`{"messages":[{"role":"system"},{"role":"user"}]}`)
+	payload, _ = sjson.SetBytes(payload, "model", summaryModel)
+	payload, _ = sjson.SetBytes(payload, "max_tokens", summaryMaxTokens)
+	payload, _ = sjson.SetBytes(payload, "messages.0.content", summaryInstructions)
+	payload, _ = sjson.SetBytes(payload, "messages.1.content", transcript)
+	summaryCtx, cancel := context.WithCancel(context.WithValue(context.Background(), contextGuardSkipKey{}, true))
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	resp, errMsg := h.ExecuteWithAuthManager(summaryCtx, "openai", summaryModel, payload, "")
+	if errMsg != nil {
+		if errMsg.Error != nil {
+			return "", errMsg.Error
+		}
+		return "", fmt.Errorf("summary request failed with status %d", errMsg.StatusCode)
+	}
+	summary := strings.TrimSpace(gjson.GetBytes(resp, "choices.0.message.content").String())
+	if summary == "" {
+		return "", fmt.Errorf("summary model %s returned no text", summaryModel)
+	}
+	return summary, nil
+}
+
+func setContextHeader(ctx context.Context, value string) {
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		ginCtx.Header(ContextHeader, value)
+	}
+}
+
+// contextLengthError reports a request that does not fit the model's context window.
+type contextLengthError struct {
+	model    string
+	tokens   int
+	limit    int
+	strategy string
+}
+
+func contextLengthErrorMessage(model string, tokens, limit int, strategy string) *interfaces.ErrorMessage {
+	err := &contextLengthError{model: model, tokens: tokens, limit: limit, strategy: strategy}
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: err, Addon: headers}
+}
+
+func (e *contextLengthError) Error() string {
+	message := fmt.Sprintf("This request has about %d input tokens, which exceeds the %d-token input limit of model %s.", e.tokens, e.limit, e.model)
+	if e.strategy != "" {
+		message += fmt.Sprintf(" The %s context strategy could not shorten it enough.", e.strategy)
+	} else {
+		message += " Shorten the conversation or choose a model with a larger context window."
+	}
+	data, err := json.Marshal(ErrorResponse{Error: ErrorDetail{
+		Message: message,
+		Type:    "invalid_request_error",
+		Code:    "context_length_exceeded",
+	}})
+	if err != nil {
+		return message
+	}
+	return string(data)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// ServedModelHeader reports the model that actually answered the request, which
+// differs from the requested model when a configured fallback was used.
+const ServedModelHeader = "X-CLIProxy-Model"
+
+// setExecutionReportHeaders exposes how the request was served before the response is
+// written. The credential is only named when exposeAuth is set.
+func setExecutionReportHeaders(ctx context.Context, report *coreauth.ExecutionReport, exposeAuth bool) {
+	if report == nil {
+		return
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return
+	}
+	if report.Model != "" {
+		ginCtx.Header(ServedModelHeader, report.Model)
+	}
+	if report.CacheHit {
+		ginCtx.Header(CacheHeader, "hit")
+	}
+	if exposeAuth && report.AuthID != "" {
+		ginCtx.Header(RoutingProviderHeader, report.Provider)
+		ginCtx.Header(RoutingAuthIDHeader, report.AuthID)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
@@ -160,11 +162,23 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
-func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (_ []byte, errMsg *interfaces.ErrorMessage) {
+	start := time.Now()
+	ctx, report := coreauth.WithExecutionReport(ctx)
+	defer func() { observeRequest(handlerType, modelName, report, start, errMsg) }()
 	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	release, errMsg := h.enforceAPIKeyPolicy(ctx, modelName, false)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	defer release()
+	rawJSON, errMsg = h.guardContext(ctx, handlerType, normalizedModel, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -181,6 +195,12 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 	if cloned := cloneMetadata(metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
+	opts.Metadata = withAffinityKey(ctx, opts.Metadata, rawJSON)
+	opts.Metadata = h.withCachePreference(ctx, opts.Metadata)
+	opts.Metadata = h.withAllowedModels(ctx, opts.Metadata)
+	if opts.Metadata, errMsg = h.withRouting(ctx, opts.Metadata); errMsg != nil {
+		return nil, errMsg
+	}
 	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
 	if err != nil {
 		status := http.StatusInternalServerError
@@ -197,16 +217,25 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		}
 		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
 	}
+	setExecutionReportHeaders(ctx, report, h.isAdminKey(ctx))
 	return cloneBytes(resp.Payload), nil
 }
 
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
-func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (_ []byte, errMsg *interfaces.ErrorMessage) {
+	start := time.Now()
+	ctx, report := coreauth.WithExecutionReport(ctx)
+	defer func() { observeRequest(handlerType, modelName, report, start, errMsg) }()
 	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	release, errMsg := h.enforceAPIKeyPolicy(ctx, modelName, false)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	defer release()
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -223,6 +252,9 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	if cloned := cloneMetadata(metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
+	if opts.Metadata, errMsg = h.withRouting(ctx, opts.Metadata); errMsg != nil {
+		return nil, errMsg
+	}
 	resp, err := h.AuthManager.ExecuteCount(ctx, providers, req, opts)
 	if err != nil {
 		status := http.StatusInternalServerError
@@ -242,11 +274,96 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	return cloneBytes(resp.Payload), nil
 }
 
+// ExecuteEmbeddingWithAuthManager executes an embeddings request via the core auth manager.
+// rawJSON is an OpenAI embeddings request or a Gemini batchEmbedContents request, matching handlerType.
+func (h *BaseAPIHandler) ExecuteEmbeddingWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	return h.executeCapabilityWithAuthManager(ctx, handlerType, modelName, rawJSON, h.AuthManager.ExecuteEmbedding)
+}
+
+// ExecuteImageWithAuthManager executes an image generation or edit request via the core
+// auth manager. rawJSON is an OpenAI images request; edits carry their source images
+// inline as described by coreauth.ImageExecutor.
+func (h *BaseAPIHandler) ExecuteImageWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	return h.executeCapabilityWithAuthManager(ctx, handlerType, modelName, rawJSON, h.AuthManager.ExecuteImage)
+}
+
+// executeCapabilityWithAuthManager runs a non-streaming request through one of the
+// manager's optional capability entry points, such as embeddings or images.
+func (h *BaseAPIHandler) executeCapabilityWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, execute func(context.Context, []string, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error)) (_ []byte, errMsg *interfaces.ErrorMessage) {
+	start := time.Now()
+	ctx, report := coreauth.WithExecutionReport(ctx)
+	defer func() { observeRequest(handlerType, modelName, report, start, errMsg) }()
+	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	release, errMsg := h.enforceAPIKeyPolicy(ctx, modelName, false)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	defer release()
+	req := coreexecutor.Request{
+		Model:   normalizedModel,
+		Payload: cloneBytes(rawJSON),
+	}
+	if cloned := cloneMetadata(metadata); cloned != nil {
+		req.Metadata = cloned
+	}
+	opts := coreexecutor.Options{
+		Stream:          false,
+		OriginalRequest: cloneBytes(rawJSON),
+		SourceFormat:    sdktranslator.FromString(handlerType),
+	}
+	if cloned := cloneMetadata(metadata); cloned != nil {
+		opts.Metadata = cloned
+	}
+	if opts.Metadata, errMsg = h.withRouting(ctx, opts.Metadata); errMsg != nil {
+		return nil, errMsg
+	}
+	resp, err := execute(ctx, providers, req, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
+			if code := se.StatusCode(); code > 0 {
+				status = code
+			}
+		}
+		var addon http.Header
+		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
+			if hdr := he.Headers(); hdr != nil {
+				addon = hdr.Clone()
+			}
+		}
+		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+	}
+	return cloneBytes(resp.Payload), nil
+}
+
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	start := time.Now()
+	ctx, report := coreauth.WithExecutionReport(ctx)
 	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
+		observeRequest(handlerType, modelName, report, start, errMsg)
+		errChan := make(chan *interfaces.ErrorMessage, 1)
+		errChan <- errMsg
+		close(errChan)
+		return nil, errChan
+	}
+	release, errMsg := h.enforceAPIKeyPolicy(ctx, modelName, true)
+	if errMsg != nil {
+		observeRequest(handlerType, modelName, report, start, errMsg)
+		errChan := make(chan *interfaces.ErrorMessage, 1)
+		errChan <- errMsg
+		close(errChan)
+		return nil, errChan
+	}
+	rawJSON, errMsg = h.guardContext(ctx, handlerType, normalizedModel, rawJSON)
+	if errMsg != nil {
+		release()
+		observeRequest(handlerType, modelName, report, start, errMsg)
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
 		close(errChan)
@@ -268,8 +385,21 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 	if cloned := cloneMetadata(metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
+	opts.Metadata = withAffinityKey(ctx, opts.Metadata, rawJSON)
+	opts.Metadata = h.withHedgeDelay(ctx, opts.Metadata)
+	opts.Metadata = h.withCachePreference(ctx, opts.Metadata)
+	opts.Metadata = h.withAllowedModels(ctx, opts.Metadata)
+	if opts.Metadata, errMsg = h.withRouting(ctx, opts.Metadata); errMsg != nil {
+		release()
+		observeRequest(handlerType, modelName, report, start, errMsg)
+		errChan := make(chan *interfaces.ErrorMessage, 1)
+		errChan <- errMsg
+		close(errChan)
+		return nil, errChan
+	}
 	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
 	if err != nil {
+		release()
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -283,15 +413,21 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				addon = hdr.Clone()
 			}
 		}
-		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		errMsg = &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		observeRequest(handlerType, modelName, report, start, errMsg)
+		errChan <- errMsg
 		close(errChan)
 		return nil, errChan
 	}
+	setExecutionReportHeaders(ctx, report, h.isAdminKey(ctx))
 	dataChan := make(chan []byte)
 	errChan := make(chan *interfaces.ErrorMessage, 1)
 	go func() {
 		defer close(dataChan)
 		defer close(errChan)
+		defer release()
+		var streamErr *interfaces.ErrorMessage
+		defer func() { observeRequest(handlerType, modelName, report, start, streamErr) }()
 		for chunk := range chunks {
 			if chunk.Err != nil {
 				status := http.StatusInternalServerError
@@ -306,17 +442,38 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 						addon = hdr.Clone()
 					}
 				}
-				errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: chunk.Err, Addon: addon}
+				streamErr = &interfaces.ErrorMessage{StatusCode: status, Error: chunk.Err, Addon: addon}
+				errChan <- streamErr
 				return
 			}
 			if len(chunk.Payload) > 0 {
-				dataChan <- cloneBytes(chunk.Payload)
+				select {
+				case dataChan <- cloneBytes(chunk.Payload):
+				case <-ctx.Done():
+					// The client went away; drain the upstream so the stream slot is released.
+					for range chunks {
+					}
+					return
+				}
 			}
 		}
 	}()
 	return dataChan, errChan
 }
 
+// observeRequest records client request metrics once a request has finished. The
+// provider comes from report and is empty when no credential was selected.
+func observeRequest(handlerType, modelName string, report *coreauth.ExecutionReport, start time.Time, errMsg *interfaces.ErrorMessage) {
+	status := http.StatusOK
+	if errMsg != nil {
+		status = http.StatusInternalServerError
+		if errMsg.StatusCode > 0 {
+			status = errMsg.StatusCode
+		}
+	}
+	metrics.ObserveRequest(handlerType, report.Provider, modelName, status, time.Since(start))
+}
+
 func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string, normalizedModel string, metadata map[string]any, err *interfaces.ErrorMessage) {
 	// Resolve "auto" model to an actual available model first
 	resolvedModelName := util.ResolveAutoModel(modelName)
@@ -440,3 +597,31 @@ func (h *BaseAPIHandler) LoggingAPIResponseError(ctx context.Context, err *inter
 // APIHandlerCancelFunc is a function type for canceling an API handler's context.
 // It can optionally accept parameters, which are used for logging the response.
 type APIHandlerCancelFunc func(params ...interface{})
+
+// ClientAPIKey returns the client API key the access middleware authenticated, if any.
+func ClientAPIKey(c *gin.Context) string {
+	if v, exists := c.Get("apiKey"); exists {
+		if key, ok := v.(string); ok {
+			return key
+		}
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+// RequestBaseURL reconstructs the externally visible scheme and host of the request,
+// honouring reverse proxy headers.
+func RequestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	return scheme + "://" + host
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// HedgeHeader opts a streaming request into (or out of) hedging. The value is a
+// delay such as "800ms" or a number of milliseconds; "0" or "off" disables hedging
+// for a model that is hedged by default. Only keys with the admin role may opt in,
+// since every hedge can double upstream usage.
+const HedgeHeader = "X-CLIProxy-Hedge"
+
+// withHedgeDelay copies the client's hedge preference into the execution metadata.
+// A delay sent by a key without the admin role is ignored.
+func (h *BaseAPIHandler) withHedgeDelay(ctx context.Context, metadata map[string]any) map[string]any {
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return metadata
+	}
+	raw := strings.TrimSpace(ginCtx.GetHeader(HedgeHeader))
+	if raw == "" {
+		return metadata
+	}
+	var delay time.Duration
+	switch strings.ToLower(raw) {
+	case "off", "false", "0":
+	default:
+		if ms, err := strconv.Atoi(raw); err == nil {
+			delay = time.Duration(ms) * time.Millisecond
+		} else if parsed, errParse := time.ParseDuration(raw); errParse == nil {
+			delay = parsed
+		} else {
+			return metadata
+		}
+		if delay > 0 && !h.isAdminKey(ctx) {
+			return metadata
+		}
+	}
+	if metadata == nil {
+		metadata = make(map[string]any, 1)
+	}
+	metadata[coreauth.HedgeMetadataKey] = delay
+	return metadata
+}
@@ -0,0 +1,196 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+// CreateBatch handles POST /v1/batches. The requests of the input file are executed
+// in the background; results are published as output and error files once done.
+func (h *OpenAIAPIHandler) CreateBatch(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	endpoint := gjson.GetBytes(rawJSON, "endpoint").String()
+	if !handlers.BatchEndpointSupported(endpoint) {
+		writeBatchError(c, http.StatusBadRequest, fmt.Sprintf("Invalid endpoint %q: supported endpoints are /v1/chat/completions, /v1/responses, /v1/embeddings and /v1/messages.", endpoint))
+		return
+	}
+	window := gjson.GetBytes(rawJSON, "completion_window").String()
+	if window == "" {
+		window = "24h"
+	}
+	if window != "24h" {
+		writeBatchError(c, http.StatusBadRequest, "Invalid completion_window: only \"24h\" is supported.")
+		return
+	}
+	owner := handlers.ClientAPIKey(c)
+	inputFileID := gjson.GetBytes(rawJSON, "input_file_id").String()
+	_, data, err := batch.DefaultManager().Files().Content(inputFileID, h.BatchViewer(c))
+	if err != nil {
+		writeFileLookupError(c, inputFileID, err)
+		return
+	}
+	requests, err := parseBatchInput(data, endpoint)
+	if err != nil {
+		writeBatchError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var metadata map[string]string
+	gjson.GetBytes(rawJSON, "metadata").ForEach(func(key, value gjson.Result) bool {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[key.String()] = value.String()
+		return true
+	})
+	job, err := batch.DefaultManager().Create(batch.Job{
+		Kind:             batch.KindOpenAI,
+		OwnerKey:         owner,
+		Endpoint:         endpoint,
+		InputFileID:      inputFileID,
+		CompletionWindow: window,
+		Metadata:         metadata,
+	}, requests)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{Message: err.Error(), Type: "server_error"},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, batch.OpenAIBatch(job))
+}
+
+// ListBatches handles GET /v1/batches, newest first.
+func (h *OpenAIAPIHandler) ListBatches(c *gin.Context) {
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 100 {
+			writeBatchError(c, http.StatusBadRequest, "Invalid limit: must be between 1 and 100.")
+			return
+		}
+		limit = parsed
+	}
+	jobs := batch.DefaultManager().List(batch.KindOpenAI, h.BatchViewer(c))
+	start := 0
+	if after := c.Query("after"); after != "" {
+		for i, job := range jobs {
+			if job.ID == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := min(start+limit, len(jobs))
+	data := make([]map[string]any, 0, end-start)
+	for _, job := range jobs[start:end] {
+		data = append(data, batch.OpenAIBatch(job))
+	}
+	resp := gin.H{"object": "list", "data": data, "first_id": nil, "last_id": nil, "has_more": end < len(jobs)}
+	if end > start {
+		resp["first_id"] = jobs[start].ID
+		resp["last_id"] = jobs[end-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetBatch handles GET /v1/batches/:id.
+func (h *OpenAIAPIHandler) GetBatch(c *gin.Context) {
+	job, err := batch.DefaultManager().Get(c.Param("id"), h.BatchViewer(c))
+	if err != nil || job.Kind != batch.KindOpenAI {
+		writeBatchLookupError(c, c.Param("id"), err)
+		return
+	}
+	c.JSON(http.StatusOK, batch.OpenAIBatch(job))
+}
+
+// CancelBatch handles POST /v1/batches/:id/cancel.
+func (h *OpenAIAPIHandler) CancelBatch(c *gin.Context) {
+	id := c.Param("id")
+	job, err := batch.DefaultManager().Get(id, h.BatchViewer(c))
+	if err != nil || job.Kind != batch.KindOpenAI {
+		writeBatchLookupError(c, id, err)
+		return
+	}
+	if job, err = batch.DefaultManager().Cancel(id, h.BatchViewer(c)); err != nil {
+		writeBatchLookupError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusOK, batch.OpenAIBatch(job))
+}
+
+// parseBatchInput reads the JSON Lines input of an OpenAI batch. Every line must
+// target endpoint with POST and carry a unique custom_id.
+func parseBatchInput(data []byte, endpoint string) ([]batch.Request, error) {
+	var requests []batch.Request
+	seen := make(map[string]struct{})
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for lineNo := 1; ; lineNo++ {
+		line, errRead := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if !gjson.ValidBytes(trimmed) {
+				return nil, fmt.Errorf("line %d: invalid JSON", lineNo)
+			}
+			item := gjson.ParseBytes(trimmed)
+			customID := item.Get("custom_id").String()
+			if customID == "" {
+				return nil, fmt.Errorf("line %d: custom_id is required", lineNo)
+			}
+			if _, dup := seen[customID]; dup {
+				return nil, fmt.Errorf("line %d: duplicate custom_id %q", lineNo, customID)
+			}
+			seen[customID] = struct{}{}
+			if method := item.Get("method").String(); method != http.MethodPost {
+				return nil, fmt.Errorf("line %d: method must be POST", lineNo)
+			}
+			if url := item.Get("url").String(); url != endpoint {
+				return nil, fmt.Errorf("line %d: url %q does not match the batch endpoint %s", lineNo, url, endpoint)
+			}
+			body := item.Get("body")
+			if !body.IsObject() {
+				return nil, fmt.Errorf("line %d: body must be an object", lineNo)
+			}
+			requests = append(requests, batch.Request{CustomID: customID, Body: []byte(body.Raw)})
+			if len(requests) > batch.MaxRequests {
+				return nil, fmt.Errorf("input file has more than %d requests", batch.MaxRequests)
+			}
+		}
+		if errRead != nil {
+			break
+		}
+	}
+	if len(requests) == 0 {
+		return nil, errors.New("input file contains no requests")
+	}
+	return requests, nil
+}
+
+func writeBatchLookupError(c *gin.Context, id string, err error) {
+	if err == nil || errors.Is(err, batch.ErrNotFound) {
+		c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{Message: fmt.Sprintf("No batch found with id '%s'.", id), Type: "invalid_request_error"},
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{Message: err.Error(), Type: "server_error"},
+	})
+}
+
+func writeBatchError(c *gin.Context, status int, message string) {
+	c.JSON(status, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{Message: message, Type: "invalid_request_error"},
+	})
+}
@@ -0,0 +1,121 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+)
+
+// maxFileUploadBytes bounds uploaded batch input files, matching OpenAI's 200 MB limit.
+const maxFileUploadBytes = 200 << 20
+
+// UploadFile handles POST /v1/files. Only purpose=batch is supported; the file is
+// kept for use as the input of /v1/batches.
+func (h *OpenAIAPIHandler) UploadFile(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileUploadBytes)
+	purpose := c.PostForm("purpose")
+	if purpose != batch.PurposeBatch {
+		writeFileError(c, http.StatusBadRequest, "Invalid purpose: only \"batch\" is supported.")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		writeFileError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		writeFileError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		writeFileError(c, http.StatusBadRequest, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	file, err := batch.DefaultManager().Files().Create(h.BatchViewer(c).Owner(), header.Filename, purpose, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{Message: err.Error(), Type: "server_error"},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, fileObject(file))
+}
+
+// ListFiles handles GET /v1/files, optionally filtered by purpose.
+func (h *OpenAIAPIHandler) ListFiles(c *gin.Context) {
+	files := batch.DefaultManager().Files().List(h.BatchViewer(c), c.Query("purpose"))
+	data := make([]gin.H, 0, len(files))
+	for _, file := range files {
+		data = append(data, fileObject(file))
+	}
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": data, "has_more": false})
+}
+
+// GetFile handles GET /v1/files/:id.
+func (h *OpenAIAPIHandler) GetFile(c *gin.Context) {
+	file, err := batch.DefaultManager().Files().Get(c.Param("id"), h.BatchViewer(c))
+	if err != nil {
+		writeFileLookupError(c, c.Param("id"), err)
+		return
+	}
+	c.JSON(http.StatusOK, fileObject(file))
+}
+
+// FileContent handles GET /v1/files/:id/content.
+func (h *OpenAIAPIHandler) FileContent(c *gin.Context) {
+	_, data, err := batch.DefaultManager().Files().Content(c.Param("id"), h.BatchViewer(c))
+	if err != nil {
+		writeFileLookupError(c, c.Param("id"), err)
+		return
+	}
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// DeleteFile handles DELETE /v1/files/:id.
+func (h *OpenAIAPIHandler) DeleteFile(c *gin.Context) {
+	id := c.Param("id")
+	if err := batch.DefaultManager().Files().Delete(id, h.BatchViewer(c)); err != nil {
+		writeFileLookupError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "file", "deleted": true})
+}
+
+func fileObject(file batch.File) gin.H {
+	return gin.H{
+		"id":         file.ID,
+		"object":     "file",
+		"bytes":      file.Bytes,
+		"created_at": file.CreatedAt,
+		"filename":   file.Filename,
+		"purpose":    file.Purpose,
+		"status":     "processed",
+		"expires_at": nil,
+	}
+}
+
+func writeFileLookupError(c *gin.Context, id string, err error) {
+	if errors.Is(err, batch.ErrNotFound) {
+		c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{Message: fmt.Sprintf("No such File object: %s", id), Type: "invalid_request_error"},
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{Message: err.Error(), Type: "server_error"},
+	})
+}
+
+func writeFileError(c *gin.Context, status int, message string) {
+	c.JSON(status, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{Message: message, Type: "invalid_request_error"},
+	})
+}
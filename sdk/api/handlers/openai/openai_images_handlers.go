@@ -0,0 +1,233 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/imagegen"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxImageUploadBytes bounds the multipart form kept in memory for image edits.
+const maxImageUploadBytes = 32 << 20
+
+// ImageGenerations handles the /v1/images/generations endpoint.
+// Gemini image models and OpenAI-compatible providers can serve the request.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIAPIHandler) ImageGenerations(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	// If data retrieval fails, return a 400 Bad Request error.
+	if err != nil {
+		writeImageRequestError(c, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	h.handleImageRequest(c, rawJSON)
+}
+
+// ImageEdits handles the /v1/images/edits endpoint. Multipart uploads are converted
+// into a JSON request carrying the images inline; JSON bodies may reference images
+// as base64 data URLs.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIAPIHandler) ImageEdits(c *gin.Context) {
+	var rawJSON []byte
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		rawJSON, err = imageEditFromMultipart(c)
+	} else {
+		rawJSON, err = c.GetRawData()
+		if err == nil {
+			rawJSON, err = imageEditFromJSON(rawJSON)
+		}
+	}
+	if err != nil {
+		writeImageRequestError(c, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if !imagegen.IsEdit(rawJSON) {
+		writeImageRequestError(c, "Invalid request: image is required")
+		return
+	}
+	h.handleImageRequest(c, rawJSON)
+}
+
+// ImageFile handles GET /v1/images/files/:id, serving an image generated with
+// response_format=url. The route is public; ids are random and expire.
+func (h *OpenAIAPIHandler) ImageFile(c *gin.Context) {
+	image, ok := imagegen.DefaultStore().Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{Message: "Image not found or expired.", Type: "invalid_request_error"},
+		})
+		return
+	}
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(imagegen.DefaultStore().TTL().Seconds())))
+	c.Data(http.StatusOK, image.MimeType, image.Data)
+}
+
+func (h *OpenAIAPIHandler) handleImageRequest(c *gin.Context, rawJSON []byte) {
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	if modelName == "" {
+		writeImageRequestError(c, "Invalid request: model is required")
+		return
+	}
+	if strings.TrimSpace(gjson.GetBytes(rawJSON, "prompt").String()) == "" {
+		writeImageRequestError(c, "Invalid request: prompt is required")
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteImageWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON)
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	if gjson.GetBytes(rawJSON, "response_format").String() == "url" {
+		resp = storeImagesAsURLs(c, resp)
+	}
+	_, _ = c.Writer.Write(resp)
+	cliCancel()
+}
+
+// storeImagesAsURLs replaces b64_json entries with URLs served by ImageFile.
+// Entries that already carry an upstream URL are left untouched.
+func storeImagesAsURLs(c *gin.Context, resp []byte) []byte {
+	base := handlers.RequestBaseURL(c)
+	for i, item := range gjson.GetBytes(resp, "data").Array() {
+		encoded := item.Get("b64_json").String()
+		if encoded == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			log.Warnf("images: decode generated image: %v", err)
+			continue
+		}
+		id, err := imagegen.DefaultStore().Put(data, http.DetectContentType(data))
+		if err != nil {
+			log.Warnf("images: store generated image: %v", err)
+			continue
+		}
+		resp, _ = sjson.DeleteBytes(resp, fmt.Sprintf("data.%d.b64_json", i))
+		resp, _ = sjson.SetBytes(resp, fmt.Sprintf("data.%d.url", i), base+"/v1/images/files/"+id)
+	}
+	return resp
+}
+
+// imageEditFromMultipart converts an images/edits multipart upload into the JSON
+// request understood by image executors.
+func imageEditFromMultipart(c *gin.Context) ([]byte, error) {
+	if err := c.Request.ParseMultipartForm(maxImageUploadBytes); err != nil {
+		return nil, err
+	}
+	form := c.Request.MultipartForm
+	out := []byte(`{}`)
+	for key, values := range form.Value {
+		if len(values) == 0 {
+			continue
+		}
+		if key == "n" {
+			n, err := strconv.Atoi(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("n must be an integer")
+			}
+			out, _ = sjson.SetBytes(out, "n", n)
+			continue
+		}
+		out, _ = sjson.SetBytes(out, key, values[0])
+	}
+	for _, field := range []string{"image", "image[]"} {
+		for _, file := range form.File[field] {
+			image, err := inlineImageFromFile(file)
+			if err != nil {
+				return nil, err
+			}
+			out, _ = sjson.SetRawBytes(out, "images.-1", image)
+		}
+	}
+	if files := form.File["mask"]; len(files) > 0 {
+		mask, err := inlineImageFromFile(files[0])
+		if err != nil {
+			return nil, err
+		}
+		out, _ = sjson.SetRawBytes(out, "mask", mask)
+	}
+	return out, nil
+}
+
+// imageEditFromJSON normalises a JSON images/edits request whose images are given as
+// {"image_url": "data:..."} objects into inline {"mime_type","data"} images.
+func imageEditFromJSON(rawJSON []byte) ([]byte, error) {
+	out := rawJSON
+	for i, image := range gjson.GetBytes(rawJSON, "images").Array() {
+		if image.Get("data").Exists() {
+			continue
+		}
+		inline, err := inlineImageFromDataURL(image.Get("image_url").String())
+		if err != nil {
+			return nil, err
+		}
+		out, _ = sjson.SetRawBytes(out, fmt.Sprintf("images.%d", i), inline)
+	}
+	if mask := gjson.GetBytes(rawJSON, "mask"); mask.Exists() && !mask.Get("data").Exists() {
+		inline, err := inlineImageFromDataURL(mask.Get("image_url").String())
+		if err != nil {
+			return nil, err
+		}
+		out, _ = sjson.SetRawBytes(out, "mask", inline)
+	}
+	return out, nil
+}
+
+func inlineImageFromFile(file *multipart.FileHeader) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := file.Header.Get("Content-Type")
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	return inlineImage(mimeType, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func inlineImageFromDataURL(url string) ([]byte, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !strings.HasPrefix(url, "data:") || !ok || !strings.HasSuffix(header, ";base64") {
+		return nil, fmt.Errorf("images must be base64 data URLs")
+	}
+	return inlineImage(strings.TrimSuffix(header, ";base64"), data), nil
+}
+
+func inlineImage(mimeType, data string) []byte {
+	out := []byte(`{"mime_type":"","data":""}`)
+	out, _ = sjson.SetBytes(out, "mime_type", mimeType)
+	out, _ = sjson.SetBytes(out, "data", data)
+	return out
+}
+
+func writeImageRequestError(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{Message: message, Type: "invalid_request_error"},
+	})
+}
@@ -0,0 +1,215 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/responses"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// GetResponse handles GET /v1/responses/:id by returning a stored response.
+func (h *OpenAIResponsesAPIHandler) GetResponse(c *gin.Context) {
+	id := c.Param("id")
+	record, err := responses.DefaultStore().Get(c.Request.Context(), id, handlers.ClientAPIKey(c))
+	if err != nil {
+		writeResponseLookupError(c, id, "", err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", record.Response)
+}
+
+// DeleteResponse handles DELETE /v1/responses/:id.
+func (h *OpenAIResponsesAPIHandler) DeleteResponse(c *gin.Context) {
+	id := c.Param("id")
+	if err := responses.DefaultStore().Delete(c.Request.Context(), id, handlers.ClientAPIKey(c)); err != nil {
+		writeResponseLookupError(c, id, "", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "response.deleted", "deleted": true})
+}
+
+// prepareStatefulRequest expands previous_response_id into the request input and
+// returns the recorder that stores the new turn. It writes the error response and
+// returns ok=false when the previous response cannot be found. While the store is
+// disabled the request passes through unchanged.
+func prepareStatefulRequest(c *gin.Context, rawJSON []byte) (_ []byte, recorder *responseRecorder, ok bool) {
+	store := responses.DefaultStore()
+	if !store.Enabled() {
+		return rawJSON, nil, true
+	}
+	owner := handlers.ClientAPIKey(c)
+	turnInput := normalizeResponseInput(gjson.GetBytes(rawJSON, "input"))
+	previousID := gjson.GetBytes(rawJSON, "previous_response_id").String()
+
+	if previousID != "" {
+		history, err := store.History(c.Request.Context(), previousID, owner)
+		if err != nil {
+			writeResponseLookupError(c, previousID, "previous_response_id", err)
+			return nil, nil, false
+		}
+		input := make([]json.RawMessage, 0, len(history)+1)
+		for _, item := range history {
+			if replayed, keep := replayableItem(item); keep {
+				input = append(input, replayed)
+			}
+		}
+		var current []json.RawMessage
+		_ = json.Unmarshal(turnInput, &current)
+		input = append(input, current...)
+		if merged, errMarshal := json.Marshal(input); errMarshal == nil {
+			rawJSON, _ = sjson.SetRawBytes(rawJSON, "input", merged)
+		}
+		rawJSON, _ = sjson.DeleteBytes(rawJSON, "previous_response_id")
+	}
+
+	if gjson.GetBytes(rawJSON, "store").Type != gjson.False {
+		recorder = &responseRecorder{
+			store:      store,
+			owner:      owner,
+			previousID: previousID,
+			model:      gjson.GetBytes(rawJSON, "model").String(),
+			input:      turnInput,
+		}
+	}
+	return rawJSON, recorder, true
+}
+
+// normalizeResponseInput converts the request input into an array of input items.
+func normalizeResponseInput(input gjson.Result) json.RawMessage {
+	switch {
+	case input.IsArray():
+		return json.RawMessage(input.Raw)
+	case input.Type == gjson.String:
+		item := `{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`
+		item, _ = sjson.Set(item, "content.0.text", input.String())
+		return json.RawMessage("[" + item + "]")
+	default:
+		return json.RawMessage("[]")
+	}
+}
+
+// replayableItem prepares a stored item for resending upstream. Server-side item ids
+// are dropped because upstreams do not persist them, and reasoning items are only
+// kept when they carry encrypted content that can be replayed.
+func replayableItem(item json.RawMessage) (json.RawMessage, bool) {
+	parsed := gjson.ParseBytes(item)
+	if parsed.Get("type").String() == "reasoning" && parsed.Get("encrypted_content").String() == "" {
+		return nil, false
+	}
+	if !parsed.Get("id").Exists() {
+		return item, true
+	}
+	stripped, err := sjson.DeleteBytes(item, "id")
+	if err != nil {
+		return item, true
+	}
+	return stripped, true
+}
+
+func writeResponseLookupError(c *gin.Context, id, param string, err error) {
+	if !errors.Is(err, responses.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{Message: err.Error(), Type: "server_error"},
+		})
+		return
+	}
+	message := fmt.Sprintf("Response with id '%s' not found.", id)
+	if param == "previous_response_id" {
+		message = fmt.Sprintf("Previous response with id '%s' not found.", id)
+	}
+	c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{Message: message, Type: "invalid_request_error"},
+	})
+}
+
+// responseRecorder captures a completed response and stores it as a new turn.
+type responseRecorder struct {
+	store      *responses.Store
+	owner      string
+	previousID string
+	model      string
+	input      json.RawMessage
+
+	items     []json.RawMessage
+	completed []byte
+}
+
+// observeChunk inspects a Responses SSE chunk for output items and the final response.
+func (r *responseRecorder) observeChunk(chunk []byte) {
+	if r == nil {
+		return
+	}
+	for _, line := range bytes.Split(chunk, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(line[5:])
+		switch gjson.GetBytes(payload, "type").String() {
+		case "response.output_item.done":
+			if item := gjson.GetBytes(payload, "item"); item.Exists() {
+				r.items = append(r.items, json.RawMessage(item.Raw))
+			}
+		case "response.completed":
+			if resp := gjson.GetBytes(payload, "response"); resp.Exists() {
+				r.completed = []byte(resp.Raw)
+			}
+		}
+	}
+}
+
+// finishStream stores the response captured from the stream, if any.
+func (r *responseRecorder) finishStream(ctx context.Context) {
+	if r == nil || len(r.completed) == 0 {
+		return
+	}
+	r.record(ctx, r.completed)
+}
+
+// record stores a complete response object and returns it with previous_response_id restored.
+func (r *responseRecorder) record(ctx context.Context, resp []byte) []byte {
+	if r == nil {
+		return resp
+	}
+	if r.previousID != "" {
+		if patched, err := sjson.SetBytes(resp, "previous_response_id", r.previousID); err == nil {
+			resp = patched
+		}
+	}
+	id := gjson.GetBytes(resp, "id").String()
+	if id == "" {
+		return resp
+	}
+	output := json.RawMessage(gjson.GetBytes(resp, "output").Raw)
+	if len(gjson.GetBytes(resp, "output").Array()) == 0 && len(r.items) > 0 {
+		if collected, err := json.Marshal(r.items); err == nil {
+			output = collected
+		}
+	}
+	if len(output) == 0 {
+		output = json.RawMessage("[]")
+	}
+	err := r.store.Put(ctx, responses.Record{
+		ID:         id,
+		PreviousID: r.previousID,
+		Owner:      util.HashAPIKey(r.owner),
+		Model:      r.model,
+		Input:      r.input,
+		Output:     output,
+		Response:   append(json.RawMessage(nil), resp...),
+	})
+	if err != nil {
+		log.Warnf("failed to store response %s: %v", id, err)
+	}
+	return resp
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// CacheHeader carries the client's response cache preference: "off" (or "no-store")
+// bypasses the cache and "on" caches a request even when it is not deterministic.
+// Only keys with the admin role may send "on". Responses replayed from the cache
+// carry "X-CLIProxy-Cache: hit".
+const CacheHeader = "X-CLIProxy-Cache"
+
+// withCachePreference scopes cached responses to the caller's API key and copies
+// the client's cache preference into the execution metadata.
+func (h *BaseAPIHandler) withCachePreference(ctx context.Context, metadata map[string]any) map[string]any {
+	if key := clientAPIKeyFromContext(ctx); key != "" {
+		if metadata == nil {
+			metadata = make(map[string]any, 2)
+		}
+		metadata[coreauth.ResponseCacheScopeMetadataKey] = key
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return metadata
+	}
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(ginCtx.GetHeader(CacheHeader))) {
+	case "on", "true", "1":
+		if !h.isAdminKey(ctx) {
+			return metadata
+		}
+		enabled = true
+	case "off", "false", "0", "no-store", "bypass":
+	default:
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]any, 1)
+	}
+	metadata[coreauth.ResponseCacheMetadataKey] = enabled
+	return metadata
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// Routing headers let clients holding an admin API key steer a request to specific
+// credentials, for example to reproduce a problem with one account. Provider and
+// Auth-ID are also set on responses to admin clients to name the credential used.
+const (
+	// RoutingProviderHeader restricts the request to one provider, e.g. "gemini-cli".
+	RoutingProviderHeader = "X-CLIProxy-Provider"
+	// RoutingAuthIDHeader pins the request to one credential by auth ID.
+	RoutingAuthIDHeader = "X-CLIProxy-Auth-ID"
+	// RoutingExcludeAuthHeader lists auth IDs, comma separated, that must not be used.
+	RoutingExcludeAuthHeader = "X-CLIProxy-Exclude-Auth"
+	// RoutingNoRetryHeader set to "true" or "1" makes a single upstream attempt.
+	RoutingNoRetryHeader = "X-CLIProxy-No-Retry"
+)
+
+// withRouting copies the client's routing headers into the execution metadata. It
+// rejects the request when routing headers come from a key without the admin role.
+func (h *BaseAPIHandler) withRouting(ctx context.Context, metadata map[string]any) (map[string]any, *interfaces.ErrorMessage) {
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return metadata, nil
+	}
+	// The context guard's summary request serves the client's request indirectly.
+	if skip, _ := ctx.Value(contextGuardSkipKey{}).(bool); skip {
+		return metadata, nil
+	}
+	routing := &coreauth.Routing{
+		Provider: strings.ToLower(strings.TrimSpace(ginCtx.GetHeader(RoutingProviderHeader))),
+		AuthID:   strings.TrimSpace(ginCtx.GetHeader(RoutingAuthIDHeader)),
+	}
+	for _, value := range ginCtx.Request.Header.Values(RoutingExcludeAuthHeader) {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				routing.ExcludeAuths = append(routing.ExcludeAuths, id)
+			}
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(ginCtx.GetHeader(RoutingNoRetryHeader))) {
+	case "1", "true", "yes", "on":
+		routing.NoRetry = true
+	}
+	if routing.Provider == "" && routing.AuthID == "" && len(routing.ExcludeAuths) == 0 && !routing.NoRetry {
+		return metadata, nil
+	}
+	if !h.isAdminKey(ctx) {
+		errRouting := &apiKeyLimitError{
+			code:    "routing_not_allowed",
+			message: "routing headers require an API key with the admin role",
+			status:  http.StatusForbidden,
+		}
+		return metadata, &interfaces.ErrorMessage{StatusCode: errRouting.StatusCode(), Error: errRouting, Addon: errRouting.Headers()}
+	}
+	if metadata == nil {
+		metadata = make(map[string]any, 1)
+	}
+	metadata[coreauth.RoutingMetadataKey] = routing
+	return metadata, nil
+}
+
+// isAdminKey reports whether the caller's API key policy grants the admin role.
+func (h *BaseAPIHandler) isAdminKey(ctx context.Context) bool {
+	return h.isAdminAPIKey(clientAPIKeyFromContext(ctx))
+}
+
+// isAdminAPIKey reports whether the policy of key grants the admin role.
+func (h *BaseAPIHandler) isAdminAPIKey(key string) bool {
+	policy := h.Cfg.APIKeyPolicy(key)
+	return policy != nil && strings.EqualFold(strings.TrimSpace(policy.Role), config.APIKeyRoleAdmin)
+}
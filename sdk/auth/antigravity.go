@@ -291,3 +291,8 @@ func sanitizeAntigravityFileName(email string) string {
 	replacer := strings.NewReplacer("@", "_", ".", "_")
 	return fmt.Sprintf("antigravity-%s.json", replacer.Replace(email))
 }
+
+// ValidateCredential implements CredentialValidator.
+func (AntigravityAuthenticator) ValidateCredential(metadata map[string]any) error {
+	return requireMetadata("antigravity", metadata, "refresh_token", "access_token")
+}
@@ -57,21 +57,6 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 		return nil, fmt.Errorf("claude state generation failed: %w", err)
 	}
 
-	oauthServer := claude.NewOAuthServer(a.CallbackPort)
-	if err = oauthServer.Start(); err != nil {
-		if strings.Contains(err.Error(), "already in use") {
-			return nil, claude.NewAuthenticationError(claude.ErrPortInUse, err)
-		}
-		return nil, claude.NewAuthenticationError(claude.ErrServerStartFailed, err)
-	}
-	defer func() {
-		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if stopErr := oauthServer.Stop(stopCtx); stopErr != nil {
-			log.Warnf("claude oauth server stop error: %v", stopErr)
-		}
-	}()
-
 	authSvc := claude.NewClaudeAuth(cfg)
 
 	authURL, returnedState, err := authSvc.GenerateAuthURL(state, pkceCodes)
@@ -80,29 +65,14 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 	}
 	state = returnedState
 
-	if !opts.NoBrowser {
-		fmt.Println("Opening browser for Claude authentication")
-		if !browser.IsAvailable() {
-			log.Warn("No browser available; please open the URL manually")
-			util.PrintSSHTunnelInstructions(a.CallbackPort)
-			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
-		} else if err = browser.OpenURL(authURL); err != nil {
-			log.Warnf("Failed to open browser automatically: %v", err)
-			util.PrintSSHTunnelInstructions(a.CallbackPort)
-			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
-		}
-	} else {
-		util.PrintSSHTunnelInstructions(a.CallbackPort)
-		fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
-	}
-
-	fmt.Println("Waiting for Claude authentication callback...")
-
-	result, err := oauthServer.WaitForCallback(5 * time.Minute)
-	if err != nil {
-		if strings.Contains(err.Error(), "timeout") {
-			return nil, claude.NewAuthenticationError(claude.ErrCallbackTimeout, err)
+	var result *claude.OAuthResult
+	if opts.Headless {
+		pasted, errPrompt := promptCallbackURL(opts, "Claude", authURL)
+		if errPrompt != nil {
+			return nil, errPrompt
 		}
+		result = &claude.OAuthResult{Code: pasted.Code, State: pasted.State, Error: pasted.Error}
+	} else if result, err = a.waitForCallback(authURL, opts); err != nil {
 		return nil, err
 	}
 
@@ -110,7 +80,8 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 		return nil, claude.NewOAuthError(result.Error, "", http.StatusBadRequest)
 	}
 
-	if result.State != state {
+	// A code pasted without its redirect URL carries no state to compare.
+	if result.State != state && (!opts.Headless || result.State != "") {
 		return nil, claude.NewAuthenticationError(claude.ErrInvalidState, fmt.Errorf("state mismatch"))
 	}
 
@@ -145,3 +116,57 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 		Metadata: metadata,
 	}, nil
 }
+
+// waitForCallback serves the local OAuth callback, sends the user to authURL and
+// waits for the provider to redirect back.
+func (a *ClaudeAuthenticator) waitForCallback(authURL string, opts *LoginOptions) (*claude.OAuthResult, error) {
+	oauthServer := claude.NewOAuthServer(a.CallbackPort)
+	if err := oauthServer.Start(); err != nil {
+		if strings.Contains(err.Error(), "already in use") {
+			return nil, claude.NewAuthenticationError(claude.ErrPortInUse, err)
+		}
+		return nil, claude.NewAuthenticationError(claude.ErrServerStartFailed, err)
+	}
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if stopErr := oauthServer.Stop(stopCtx); stopErr != nil {
+			log.Warnf("claude oauth server stop error: %v", stopErr)
+		}
+	}()
+
+	if !opts.NoBrowser {
+		fmt.Println("Opening browser for Claude authentication")
+		if !browser.IsAvailable() {
+			log.Warn("No browser available; please open the URL manually")
+			util.PrintSSHTunnelInstructions(a.CallbackPort)
+			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
+		} else if err := browser.OpenURL(authURL); err != nil {
+			log.Warnf("Failed to open browser automatically: %v", err)
+			util.PrintSSHTunnelInstructions(a.CallbackPort)
+			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
+		}
+	} else {
+		util.PrintSSHTunnelInstructions(a.CallbackPort)
+		fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
+	}
+
+	fmt.Println("Waiting for Claude authentication callback...")
+
+	result, err := oauthServer.WaitForCallback(5 * time.Minute)
+	if err != nil {
+		if strings.Contains(err.Error(), "timeout") {
+			return nil, claude.NewAuthenticationError(claude.ErrCallbackTimeout, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// ValidateCredential implements CredentialValidator.
+func (a *ClaudeAuthenticator) ValidateCredential(metadata map[string]any) error {
+	if err := requireMetadata("claude", metadata, "refresh_token", "access_token"); err != nil {
+		return err
+	}
+	return requireMetadata("claude", metadata, "email")
+}
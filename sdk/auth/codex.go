@@ -57,21 +57,6 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		return nil, fmt.Errorf("codex state generation failed: %w", err)
 	}
 
-	oauthServer := codex.NewOAuthServer(a.CallbackPort)
-	if err = oauthServer.Start(); err != nil {
-		if strings.Contains(err.Error(), "already in use") {
-			return nil, codex.NewAuthenticationError(codex.ErrPortInUse, err)
-		}
-		return nil, codex.NewAuthenticationError(codex.ErrServerStartFailed, err)
-	}
-	defer func() {
-		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if stopErr := oauthServer.Stop(stopCtx); stopErr != nil {
-			log.Warnf("codex oauth server stop error: %v", stopErr)
-		}
-	}()
-
 	authSvc := codex.NewCodexAuth(cfg)
 
 	authURL, err := authSvc.GenerateAuthURL(state, pkceCodes)
@@ -79,29 +64,14 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		return nil, fmt.Errorf("codex authorization url generation failed: %w", err)
 	}
 
-	if !opts.NoBrowser {
-		fmt.Println("Opening browser for Codex authentication")
-		if !browser.IsAvailable() {
-			log.Warn("No browser available; please open the URL manually")
-			util.PrintSSHTunnelInstructions(a.CallbackPort)
-			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
-		} else if err = browser.OpenURL(authURL); err != nil {
-			log.Warnf("Failed to open browser automatically: %v", err)
-			util.PrintSSHTunnelInstructions(a.CallbackPort)
-			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
-		}
-	} else {
-		util.PrintSSHTunnelInstructions(a.CallbackPort)
-		fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
-	}
-
-	fmt.Println("Waiting for Codex authentication callback...")
-
-	result, err := oauthServer.WaitForCallback(5 * time.Minute)
-	if err != nil {
-		if strings.Contains(err.Error(), "timeout") {
-			return nil, codex.NewAuthenticationError(codex.ErrCallbackTimeout, err)
+	var result *codex.OAuthResult
+	if opts.Headless {
+		pasted, errPrompt := promptCallbackURL(opts, "Codex", authURL)
+		if errPrompt != nil {
+			return nil, errPrompt
 		}
+		result = &codex.OAuthResult{Code: pasted.Code, State: pasted.State, Error: pasted.Error}
+	} else if result, err = a.waitForCallback(authURL, opts); err != nil {
 		return nil, err
 	}
 
@@ -109,7 +79,8 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		return nil, codex.NewOAuthError(result.Error, "", http.StatusBadRequest)
 	}
 
-	if result.State != state {
+	// A code pasted without its redirect URL carries no state to compare.
+	if result.State != state && (!opts.Headless || result.State != "") {
 		return nil, codex.NewAuthenticationError(codex.ErrInvalidState, fmt.Errorf("state mismatch"))
 	}
 
@@ -144,3 +115,57 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		Metadata: metadata,
 	}, nil
 }
+
+// waitForCallback serves the local OAuth callback, sends the user to authURL and
+// waits for the provider to redirect back.
+func (a *CodexAuthenticator) waitForCallback(authURL string, opts *LoginOptions) (*codex.OAuthResult, error) {
+	oauthServer := codex.NewOAuthServer(a.CallbackPort)
+	if err := oauthServer.Start(); err != nil {
+		if strings.Contains(err.Error(), "already in use") {
+			return nil, codex.NewAuthenticationError(codex.ErrPortInUse, err)
+		}
+		return nil, codex.NewAuthenticationError(codex.ErrServerStartFailed, err)
+	}
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if stopErr := oauthServer.Stop(stopCtx); stopErr != nil {
+			log.Warnf("codex oauth server stop error: %v", stopErr)
+		}
+	}()
+
+	if !opts.NoBrowser {
+		fmt.Println("Opening browser for Codex authentication")
+		if !browser.IsAvailable() {
+			log.Warn("No browser available; please open the URL manually")
+			util.PrintSSHTunnelInstructions(a.CallbackPort)
+			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
+		} else if err := browser.OpenURL(authURL); err != nil {
+			log.Warnf("Failed to open browser automatically: %v", err)
+			util.PrintSSHTunnelInstructions(a.CallbackPort)
+			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
+		}
+	} else {
+		util.PrintSSHTunnelInstructions(a.CallbackPort)
+		fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
+	}
+
+	fmt.Println("Waiting for Codex authentication callback...")
+
+	result, err := oauthServer.WaitForCallback(5 * time.Minute)
+	if err != nil {
+		if strings.Contains(err.Error(), "timeout") {
+			return nil, codex.NewAuthenticationError(codex.ErrCallbackTimeout, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// ValidateCredential implements CredentialValidator.
+func (a *CodexAuthenticator) ValidateCredential(metadata map[string]any) error {
+	if err := requireMetadata("codex", metadata, "refresh_token", "access_token"); err != nil {
+		return err
+	}
+	return requireMetadata("codex", metadata, "email")
+}
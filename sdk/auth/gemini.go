@@ -44,6 +44,18 @@ func (a *GeminiAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 	}
 
 	geminiAuth := gemini.NewGeminiAuth()
+	if opts.Headless {
+		geminiAuth.PromptCallback = func(authURL string) (string, string, error) {
+			pasted, err := promptCallbackURL(opts, "Gemini", authURL)
+			if err != nil {
+				return "", "", err
+			}
+			if pasted.Error != "" {
+				return "", "", fmt.Errorf("authentication failed via callback: %s", pasted.Error)
+			}
+			return pasted.Code, pasted.State, nil
+		}
+	}
 	_, err := geminiAuth.GetAuthenticatedClient(ctx, &ts, cfg, opts.NoBrowser)
 	if err != nil {
 		return nil, fmt.Errorf("gemini authentication failed: %w", err)
@@ -67,3 +79,15 @@ func (a *GeminiAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 		Metadata: metadata,
 	}, nil
 }
+
+// ValidateCredential implements CredentialValidator.
+func (a *GeminiAuthenticator) ValidateCredential(metadata map[string]any) error {
+	token, _ := metadata["token"].(map[string]any)
+	if token == nil {
+		return fmt.Errorf("gemini credential is missing token")
+	}
+	if err := requireMetadata("gemini", token, "refresh_token", "access_token"); err != nil {
+		return err
+	}
+	return requireMetadata("gemini", metadata, "email")
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+)
+
+// promptCallbackURL completes the browser step of an OAuth login without a local
+// callback server. The user opens authURL on any machine, approves access and
+// pastes back the URL the browser was redirected to, which does not need to load.
+func promptCallbackURL(opts *LoginOptions, providerName, authURL string) (*misc.OAuthCallback, error) {
+	prompt := opts.Prompt
+	if prompt == nil {
+		prompt = stdinPrompt()
+	}
+	fmt.Printf("Open the following URL in a browser on any machine to sign in to %s:\n%s\n\n", providerName, authURL)
+	fmt.Println("After you approve access the browser is redirected to a localhost address that will fail to load.")
+	fmt.Println("Copy the full URL from the address bar and paste it below.")
+	for {
+		input, err := prompt("Redirect URL: ")
+		if err != nil {
+			return nil, fmt.Errorf("%s login: read redirect url: %w", strings.ToLower(providerName), err)
+		}
+		if strings.TrimSpace(input) == "" {
+			return nil, fmt.Errorf("%s login: no redirect url provided", strings.ToLower(providerName))
+		}
+		result, errParse := misc.ParseOAuthCallback(input)
+		if errParse != nil {
+			fmt.Printf("%v; please paste the complete URL.\n", errParse)
+			continue
+		}
+		return result, nil
+	}
+}
+
+func stdinPrompt() func(string) (string, error) {
+	reader := bufio.NewReader(os.Stdin)
+	return func(prompt string) (string, error) {
+		fmt.Print(prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+}
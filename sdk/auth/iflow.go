@@ -129,3 +129,8 @@ func (a *IFlowAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		},
 	}, nil
 }
+
+// ValidateCredential implements CredentialValidator.
+func (a *IFlowAuthenticator) ValidateCredential(metadata map[string]any) error {
+	return requireMetadata("iflow", metadata, "api_key", "refresh_token", "cookie")
+}
@@ -11,10 +11,16 @@ import (
 
 var ErrRefreshNotSupported = errors.New("cliproxy auth: refresh not supported")
 
+// ErrNoAuthenticator reports a provider without a registered authenticator.
+var ErrNoAuthenticator = errors.New("cliproxy auth: no authenticator for provider")
+
 // LoginOptions captures generic knobs shared across authenticators.
 // Provider-specific logic can inspect Metadata for extra parameters.
+// Headless skips the local callback server and asks, through Prompt, for the
+// redirect URL the user's browser ended up on.
 type LoginOptions struct {
 	NoBrowser bool
+	Headless  bool
 	ProjectID string
 	Metadata  map[string]string
 	Prompt    func(prompt string) (string, error)
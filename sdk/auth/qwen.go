@@ -112,3 +112,8 @@ func (a *QwenAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		Metadata: metadata,
 	}, nil
 }
+
+// ValidateCredential implements CredentialValidator.
+func (a *QwenAuthenticator) ValidateCredential(metadata map[string]any) error {
+	return requireMetadata("qwen", metadata, "refresh_token", "access_token")
+}
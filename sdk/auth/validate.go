@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CredentialValidator is implemented by authenticators that can check a stored
+// credential, such as one imported from another instance, without a new login.
+type CredentialValidator interface {
+	ValidateCredential(metadata map[string]any) error
+}
+
+// Validate checks credential metadata with the authenticator registered for
+// provider. It returns ErrNoAuthenticator when none is registered, and nil when
+// the authenticator has no validation of its own.
+func (m *Manager) Validate(provider string, metadata map[string]any) error {
+	a, ok := m.authenticators[provider]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoAuthenticator, provider)
+	}
+	if validator, okValidator := a.(CredentialValidator); okValidator {
+		return validator.ValidateCredential(metadata)
+	}
+	return nil
+}
+
+// requireMetadata returns an error unless metadata has a non-empty string under at least one of keys.
+func requireMetadata(provider string, metadata map[string]any, keys ...string) error {
+	for _, key := range keys {
+		if value, ok := metadata[key].(string); ok && strings.TrimSpace(value) != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s credential is missing %s", provider, strings.Join(keys, " or "))
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// AffinityMetadataKey is the Options.Metadata key carrying the session affinity key
+// computed by the API handlers.
+const AffinityMetadataKey = "affinity_key"
+
+// DefaultAffinityTTL is how long an idle session stays pinned to a credential.
+const DefaultAffinityTTL = 30 * time.Minute
+
+const (
+	affinitySweepInterval = time.Minute
+	affinityMaxEntries    = 100_000
+)
+
+// AffinitySelector pins requests sharing an affinity key to the credential that
+// served the first request, so upstream prompt caches stay warm. When the pinned
+// credential is blocked, missing, or was already tried for this request, it falls
+// back to the wrapped selector and re-pins to the new choice.
+type AffinitySelector struct {
+	inner Selector
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]affinityEntry
+	lastSweep time.Time
+}
+
+type affinityEntry struct {
+	authID  string
+	expires time.Time
+}
+
+// NewAffinitySelector wraps inner with session affinity. A non-positive ttl uses DefaultAffinityTTL.
+func NewAffinitySelector(inner Selector, ttl time.Duration) *AffinitySelector {
+	if inner == nil {
+		inner = &RoundRobinSelector{}
+	}
+	if ttl <= 0 {
+		ttl = DefaultAffinityTTL
+	}
+	return &AffinitySelector{inner: inner, ttl: ttl, entries: make(map[string]affinityEntry)}
+}
+
+// Pick implements Selector.
+func (s *AffinitySelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	key := affinityKeyFromOptions(opts)
+	if key == "" {
+		return s.inner.Pick(ctx, provider, model, opts, auths)
+	}
+	entryKey := provider + "|" + model + "|" + key
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.entries[entryKey]
+	s.mu.Unlock()
+	if ok && entry.expires.After(now) {
+		for _, candidate := range auths {
+			if candidate == nil || candidate.ID != entry.authID {
+				continue
+			}
+			if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); !blocked {
+				s.remember(entryKey, candidate.ID, now)
+				return candidate, nil
+			}
+			break
+		}
+	}
+
+	selected, err := s.inner.Pick(ctx, provider, model, opts, auths)
+	if err != nil || selected == nil {
+		return selected, err
+	}
+	s.remember(entryKey, selected.ID, now)
+	return selected, nil
+}
+
+// AttemptStarted implements SelectionObserver by forwarding to the wrapped selector.
+func (s *AffinitySelector) AttemptStarted(auth *Auth, model string) {
+	if observer, ok := s.inner.(SelectionObserver); ok {
+		observer.AttemptStarted(auth, model)
+	}
+}
+
+// AttemptFinished implements SelectionObserver by forwarding to the wrapped selector.
+func (s *AffinitySelector) AttemptFinished(auth *Auth, model string, err error) {
+	if observer, ok := s.inner.(SelectionObserver); ok {
+		observer.AttemptFinished(auth, model, err)
+	}
+}
+
+func (s *AffinitySelector) remember(entryKey, authID string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= affinitySweepInterval || len(s.entries) >= affinityMaxEntries {
+		for k, v := range s.entries {
+			if !v.expires.After(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+		if len(s.entries) >= affinityMaxEntries {
+			// Still full of live sessions; start over rather than grow without bound.
+			s.entries = make(map[string]affinityEntry)
+		}
+	}
+	s.entries[entryKey] = affinityEntry{authID: authID, expires: now.Add(s.ttl)}
+}
+
+func affinityKeyFromOptions(opts cliproxyexecutor.Options) string {
+	if len(opts.Metadata) == 0 {
+		return ""
+	}
+	raw, ok := opts.Metadata[AffinityMetadataKey].(string)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(raw)
+}
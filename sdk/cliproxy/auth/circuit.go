@@ -0,0 +1,388 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// CircuitState describes the state of an upstream endpoint circuit.
+type CircuitState string
+
+const (
+	// CircuitClosed lets all traffic through.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects traffic until the open period elapses.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen admits a limited number of probe requests.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// Default circuit breaker tuning used when a config field is zero.
+const (
+	DefaultCircuitFailureThreshold = 5
+	DefaultCircuitOpenDuration     = 30 * time.Second
+	DefaultCircuitHalfOpenProbes   = 1
+)
+
+// CircuitBreakerConfig tunes the per-endpoint circuit breaker.
+type CircuitBreakerConfig struct {
+	// Disabled turns the breaker off; every credential is always eligible.
+	Disabled bool
+	// FailureThreshold is the number of consecutive endpoint failures that opens the circuit.
+	FailureThreshold int
+	// OpenDuration is how long an open circuit rejects traffic before probing.
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of concurrent probe requests allowed while half-open.
+	HalfOpenProbes int
+}
+
+// CircuitStatus is a snapshot of one endpoint circuit.
+type CircuitStatus struct {
+	Provider            string       `json:"provider"`
+	BaseURL             string       `json:"base_url"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	ProbesInFlight      int          `json:"probes_in_flight,omitempty"`
+	OpenedAt            time.Time    `json:"opened_at"`
+	RetryAt             time.Time    `json:"retry_at"`
+	LastError           string       `json:"last_error,omitempty"`
+	UpdatedAt           time.Time    `json:"updated_at"`
+}
+
+// circuitBreakers tracks endpoint health shared by every credential pointing at
+// the same provider and base URL. Only credentials with a base_url attribute
+// participate, since built-in endpoints are shared by all accounts of a provider
+// and their failures are almost always credential specific.
+type circuitBreakers struct {
+	mu       sync.Mutex
+	cfg      CircuitBreakerConfig
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	provider string
+	baseURL  string
+	state    CircuitState
+	failures int
+	probes   int
+	openedAt time.Time
+	retryAt  time.Time
+	lastErr  string
+	updated  time.Time
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	b := &circuitBreakers{circuits: make(map[string]*circuit)}
+	b.configure(CircuitBreakerConfig{})
+	return b
+}
+
+// circuitEndpoint returns the provider and normalised base URL identifying the
+// circuit for auth, or ok=false when auth does not use a custom endpoint.
+func circuitEndpoint(auth *Auth) (provider, baseURL string, ok bool) {
+	if auth == nil || auth.Attributes == nil {
+		return "", "", false
+	}
+	baseURL = strings.TrimRight(strings.TrimSpace(auth.Attributes["base_url"]), "/")
+	if baseURL == "" {
+		return "", "", false
+	}
+	return strings.ToLower(auth.Provider), strings.ToLower(baseURL), true
+}
+
+func circuitKey(provider, baseURL string) string { return provider + "|" + baseURL }
+
+func (b *circuitBreakers) configure(cfg CircuitBreakerConfig) {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = DefaultCircuitOpenDuration
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = DefaultCircuitHalfOpenProbes
+	}
+	b.mu.Lock()
+	b.cfg = cfg
+	if cfg.Disabled {
+		b.circuits = make(map[string]*circuit)
+	}
+	b.mu.Unlock()
+}
+
+// admits reports whether auth's endpoint currently accepts traffic without
+// reserving anything; allow makes the reservation for the credential picked.
+func (b *circuitBreakers) admits(auth *Auth, now time.Time) bool {
+	provider, baseURL, ok := circuitEndpoint(auth)
+	if !ok {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.Disabled {
+		return true
+	}
+	c, exists := b.circuits[circuitKey(provider, baseURL)]
+	if !exists {
+		return true
+	}
+	b.advance(c, now)
+	switch c.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		return c.probes < b.cfg.HalfOpenProbes
+	default:
+		return true
+	}
+}
+
+// allow admits one attempt against auth's endpoint. While the circuit is
+// half-open it reserves a probe slot under the same lock as the check, so
+// concurrent callers cannot exceed HalfOpenProbes; probe reports whether a slot
+// was taken and must be passed to begin.
+func (b *circuitBreakers) allow(auth *Auth, now time.Time) (probe bool, ok bool) {
+	provider, baseURL, okEndpoint := circuitEndpoint(auth)
+	if !okEndpoint {
+		return false, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.Disabled {
+		return false, true
+	}
+	c, exists := b.circuits[circuitKey(provider, baseURL)]
+	if !exists {
+		return false, true
+	}
+	b.advance(c, now)
+	switch c.state {
+	case CircuitOpen:
+		return false, false
+	case CircuitHalfOpen:
+		if c.probes >= b.cfg.HalfOpenProbes {
+			return false, false
+		}
+		c.probes++
+		return true, true
+	default:
+		return false, true
+	}
+}
+
+// retryAt returns the earliest time any of the given open circuits will probe again.
+func (b *circuitBreakers) retryAt(auths []*Auth) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var earliest time.Time
+	for _, auth := range auths {
+		provider, baseURL, ok := circuitEndpoint(auth)
+		if !ok {
+			continue
+		}
+		if c, exists := b.circuits[circuitKey(provider, baseURL)]; exists && c.state == CircuitOpen {
+			if earliest.IsZero() || c.retryAt.Before(earliest) {
+				earliest = c.retryAt
+			}
+		}
+	}
+	return earliest
+}
+
+// begin registers an attempt against auth's endpoint and returns the function
+// reporting its outcome. probe is the result of the allow call admitting it.
+func (b *circuitBreakers) begin(auth *Auth, probe bool) func(error) {
+	provider, baseURL, ok := circuitEndpoint(auth)
+	if !ok {
+		return func(error) {}
+	}
+	key := circuitKey(provider, baseURL)
+	return func(err error) { b.finish(key, provider, baseURL, probe, err) }
+}
+
+func (b *circuitBreakers) finish(key, provider, baseURL string, probe bool, err error) {
+	failure, counted := classifyEndpointOutcome(err)
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.Disabled {
+		return
+	}
+	c, exists := b.circuits[key]
+	if probe && exists && c.probes > 0 {
+		c.probes--
+	}
+	if !counted {
+		return
+	}
+	if !failure {
+		if exists {
+			if c.state != CircuitClosed {
+				b.close(c, now)
+			} else {
+				c.failures = 0
+			}
+		}
+		return
+	}
+	if !exists {
+		c = &circuit{provider: provider, baseURL: baseURL, state: CircuitClosed}
+		b.circuits[key] = c
+	}
+	c.failures++
+	c.lastErr = err.Error()
+	c.updated = now
+	if c.state == CircuitHalfOpen || c.failures >= b.cfg.FailureThreshold {
+		c.state = CircuitOpen
+		c.openedAt = now
+		c.retryAt = now.Add(b.cfg.OpenDuration)
+	}
+}
+
+// advance moves an open circuit to half-open once its open period has elapsed.
+func (b *circuitBreakers) advance(c *circuit, now time.Time) {
+	if c.state == CircuitOpen && !now.Before(c.retryAt) {
+		c.state = CircuitHalfOpen
+		c.probes = 0
+		c.updated = now
+	}
+}
+
+func (b *circuitBreakers) close(c *circuit, now time.Time) {
+	c.state = CircuitClosed
+	c.failures = 0
+	c.probes = 0
+	c.openedAt = time.Time{}
+	c.retryAt = time.Time{}
+	c.updated = now
+}
+
+func (b *circuitBreakers) snapshot() []CircuitStatus {
+	now := time.Now()
+	b.mu.Lock()
+	out := make([]CircuitStatus, 0, len(b.circuits))
+	for _, c := range b.circuits {
+		b.advance(c, now)
+		out = append(out, CircuitStatus{
+			Provider:            c.provider,
+			BaseURL:             c.baseURL,
+			State:               c.state,
+			ConsecutiveFailures: c.failures,
+			ProbesInFlight:      c.probes,
+			OpenedAt:            c.openedAt,
+			RetryAt:             c.retryAt,
+			LastError:           c.lastErr,
+			UpdatedAt:           c.updated,
+		})
+	}
+	b.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].BaseURL < out[j].BaseURL
+	})
+	return out
+}
+
+func (b *circuitBreakers) reset(provider, baseURL string) bool {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	baseURL = strings.ToLower(strings.TrimRight(strings.TrimSpace(baseURL), "/"))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if provider == "" && baseURL == "" {
+		found := len(b.circuits) > 0
+		b.circuits = make(map[string]*circuit)
+		return found
+	}
+	found := false
+	for key, c := range b.circuits {
+		if (provider == "" || c.provider == provider) && (baseURL == "" || c.baseURL == baseURL) {
+			delete(b.circuits, key)
+			found = true
+		}
+	}
+	return found
+}
+
+// classifyEndpointOutcome decides whether err says something about endpoint health.
+// Transport errors, timeouts and 5xx responses count as failures; other HTTP errors
+// prove the endpoint is reachable and count as successes. Client cancellations and
+// errors raised before anything was sent, such as translation failures, are ignored.
+func classifyEndpointOutcome(err error) (failure bool, counted bool) {
+	if err == nil {
+		return false, true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false, false
+	}
+	var se cliproxyexecutor.StatusError
+	if errors.As(err, &se) && se != nil {
+		status := se.StatusCode()
+		switch {
+		case status == 0:
+			return false, false
+		case status == http.StatusRequestTimeout || status >= http.StatusInternalServerError:
+			return true, true
+		default:
+			return false, true
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, true
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true, true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true, true
+	}
+	return false, false
+}
+
+// newCircuitOpenError reports that every remaining credential sits behind an open circuit.
+func newCircuitOpenError(retryAt, now time.Time) *Error {
+	message := "upstream endpoint circuit is open"
+	if wait := retryAt.Sub(now); !retryAt.IsZero() && wait > 0 {
+		message = fmt.Sprintf("%s; retry in %s", message, wait.Round(time.Second))
+	}
+	return &Error{Code: "circuit_open", Message: message, Retryable: true, HTTPStatus: http.StatusServiceUnavailable}
+}
+
+// SetCircuitBreakerConfig updates the endpoint circuit breaker tuning.
+func (m *Manager) SetCircuitBreakerConfig(cfg CircuitBreakerConfig) {
+	if m == nil {
+		return
+	}
+	m.circuits.configure(cfg)
+}
+
+// CircuitStatuses returns a snapshot of every tracked endpoint circuit.
+func (m *Manager) CircuitStatuses() []CircuitStatus {
+	if m == nil {
+		return nil
+	}
+	return m.circuits.snapshot()
+}
+
+// ResetCircuits closes circuits matching provider and baseURL; empty values match all.
+// It reports whether any circuit was reset.
+func (m *Manager) ResetCircuits(provider, baseURL string) bool {
+	if m == nil {
+		return false
+	}
+	return m.circuits.reset(provider, baseURL)
+}
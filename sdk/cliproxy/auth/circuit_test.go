@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestCircuitAuth(id string) *Auth {
+	return &Auth{ID: id, Provider: "openai-compatibility", Attributes: map[string]string{"base_url": "https://llm.example.com/v1/"}}
+}
+
+// tripCircuit records enough endpoint failures to open the circuit of auth.
+func tripCircuit(b *circuitBreakers, auth *Auth) {
+	for i := 0; i < b.cfg.FailureThreshold; i++ {
+		b.begin(auth, false)(testStatusError{code: http.StatusBadGateway})
+	}
+}
+
+func TestCircuitHalfOpenProbes(t *testing.T) {
+	testCases := []struct {
+		name       string
+		probes     int
+		outcome    error
+		wantAdmit  int
+		wantState  CircuitState
+		wantRetest bool
+	}{
+		{
+			name:       "single probe succeeds",
+			probes:     1,
+			outcome:    nil,
+			wantAdmit:  1,
+			wantState:  CircuitClosed,
+			wantRetest: true,
+		},
+		{
+			name:       "single probe fails",
+			probes:     1,
+			outcome:    testStatusError{code: http.StatusServiceUnavailable},
+			wantAdmit:  1,
+			wantState:  CircuitOpen,
+			wantRetest: false,
+		},
+		{
+			name:       "probe rejected by the endpoint still closes",
+			probes:     1,
+			outcome:    testStatusError{code: http.StatusUnauthorized},
+			wantAdmit:  1,
+			wantState:  CircuitClosed,
+			wantRetest: true,
+		},
+		{
+			name:       "several probe slots",
+			probes:     3,
+			outcome:    nil,
+			wantAdmit:  3,
+			wantState:  CircuitClosed,
+			wantRetest: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newCircuitBreakers()
+			b.configure(CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute, HalfOpenProbes: tc.probes})
+			auth := newTestCircuitAuth("circuit-auth")
+			now := time.Now()
+
+			tripCircuit(b, auth)
+			if _, ok := b.allow(auth, now); ok {
+				t.Fatalf("allow() admitted a request while the circuit is open")
+			}
+			if b.admits(auth, now) {
+				t.Fatalf("admits() = true while the circuit is open")
+			}
+
+			// Once the open period has elapsed, concurrent callers race for the probe slots.
+			later := now.Add(2 * time.Minute)
+			var admitted atomic.Int32
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if probe, ok := b.allow(auth, later); ok {
+						if !probe {
+							t.Errorf("allow() admitted a half-open request without reserving a probe slot")
+						}
+						admitted.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := int(admitted.Load()); got != tc.wantAdmit {
+				t.Fatalf("allow() admitted %d probes, want %d", got, tc.wantAdmit)
+			}
+			if b.admits(auth, later) {
+				t.Fatalf("admits() = true with every probe slot taken")
+			}
+
+			b.begin(auth, true)(tc.outcome)
+			status := b.snapshot()[0]
+			if status.State != tc.wantState {
+				t.Fatalf("state after probe = %s, want %s", status.State, tc.wantState)
+			}
+			if _, ok := b.allow(auth, time.Now()); ok != tc.wantRetest {
+				t.Fatalf("allow() after probe = %v, want %v", ok, tc.wantRetest)
+			}
+		})
+	}
+}
+
+func TestCircuitIgnoresAuthsWithoutEndpoint(t *testing.T) {
+	b := newCircuitBreakers()
+	b.configure(CircuitBreakerConfig{FailureThreshold: 1})
+	auth := &Auth{ID: "builtin", Provider: "claude"}
+	b.begin(auth, false)(testStatusError{code: http.StatusBadGateway})
+	if probe, ok := b.allow(auth, time.Now()); !ok || probe {
+		t.Fatalf("allow() = %v, %v for an auth without base_url", probe, ok)
+	}
+	if len(b.snapshot()) != 0 {
+		t.Fatalf("a circuit was created for an auth without base_url")
+	}
+}
+
+func TestClassifyEndpointOutcome(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		wantFailure bool
+		wantCounted bool
+	}{
+		{name: "success", err: nil, wantFailure: false, wantCounted: true},
+		{name: "server error", err: testStatusError{code: http.StatusInternalServerError}, wantFailure: true, wantCounted: true},
+		{name: "gateway timeout", err: testStatusError{code: http.StatusGatewayTimeout}, wantFailure: true, wantCounted: true},
+		{name: "request timeout", err: testStatusError{code: http.StatusRequestTimeout}, wantFailure: true, wantCounted: true},
+		{name: "rate limited", err: testStatusError{code: http.StatusTooManyRequests}, wantFailure: false, wantCounted: true},
+		{name: "bad request", err: testStatusError{code: http.StatusBadRequest}, wantFailure: false, wantCounted: true},
+		{name: "status without code", err: testStatusError{code: 0}, wantFailure: false, wantCounted: false},
+		{name: "transport error", err: &url.Error{Op: "Post", URL: "https://llm.example.com", Err: errors.New("connection refused")}, wantFailure: true, wantCounted: true},
+		{name: "transport timeout", err: &url.Error{Op: "Post", URL: "https://llm.example.com", Err: testTimeoutError{}}, wantFailure: true, wantCounted: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantFailure: true, wantCounted: true},
+		{name: "truncated body", err: fmt.Errorf("read response: %w", io.ErrUnexpectedEOF), wantFailure: true, wantCounted: true},
+		{name: "client cancelled", err: context.Canceled, wantFailure: false, wantCounted: false},
+		{name: "cancelled transport", err: &url.Error{Op: "Post", URL: "https://llm.example.com", Err: context.Canceled}, wantFailure: false, wantCounted: false},
+		{name: "translator error", err: errors.New("translate request: invalid payload"), wantFailure: false, wantCounted: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			failure, counted := classifyEndpointOutcome(tc.err)
+			if failure != tc.wantFailure || counted != tc.wantCounted {
+				t.Fatalf("classifyEndpointOutcome() = %v, %v, want %v, %v", failure, counted, tc.wantFailure, tc.wantCounted)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// EmbeddingExecutor is implemented by provider executors that can create embeddings.
+// The request payload uses opts.SourceFormat: an OpenAI embeddings request or a Gemini
+// batchEmbedContents request. The response payload is returned in the same format.
+type EmbeddingExecutor interface {
+	Embed(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error)
+}
+
+// ExecuteEmbedding creates embeddings using the configured selector and executor.
+// Only providers whose executor implements EmbeddingExecutor are considered; credential
+// rotation, cooldowns and retries behave as they do for Execute.
+func (m *Manager) ExecuteEmbedding(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	supports := func(executor ProviderExecutor) bool {
+		_, ok := executor.(EmbeddingExecutor)
+		return ok
+	}
+	unsupported := &Error{
+		Code:       "embeddings_not_supported",
+		Message:    fmt.Sprintf("model %s does not support embeddings", req.Model),
+		HTTPStatus: http.StatusBadRequest,
+	}
+	return m.executeCapability(ctx, providers, req, opts, supports, unsupported, func(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+		return executor.(EmbeddingExecutor).Embed(ctx, auth, req, opts)
+	})
+}
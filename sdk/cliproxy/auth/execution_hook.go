@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+)
+
+// Execution describes a single provider attempt dispatched by the Manager.
+// Hooks receive a pointer so they can mutate the request, options, or transport
+// before the executor runs.
+type Execution struct {
+	// Provider is the provider key handling this attempt.
+	Provider string
+	// Request is the payload forwarded to the provider executor.
+	Request cliproxyexecutor.Request
+	// Options carries execution flags (streaming, headers, etc.).
+	Options cliproxyexecutor.Options
+	// Auth references the credential selected for this attempt. Hooks may replace it
+	// with an adjusted copy of the same credential; switching to another credential
+	// is ignored because the attempt is attributed to the one the Manager selected.
+	Auth *Auth
+	// HTTPClient optionally replaces the outbound HTTP client used by the executor.
+	HTTPClient *http.Client
+
+	mu     sync.Mutex
+	values map[any]any
+}
+
+// Value returns the state a hook stored under key with SetValue.
+func (e *Execution) Value(key any) any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.values[key]
+}
+
+// SetValue stores hook state under key for the rest of the attempt, so a hook can
+// carry data from BeforeExecute to OnStreamChunk and AfterExecute.
+func (e *Execution) SetValue(key, value any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.values == nil {
+		e.values = make(map[any]any)
+	}
+	e.values[key] = value
+}
+
+// ExecutionHook intercepts provider executions performed by the Manager. A hook may
+// also implement Enabled() bool; while it reports false the hook is skipped, so an
+// idle hook adds no per-attempt cost.
+type ExecutionHook interface {
+	// BeforeExecute fires after an auth has been selected and before the executor runs.
+	BeforeExecute(ctx context.Context, exec *Execution)
+	// AfterExecute fires once the attempt completes; for streams it fires after the last
+	// chunk, or once the caller's context is cancelled and the upstream stream has ended.
+	AfterExecute(ctx context.Context, exec *Execution, resp cliproxyexecutor.Response, err error)
+	// OnStreamChunk fires for every chunk emitted by a streaming executor.
+	OnStreamChunk(ctx context.Context, exec *Execution, chunk cliproxyexecutor.StreamChunk)
+}
+
+// httpClientContextKey is an unexported context key type for hook supplied HTTP clients.
+type httpClientContextKey struct{}
+
+// HTTPClientFromContext returns the HTTP client an execution hook supplied for the
+// attempt, or nil. Executors use it in place of the client they would build.
+func HTTPClientFromContext(ctx context.Context) *http.Client {
+	if ctx == nil {
+		return nil
+	}
+	client, _ := ctx.Value(httpClientContextKey{}).(*http.Client)
+	return client
+}
+
+// SetExecutionHooks replaces the hooks invoked around every provider attempt.
+func (m *Manager) SetExecutionHooks(hooks ...ExecutionHook) {
+	filtered := make([]ExecutionHook, 0, len(hooks))
+	for _, hook := range hooks {
+		if hook != nil {
+			filtered = append(filtered, hook)
+		}
+	}
+	m.mu.Lock()
+	m.executionHooks = filtered
+	m.mu.Unlock()
+}
+
+// AddExecutionHook appends a hook invoked around every provider attempt.
+func (m *Manager) AddExecutionHook(hook ExecutionHook) {
+	if hook == nil {
+		return
+	}
+	m.mu.Lock()
+	m.executionHooks = append(m.executionHooks, hook)
+	m.mu.Unlock()
+}
+
+func (m *Manager) executionHookSnapshot() []ExecutionHook {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.executionHooks) == 0 {
+		return nil
+	}
+	hooks := make([]ExecutionHook, 0, len(m.executionHooks))
+	for _, hook := range m.executionHooks {
+		if toggle, ok := hook.(interface{ Enabled() bool }); ok && !toggle.Enabled() {
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	return hooks
+}
+
+// beginExecution runs BeforeExecute hooks for an attempt and returns the context,
+// auth, request and options the executor should use. The returned Execution is nil
+// when no hooks are registered.
+func (m *Manager) beginExecution(ctx context.Context, provider string, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (context.Context, *Execution, []ExecutionHook) {
+	hooks := m.executionHookSnapshot()
+	if len(hooks) == 0 {
+		return ctx, nil, nil
+	}
+	exec := &Execution{
+		Provider: provider,
+		Request:  cloneExecutorRequest(req),
+		Options:  opts,
+		Auth:     auth,
+	}
+	for _, hook := range hooks {
+		hook.BeforeExecute(ctx, exec)
+	}
+	if exec.Auth == nil || exec.Auth.ID != auth.ID || exec.Auth.Provider != auth.Provider {
+		if exec.Auth != nil {
+			log.Warnf("execution hook replaced auth %s with %s; keeping the selected auth", auth.ID, exec.Auth.ID)
+		}
+		exec.Auth = auth
+	}
+	if exec.HTTPClient != nil {
+		ctx = context.WithValue(ctx, httpClientContextKey{}, exec.HTTPClient)
+	}
+	return ctx, exec, hooks
+}
+
+func finishExecution(ctx context.Context, exec *Execution, hooks []ExecutionHook, resp cliproxyexecutor.Response, err error) {
+	if exec == nil {
+		return
+	}
+	for _, hook := range hooks {
+		hook.AfterExecute(ctx, exec, resp, err)
+	}
+}
+
+func observeStreamChunk(ctx context.Context, exec *Execution, hooks []ExecutionHook, chunk cliproxyexecutor.StreamChunk) {
+	if exec == nil {
+		return
+	}
+	for _, hook := range hooks {
+		hook.OnStreamChunk(ctx, exec, chunk)
+	}
+}
+
+func cloneExecutorRequest(req cliproxyexecutor.Request) cliproxyexecutor.Request {
+	cloned := req
+	if len(req.Payload) > 0 {
+		cloned.Payload = append([]byte(nil), req.Payload...)
+	}
+	if len(req.Metadata) > 0 {
+		cloned.Metadata = make(map[string]any, len(req.Metadata))
+		for k, v := range req.Metadata {
+			cloned.Metadata[k] = v
+		}
+	}
+	return cloned
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// ExecutionReport describes how the Manager served a request. Attach one with
+// WithExecutionReport before calling Execute or ExecuteStream and read it once
+// the call returns successfully.
+type ExecutionReport struct {
+	// RequestedModel is the model the caller asked for.
+	RequestedModel string
+	// Model is the model that actually answered; it differs from RequestedModel
+	// when a fallback served the request.
+	Model string
+	// CacheHit is set when the response was replayed from the response cache.
+	CacheHit bool
+	// Provider and AuthID identify the credential that served the request.
+	Provider string
+	AuthID   string
+}
+
+// Fallback reports whether a fallback model served the request.
+func (r *ExecutionReport) Fallback() bool {
+	return r != nil && r.Model != "" && r.Model != r.RequestedModel
+}
+
+type executionReportContextKey struct{}
+
+// FallbackFilterMetadataKey is the Options.Metadata key holding a func(model string) bool
+// that reports whether a fallback model may serve the request, for example because
+// the client's API key is restricted to some models.
+const FallbackFilterMetadataKey = "fallback_filter"
+
+// WithExecutionReport returns a context carrying a fresh ExecutionReport.
+func WithExecutionReport(ctx context.Context) (context.Context, *ExecutionReport) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	report := &ExecutionReport{}
+	return context.WithValue(ctx, executionReportContextKey{}, report), report
+}
+
+func reportServedModel(ctx context.Context, requested, served string) {
+	if ctx == nil {
+		return
+	}
+	if report, ok := ctx.Value(executionReportContextKey{}).(*ExecutionReport); ok && report != nil {
+		report.RequestedModel = requested
+		report.Model = served
+	}
+}
+
+// SetModelFallbacks replaces the model fallback chains. Each key lists the models
+// tried in order when the key model cannot serve a request.
+func (m *Manager) SetModelFallbacks(fallbacks map[string][]string) {
+	if m == nil {
+		return
+	}
+	normalized := make(map[string][]string, len(fallbacks))
+	for model, chain := range fallbacks {
+		model = strings.TrimSpace(model)
+		if model == "" {
+			continue
+		}
+		seen := map[string]struct{}{model: {}}
+		for _, next := range chain {
+			next = strings.TrimSpace(next)
+			if next == "" {
+				continue
+			}
+			if _, dup := seen[next]; dup {
+				continue
+			}
+			seen[next] = struct{}{}
+			normalized[model] = append(normalized[model], next)
+		}
+	}
+	m.mu.Lock()
+	m.modelFallbacks = normalized
+	m.mu.Unlock()
+}
+
+// fallbackChain returns model followed by its configured fallbacks.
+func (m *Manager) fallbackChain(model string) []string {
+	m.mu.RLock()
+	fallbacks := m.modelFallbacks[strings.TrimSpace(model)]
+	m.mu.RUnlock()
+	chain := make([]string, 0, 1+len(fallbacks))
+	chain = append(chain, model)
+	return append(chain, fallbacks...)
+}
+
+// fallbackAllowed reports whether the request's fallback filter, if any, admits model.
+func fallbackAllowed(opts cliproxyexecutor.Options, model string) bool {
+	filter, ok := opts.Metadata[FallbackFilterMetadataKey].(func(string) bool)
+	return !ok || filter == nil || filter(model)
+}
+
+// shouldFallback reports whether err means another model might still serve the
+// request: rate limits and cooldowns (429), upstream 5xx including open circuits,
+// and upstream timeouts. Client errors, cancellations, missing credentials or
+// providers and translation errors do not fall back.
+func shouldFallback(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if status := statusCodeFromError(err); status != 0 {
+		return status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
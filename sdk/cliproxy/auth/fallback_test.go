@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// testStatusError is an upstream error carrying an HTTP status.
+type testStatusError struct{ code int }
+
+func (e testStatusError) Error() string   { return fmt.Sprintf("upstream status %d", e.code) }
+func (e testStatusError) StatusCode() int { return e.code }
+
+// testTimeoutError is a transport error reporting a timeout.
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string   { return "i/o timeout" }
+func (testTimeoutError) Timeout() bool   { return true }
+func (testTimeoutError) Temporary() bool { return true }
+
+var _ net.Error = testTimeoutError{}
+
+// testExecutor answers every request with the error configured for its model.
+type testExecutor struct {
+	provider string
+
+	mu     sync.Mutex
+	errs   map[string]error
+	models []string
+}
+
+func (e *testExecutor) Identifier() string { return e.provider }
+
+func (e *testExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.models = append(e.models, req.Model)
+	if err := e.errs[req.Model]; err != nil {
+		return cliproxyexecutor.Response{}, err
+	}
+	return cliproxyexecutor.Response{Payload: []byte(req.Model)}, nil
+}
+
+func (e *testExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *testExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e *testExecutor) CountTokens(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return e.Execute(ctx, auth, req, opts)
+}
+
+func (e *testExecutor) calls() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.models...)
+}
+
+// newTestManager returns a manager with one credential of provider serving models.
+func newTestManager(t *testing.T, provider string, models []string, errs map[string]error) (*Manager, *testExecutor) {
+	t.Helper()
+	manager := NewManager(nil, nil, nil)
+	executor := &testExecutor{provider: provider, errs: errs}
+	manager.RegisterExecutor(executor)
+	auth := &Auth{ID: provider + "-auth", Provider: provider, Status: StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("failed to register auth: %v", err)
+	}
+	infos := make([]*registry.ModelInfo, 0, len(models))
+	for _, model := range models {
+		infos = append(infos, &registry.ModelInfo{ID: model, Object: "model", OwnedBy: provider})
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, provider, infos)
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	return manager, executor
+}
+
+func TestShouldFallback(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "no error", ctx: context.Background(), err: nil, want: false},
+		{name: "rate limited", ctx: context.Background(), err: testStatusError{code: http.StatusTooManyRequests}, want: true},
+		{name: "server error", ctx: context.Background(), err: testStatusError{code: http.StatusBadGateway}, want: true},
+		{name: "request timeout status", ctx: context.Background(), err: testStatusError{code: http.StatusRequestTimeout}, want: true},
+		{name: "open circuit", ctx: context.Background(), err: newCircuitOpenError(time.Time{}, time.Now()), want: true},
+		{name: "client error", ctx: context.Background(), err: testStatusError{code: http.StatusBadRequest}, want: false},
+		{name: "no credential", ctx: context.Background(), err: &Error{Code: "auth_not_found", Message: "no auth available"}, want: false},
+		{name: "unknown provider", ctx: context.Background(), err: &Error{Code: "provider_not_found", Message: "no provider supplied"}, want: false},
+		{name: "untyped error", ctx: context.Background(), err: errors.New("translate request"), want: false},
+		{name: "transport timeout", ctx: context.Background(), err: &url.Error{Op: "Post", URL: "https://example.com", Err: testTimeoutError{}}, want: true},
+		{name: "attempt deadline", ctx: context.Background(), err: context.DeadlineExceeded, want: true},
+		{name: "caller cancelled", ctx: context.Background(), err: context.Canceled, want: false},
+		{name: "caller context done", ctx: cancelled, err: testStatusError{code: http.StatusBadGateway}, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shouldFallback(tc.ctx, tc.err); got != tc.want {
+				t.Fatalf("shouldFallback() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExecuteFallbackChain(t *testing.T) {
+	testCases := []struct {
+		name      string
+		errs      map[string]error
+		filter    func(string) bool
+		wantModel string
+		wantCalls []string
+		wantErr   int
+	}{
+		{
+			name:      "primary answers",
+			wantModel: "fb-primary",
+			wantCalls: []string{"fb-primary"},
+		},
+		{
+			name:      "rate limit moves to the next model",
+			errs:      map[string]error{"fb-primary": testStatusError{code: http.StatusTooManyRequests}},
+			wantModel: "fb-secondary",
+			wantCalls: []string{"fb-primary", "fb-secondary"},
+		},
+		{
+			name: "server errors walk the whole chain",
+			errs: map[string]error{
+				"fb-primary":   testStatusError{code: http.StatusInternalServerError},
+				"fb-secondary": testStatusError{code: http.StatusServiceUnavailable},
+			},
+			wantModel: "fb-tertiary",
+			wantCalls: []string{"fb-primary", "fb-secondary", "fb-tertiary"},
+		},
+		{
+			name:      "client error does not fall back",
+			errs:      map[string]error{"fb-primary": testStatusError{code: http.StatusBadRequest}},
+			wantCalls: []string{"fb-primary"},
+			wantErr:   http.StatusBadRequest,
+		},
+		{
+			name:      "filter skips disallowed models",
+			errs:      map[string]error{"fb-primary": testStatusError{code: http.StatusTooManyRequests}},
+			filter:    func(model string) bool { return model != "fb-secondary" },
+			wantModel: "fb-tertiary",
+			wantCalls: []string{"fb-primary", "fb-tertiary"},
+		},
+		{
+			name: "last error is returned when the chain is exhausted",
+			errs: map[string]error{
+				"fb-primary":   testStatusError{code: http.StatusTooManyRequests},
+				"fb-secondary": testStatusError{code: http.StatusTooManyRequests},
+				"fb-tertiary":  testStatusError{code: http.StatusBadGateway},
+			},
+			wantCalls: []string{"fb-primary", "fb-secondary", "fb-tertiary"},
+			wantErr:   http.StatusBadGateway,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := fmt.Sprintf("fallback-test-%d", i)
+			manager, executor := newTestManager(t, provider, []string{"fb-primary", "fb-secondary", "fb-tertiary"}, tc.errs)
+			manager.SetRetryConfig(0, 0)
+			manager.SetModelFallbacks(map[string][]string{"fb-primary": {"fb-secondary", "fb-tertiary"}})
+
+			opts := cliproxyexecutor.Options{}
+			if tc.filter != nil {
+				opts.Metadata = map[string]any{FallbackFilterMetadataKey: tc.filter}
+			}
+			ctx, report := WithExecutionReport(context.Background())
+			resp, err := manager.Execute(ctx, []string{provider}, cliproxyexecutor.Request{Model: "fb-primary"}, opts)
+
+			if calls := executor.calls(); fmt.Sprint(calls) != fmt.Sprint(tc.wantCalls) {
+				t.Fatalf("executor calls = %v, want %v", calls, tc.wantCalls)
+			}
+			if tc.wantErr != 0 {
+				if got := statusCodeFromError(err); got != tc.wantErr {
+					t.Fatalf("Execute() error = %v (status %d), want status %d", err, got, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if string(resp.Payload) != tc.wantModel || report.Model != tc.wantModel {
+				t.Fatalf("served by %s (report %s), want %s", resp.Payload, report.Model, tc.wantModel)
+			}
+			if report.Fallback() != (tc.wantModel != "fb-primary") {
+				t.Fatalf("report.Fallback() = %v", report.Fallback())
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/sjson"
+)
+
+// Health check probe methods.
+const (
+	// HealthCheckGenerate probes with a one-token generation, which also detects exhausted quota.
+	HealthCheckGenerate = "generate"
+	// HealthCheckCountTokens probes with a token count request, which costs no quota.
+	HealthCheckCountTokens = "count-tokens"
+)
+
+const (
+	// DefaultHealthCheckInterval is the time between probes of one credential when no interval is configured.
+	DefaultHealthCheckInterval = 30 * time.Minute
+	// DefaultHealthCheckTimeout bounds a single probe when no timeout is configured.
+	DefaultHealthCheckTimeout = 30 * time.Second
+
+	healthCheckTick        = 15 * time.Second
+	healthCheckConcurrency = 4
+)
+
+// errHealthCheckSkipped reports that a credential cannot be probed, for example
+// because no model is registered for it yet.
+var errHealthCheckSkipped = errors.New("health check skipped")
+
+// HealthCheckConfig configures background probing of credentials.
+type HealthCheckConfig struct {
+	// Enabled turns the prober on.
+	Enabled bool
+	// Interval is the time between probes of one credential.
+	Interval time.Duration
+	// Timeout bounds a single probe.
+	Timeout time.Duration
+	// Providers overrides the settings for individual providers.
+	Providers map[string]ProviderHealthCheck
+}
+
+// ProviderHealthCheck overrides health checking for one provider.
+type ProviderHealthCheck struct {
+	// Disabled skips the provider's credentials.
+	Disabled bool
+	// Interval replaces HealthCheckConfig.Interval when positive.
+	Interval time.Duration
+	// Model is the model probed; empty uses the first model registered for the credential.
+	Model string
+	// Method is HealthCheckGenerate (default) or HealthCheckCountTokens.
+	Method string
+}
+
+// HealthStatus is the outcome of the latest probe of a credential.
+type HealthStatus struct {
+	Healthy             bool      `json:"healthy"`
+	Model               string    `json:"model,omitempty"`
+	Method              string    `json:"method"`
+	StatusCode          int       `json:"status_code,omitempty"`
+	Message             string    `json:"message,omitempty"`
+	LatencyMS           int64     `json:"latency_ms"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	CheckedAt           time.Time `json:"checked_at"`
+	NextCheckAt         time.Time `json:"next_check_at"`
+}
+
+// healthChecker keeps the prober configuration and the latest probe outcomes.
+type healthChecker struct {
+	mu       sync.Mutex
+	cfg      HealthCheckConfig
+	statuses map[string]*HealthStatus
+	running  map[string]bool
+	cancel   context.CancelFunc
+}
+
+func newHealthChecker() *healthChecker {
+	return &healthChecker{
+		cfg:      HealthCheckConfig{Interval: DefaultHealthCheckInterval, Timeout: DefaultHealthCheckTimeout},
+		statuses: make(map[string]*HealthStatus),
+		running:  make(map[string]bool),
+	}
+}
+
+// providerSettings resolves the effective probe settings for provider.
+func (h *healthChecker) providerSettings(provider string) (ProviderHealthCheck, time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	check := h.cfg.Providers[strings.ToLower(provider)]
+	if check.Interval <= 0 {
+		check.Interval = h.cfg.Interval
+	}
+	if check.Method == "" {
+		check.Method = HealthCheckGenerate
+	}
+	return check, h.cfg.Timeout
+}
+
+// SetHealthCheckConfig updates the prober settings. It takes effect on the next
+// tick of the loop started by StartHealthCheck.
+func (m *Manager) SetHealthCheckConfig(cfg HealthCheckConfig) {
+	if m == nil {
+		return
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHealthCheckInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultHealthCheckTimeout
+	}
+	providers := make(map[string]ProviderHealthCheck, len(cfg.Providers))
+	for provider, check := range cfg.Providers {
+		check.Method = strings.ToLower(strings.TrimSpace(check.Method))
+		check.Model = strings.TrimSpace(check.Model)
+		providers[strings.ToLower(strings.TrimSpace(provider))] = check
+	}
+	cfg.Providers = providers
+	h := m.health
+	h.mu.Lock()
+	h.cfg = cfg
+	h.mu.Unlock()
+}
+
+// StartHealthCheck launches the background loop that probes credentials whose
+// check is due. While health checking is disabled the loop only drops the rate
+// limits recorded for removed credentials. Starting a new loop cancels the
+// previous one.
+func (m *Manager) StartHealthCheck(parent context.Context) {
+	h := m.health
+	ctx, cancel := context.WithCancel(parent)
+	h.mu.Lock()
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.cancel = cancel
+	h.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(healthCheckTick)
+		defer ticker.Stop()
+		m.checkHealth(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+// StopHealthCheck cancels the background health check loop, if running.
+func (m *Manager) StopHealthCheck() {
+	h := m.health
+	h.mu.Lock()
+	if h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+	h.mu.Unlock()
+}
+
+// HealthStatus returns the outcome of the latest probe of the credential.
+func (m *Manager) HealthStatus(id string) (HealthStatus, bool) {
+	if m == nil {
+		return HealthStatus{}, false
+	}
+	h := m.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status, ok := h.statuses[id]
+	if !ok {
+		return HealthStatus{}, false
+	}
+	return *status, true
+}
+
+// ProbeAuth probes the credential immediately, records the outcome like a request
+// result and returns it. It works whether or not background checking is enabled.
+func (m *Manager) ProbeAuth(ctx context.Context, id string) (HealthStatus, error) {
+	auth, ok := m.GetByID(id)
+	if !ok {
+		return HealthStatus{}, &Error{Code: "auth_not_found", Message: fmt.Sprintf("auth %s not found", id), HTTPStatus: http.StatusNotFound}
+	}
+	h := m.health
+	h.mu.Lock()
+	if h.running[id] {
+		h.mu.Unlock()
+		return HealthStatus{}, &Error{Code: "probe_in_progress", Message: fmt.Sprintf("auth %s is already being probed", id), HTTPStatus: http.StatusConflict}
+	}
+	h.running[id] = true
+	h.mu.Unlock()
+	status, err := m.probeAuth(ctx, auth)
+	if errors.Is(err, errHealthCheckSkipped) {
+		err = &Error{Code: "probe_unavailable", Message: status.Message, HTTPStatus: http.StatusConflict}
+	}
+	return status, err
+}
+
+func (m *Manager) checkHealth(ctx context.Context) {
+	h := m.health
+	h.mu.Lock()
+	enabled := h.cfg.Enabled
+	h.mu.Unlock()
+	now := time.Now()
+	if !enabled {
+		m.rateLimits.prune(m.authIDs(), now)
+		return
+	}
+	snapshot := m.snapshotAuths()
+	known := make(map[string]struct{}, len(snapshot))
+	due := make([]*Auth, 0)
+	h.mu.Lock()
+	for _, auth := range snapshot {
+		known[auth.ID] = struct{}{}
+		if auth.Disabled || auth.Status == StatusDisabled || h.running[auth.ID] {
+			continue
+		}
+		if check := h.cfg.Providers[strings.ToLower(auth.Provider)]; check.Disabled {
+			continue
+		}
+		if status, ok := h.statuses[auth.ID]; ok && now.Before(status.NextCheckAt) {
+			continue
+		}
+		h.running[auth.ID] = true
+		due = append(due, auth)
+	}
+	for id := range h.statuses {
+		if _, ok := known[id]; !ok {
+			delete(h.statuses, id)
+		}
+	}
+	h.mu.Unlock()
+	m.rateLimits.prune(known, now)
+	if len(due) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, healthCheckConcurrency)
+	var wg sync.WaitGroup
+	for _, auth := range due {
+		select {
+		case <-ctx.Done():
+			h.mu.Lock()
+			delete(h.running, auth.ID)
+			h.mu.Unlock()
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(auth *Auth) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, _ = m.probeAuth(ctx, auth)
+		}(auth)
+	}
+	wg.Wait()
+}
+
+// probeAuth sends one minimal request with auth and records the outcome. The
+// caller must have marked auth as running.
+func (m *Manager) probeAuth(ctx context.Context, auth *Auth) (HealthStatus, error) {
+	h := m.health
+	defer func() {
+		h.mu.Lock()
+		delete(h.running, auth.ID)
+		h.mu.Unlock()
+	}()
+	check, timeout := h.providerSettings(auth.Provider)
+	status := HealthStatus{Method: check.Method, CheckedAt: time.Now()}
+	status.NextCheckAt = status.CheckedAt.Add(check.Interval)
+
+	executor := m.executorFor(auth.Provider)
+	model := check.Model
+	if model == "" {
+		if models := registry.GetGlobalRegistry().ClientModels(auth.ID); len(models) > 0 {
+			model = models[0]
+		}
+	}
+	if executor == nil || model == "" {
+		if executor == nil {
+			status.Message = fmt.Sprintf("no executor registered for provider %s", auth.Provider)
+		} else {
+			status.Message = "no model registered for this credential"
+		}
+		// Retry soon: the executor or the model list usually appears shortly after startup.
+		status.NextCheckAt = status.CheckedAt.Add(time.Minute)
+		h.mu.Lock()
+		h.statuses[auth.ID] = &status
+		h.mu.Unlock()
+		return status, errHealthCheckSkipped
+	}
+	status.Model = model
+
+	// Probes are the proxy's own traffic, so they are left out of usage statistics.
+	probeCtx, suppressUsage := coreusage.WithSuppression(m.withRoundTripper(m.withRateLimits(ctx), auth))
+	suppressUsage()
+	probeCtx, cancel := context.WithTimeout(probeCtx, timeout)
+	defer cancel()
+	payload := []byte(`{"messages":[{"role":"user","content":"ping"}],"max_tokens":1}`)
+	payload, _ = sjson.SetBytes(payload, "model", model)
+	req := cliproxyexecutor.Request{Model: model, Payload: payload}
+	opts := cliproxyexecutor.Options{OriginalRequest: payload, SourceFormat: sdktranslator.FromString("openai")}
+	var errExec error
+	if check.Method == HealthCheckCountTokens {
+		_, errExec = executor.CountTokens(probeCtx, auth, req, opts)
+	} else {
+		_, errExec = executor.Execute(probeCtx, auth, req, opts)
+	}
+	status.LatencyMS = time.Since(status.CheckedAt).Milliseconds()
+	// A probe cut short by shutdown says nothing about the credential.
+	if errExec != nil && ctx.Err() != nil {
+		return status, ctx.Err()
+	}
+
+	result := Result{AuthID: auth.ID, Provider: auth.Provider, Model: model, Success: errExec == nil}
+	h.mu.Lock()
+	previous := h.statuses[auth.ID]
+	if errExec == nil {
+		status.Healthy = true
+	} else {
+		status.StatusCode = statusCodeFromError(errExec)
+		status.Message = errExec.Error()
+		status.ConsecutiveFailures = 1
+		if previous != nil {
+			status.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+		}
+		result.Error = &Error{Message: errExec.Error(), HTTPStatus: status.StatusCode}
+		result.RetryAfter = retryAfterFromError(errExec)
+	}
+	h.statuses[auth.ID] = &status
+	h.mu.Unlock()
+
+	if errExec != nil {
+		log.Warnf("health check failed for %s auth %s (model %s): %v", auth.Provider, auth.ID, model, errExec)
+	} else if previous != nil && !previous.Healthy && previous.Model != "" {
+		log.Infof("health check recovered for %s auth %s", auth.Provider, auth.ID)
+	}
+	m.MarkResult(ctx, result)
+	return status, nil
+}
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+// HedgeMetadataKey is the Options.Metadata key holding a per-request hedge delay
+// (time.Duration). A non-positive value disables hedging for the request even when
+// the model is configured for it.
+const HedgeMetadataKey = "hedge_delay"
+
+// DefaultHedgeDelay is used for configured models when no delay is set.
+const DefaultHedgeDelay = 2 * time.Second
+
+// HedgeConfig enables hedged streaming for latency-critical models.
+type HedgeConfig struct {
+	// Delay is how long to wait for the first chunk before launching the hedge request.
+	Delay time.Duration
+	// Models lists the models hedged by default. A trailing "*" matches by prefix.
+	Models []string
+}
+
+// SetHedgeConfig replaces the hedged streaming configuration.
+func (m *Manager) SetHedgeConfig(cfg HedgeConfig) {
+	if m == nil {
+		return
+	}
+	if cfg.Delay <= 0 {
+		cfg.Delay = DefaultHedgeDelay
+	}
+	cfg.Models = append([]string(nil), cfg.Models...)
+	m.mu.Lock()
+	m.hedge = cfg
+	m.mu.Unlock()
+}
+
+// hedgeDelay returns the delay after which a hedge request is launched, or 0 when
+// the request is not hedged.
+func (m *Manager) hedgeDelay(model string, opts cliproxyexecutor.Options) time.Duration {
+	if routing := routingFromOptions(opts); routing != nil && (routing.NoRetry || routing.AuthID != "") {
+		return 0
+	}
+	if raw, ok := opts.Metadata[HedgeMetadataKey]; ok {
+		if delay, okDelay := raw.(time.Duration); okDelay && delay > 0 {
+			return delay
+		}
+		return 0
+	}
+	m.mu.RLock()
+	cfg := m.hedge
+	m.mu.RUnlock()
+	for _, pattern := range cfg.Models {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == model || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(model, strings.TrimSuffix(pattern, "*"))) {
+			return cfg.Delay
+		}
+	}
+	return 0
+}
+
+// hedgeGroup coordinates the branches of one hedged request so each branch uses
+// credentials the other has not claimed.
+type hedgeGroup struct {
+	mu      sync.Mutex
+	claimed map[string]*hedgeBranch
+}
+
+// hedgeBranch is one of the parallel attempts of a hedged request.
+type hedgeBranch struct {
+	group    *hedgeGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	suppress func()
+	report   *ExecutionReport
+	lost     atomic.Bool
+}
+
+type hedgeBranchContextKey struct{}
+
+func (g *hedgeGroup) newBranch(parent context.Context) *hedgeBranch {
+	b := &hedgeBranch{group: g}
+	ctx, cancel := context.WithCancel(parent)
+	ctx, b.suppress = coreusage.WithSuppression(ctx)
+	ctx, b.report = WithExecutionReport(ctx)
+	b.ctx = context.WithValue(ctx, hedgeBranchContextKey{}, b)
+	b.cancel = cancel
+	return b
+}
+
+func hedgeBranchFromContext(ctx context.Context) *hedgeBranch {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(hedgeBranchContextKey{}).(*hedgeBranch)
+	return b
+}
+
+// excludeSiblings adds auths claimed by other branches to tried.
+func (b *hedgeBranch) excludeSiblings(tried map[string]struct{}) {
+	if b == nil {
+		return
+	}
+	b.group.mu.Lock()
+	for id, owner := range b.group.claimed {
+		if owner != b {
+			tried[id] = struct{}{}
+		}
+	}
+	b.group.mu.Unlock()
+}
+
+func (b *hedgeBranch) claim(authID string) {
+	if b == nil {
+		return
+	}
+	b.group.mu.Lock()
+	if _, exists := b.group.claimed[authID]; !exists {
+		b.group.claimed[authID] = b
+	}
+	b.group.mu.Unlock()
+}
+
+// isLost reports whether the branch lost the race; its results must not affect auth state.
+func (b *hedgeBranch) isLost() bool { return b != nil && b.lost.Load() }
+
+// lose cancels the branch and drops any usage it still reports, so only the
+// winner's usage is recorded. The losing attempt remains visible to execution
+// hooks, which count it in the upstream request metrics.
+func (b *hedgeBranch) lose() {
+	b.lost.Store(true)
+	b.suppress()
+	b.cancel()
+}
+
+type hedgeResult struct {
+	branch *hedgeBranch
+	first  cliproxyexecutor.StreamChunk
+	ok     bool
+	rest   <-chan cliproxyexecutor.StreamChunk
+	err    error
+}
+
+func (r hedgeResult) succeeded() bool { return r.err == nil && (!r.ok || r.first.Err == nil) }
+
+// discard cancels a branch whose result is not used and drains its stream.
+func (r hedgeResult) discard() {
+	r.branch.lose()
+	if r.rest != nil {
+		go func() {
+			for range r.rest {
+			}
+		}()
+	}
+}
+
+// executeStreamHedged races the stream against a second attempt launched on a
+// different credential when no first chunk arrives within delay. The first branch
+// to produce a successful chunk wins; the other is cancelled and its usage dropped.
+func (m *Manager) executeStreamHedged(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, delay time.Duration) (<-chan cliproxyexecutor.StreamChunk, error) {
+	const maxBranches = 2
+	group := &hedgeGroup{claimed: make(map[string]*hedgeBranch)}
+	results := make(chan hedgeResult, maxBranches)
+	branches := make([]*hedgeBranch, 0, maxBranches)
+	launch := func() {
+		branch := group.newBranch(ctx)
+		branches = append(branches, branch)
+		go func() {
+			res := hedgeResult{branch: branch}
+			res.rest, res.err = m.executeStreamChain(branch.ctx, providers, req, opts)
+			if res.err == nil {
+				res.first, res.ok = <-res.rest
+			}
+			results <- res
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending := 1
+	var failed *hedgeResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if len(branches) < maxBranches {
+				log.Debugf("hedging stream for model %s after %s without first chunk", req.Model, delay)
+				launch()
+				pending++
+			}
+		case res := <-results:
+			pending--
+			if res.succeeded() {
+				for _, branch := range branches {
+					if branch != res.branch {
+						branch.lose()
+					}
+				}
+				if failed != nil {
+					failed.discard()
+				}
+				drainHedgeResults(results, pending)
+				return m.finishHedge(ctx, res), nil
+			}
+			if failed == nil {
+				failed = &res
+			} else {
+				res.discard()
+			}
+			// A stream that failed on its first chunk may still be rescued by a hedge.
+			if res.err == nil && len(branches) < maxBranches {
+				launch()
+				pending++
+			}
+		case <-ctx.Done():
+			for _, branch := range branches {
+				branch.lose()
+			}
+			if failed != nil {
+				failed.discard()
+			}
+			drainHedgeResults(results, pending)
+			return nil, ctx.Err()
+		}
+	}
+	if failed.err != nil {
+		failed.branch.cancel()
+		return nil, failed.err
+	}
+	return m.finishHedge(ctx, *failed), nil
+}
+
+// drainHedgeResults discards the results of branches still in flight.
+func drainHedgeResults(results <-chan hedgeResult, pending int) {
+	if pending <= 0 {
+		return
+	}
+	go func() {
+		for i := 0; i < pending; i++ {
+			(<-results).discard()
+		}
+	}()
+}
+
+// finishHedge forwards the selected branch's stream and copies its execution report.
+func (m *Manager) finishHedge(ctx context.Context, res hedgeResult) <-chan cliproxyexecutor.StreamChunk {
+	if res.branch.report.Model != "" {
+		reportServedModel(ctx, res.branch.report.RequestedModel, res.branch.report.Model)
+	}
+	if res.branch.report.AuthID != "" {
+		if report, ok := ctx.Value(executionReportContextKey{}).(*ExecutionReport); ok && report != nil {
+			report.Provider, report.AuthID = res.branch.report.Provider, res.branch.report.AuthID
+		}
+	}
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		defer res.branch.cancel()
+		if !res.ok {
+			return
+		}
+		out <- res.first
+		for chunk := range res.rest {
+			out <- chunk
+		}
+	}()
+	return out
+}
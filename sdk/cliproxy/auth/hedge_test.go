@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+// hedgeTestExecutor streams one chunk per call after the delay configured for the
+// call's position, and reports usage like a real executor once the stream ends.
+type hedgeTestExecutor struct {
+	*testExecutor
+	delays   []time.Duration
+	firstErr map[int]error
+
+	mu        sync.Mutex
+	auths     []string
+	cancelled []string
+	streams   sync.WaitGroup
+}
+
+func (e *hedgeTestExecutor) ExecuteStream(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	e.mu.Lock()
+	call := len(e.auths)
+	e.auths = append(e.auths, auth.ID)
+	e.mu.Unlock()
+	var delay time.Duration
+	if call < len(e.delays) {
+		delay = e.delays[call]
+	}
+
+	out := make(chan cliproxyexecutor.StreamChunk, 1)
+	e.streams.Add(1)
+	go func() {
+		defer e.streams.Done()
+		defer close(out)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			e.mu.Lock()
+			e.cancelled = append(e.cancelled, auth.ID)
+			e.mu.Unlock()
+			// Tokens consumed before the cancellation are still reported.
+			coreusage.PublishRecord(ctx, coreusage.Record{Provider: e.provider, AuthID: auth.ID, Model: req.Model, Failed: true})
+			return
+		}
+		if err := e.firstErr[call]; err != nil {
+			out <- cliproxyexecutor.StreamChunk{Err: err}
+			return
+		}
+		out <- cliproxyexecutor.StreamChunk{Payload: []byte(auth.ID)}
+		coreusage.PublishRecord(ctx, coreusage.Record{Provider: e.provider, AuthID: auth.ID, Model: req.Model})
+	}()
+	return out, nil
+}
+
+func (e *hedgeTestExecutor) snapshot() (auths, cancelled []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.auths...), append([]string(nil), e.cancelled...)
+}
+
+// usageCollector keeps the usage records published through the default usage manager.
+type usageCollector struct {
+	mu      sync.Mutex
+	records []coreusage.Record
+}
+
+func (c *usageCollector) HandleUsage(_ context.Context, record coreusage.Record) {
+	c.mu.Lock()
+	c.records = append(c.records, record)
+	c.mu.Unlock()
+}
+
+var (
+	testUsage         = &usageCollector{}
+	registerTestUsage sync.Once
+)
+
+// collectUsage returns the auth IDs of the usage records published for provider.
+// A sentinel record flushes the asynchronous usage queue first.
+func collectUsage(t *testing.T, provider string) []string {
+	t.Helper()
+	coreusage.PublishRecord(context.Background(), coreusage.Record{Provider: provider, AuthID: "sentinel"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var auths []string
+		flushed := false
+		testUsage.mu.Lock()
+		for _, record := range testUsage.records {
+			if record.Provider != provider {
+				continue
+			}
+			if record.AuthID == "sentinel" {
+				flushed = true
+				continue
+			}
+			auths = append(auths, record.AuthID)
+		}
+		testUsage.mu.Unlock()
+		if flushed {
+			return auths
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("usage records for %s were not delivered", provider)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExecuteStreamHedged(t *testing.T) {
+	registerTestUsage.Do(func() { coreusage.RegisterPlugin(testUsage) })
+
+	testCases := []struct {
+		name          string
+		auths         int
+		hedgeDelay    time.Duration
+		delays        []time.Duration
+		firstErr      map[int]error
+		wantCalls     int
+		wantWinner    int
+		wantCancelled int
+	}{
+		{
+			name:       "primary answers before the hedge delay",
+			auths:      2,
+			hedgeDelay: time.Second,
+			delays:     []time.Duration{0},
+			wantCalls:  1,
+			wantWinner: 0,
+		},
+		{
+			name:          "first chunk wins and the slow branch is cancelled",
+			auths:         2,
+			hedgeDelay:    20 * time.Millisecond,
+			delays:        []time.Duration{5 * time.Second, 0},
+			wantCalls:     2,
+			wantWinner:    1,
+			wantCancelled: 1,
+		},
+		{
+			name:       "hedge rescues a failed first chunk",
+			auths:      2,
+			hedgeDelay: time.Second,
+			delays:     []time.Duration{0, 0},
+			firstErr:   map[int]error{0: testStatusError{code: http.StatusServiceUnavailable}},
+			wantCalls:  2,
+			wantWinner: 1,
+		},
+		{
+			name:       "no credential for the hedge keeps the primary",
+			auths:      1,
+			hedgeDelay: 10 * time.Millisecond,
+			delays:     []time.Duration{50 * time.Millisecond},
+			wantCalls:  1,
+			wantWinner: 0,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Usage records outlive the test, so every run needs its own provider.
+			provider := fmt.Sprintf("hedge-test-%d-%d", i, time.Now().UnixNano())
+			manager, base := newTestManager(t, provider, []string{"hedge-model"}, nil)
+			manager.SetRetryConfig(0, 0)
+			executor := &hedgeTestExecutor{testExecutor: base, delays: tc.delays, firstErr: tc.firstErr}
+			manager.RegisterExecutor(executor)
+			for n := 1; n < tc.auths; n++ {
+				addTestAuth(t, manager, provider, fmt.Sprintf("%s-auth-%d", provider, n), "hedge-model")
+			}
+
+			opts := cliproxyexecutor.Options{Stream: true, Metadata: map[string]any{HedgeMetadataKey: tc.hedgeDelay}}
+			ctx, report := WithExecutionReport(context.Background())
+			chunks, err := manager.ExecuteStream(ctx, []string{provider}, cliproxyexecutor.Request{Model: "hedge-model"}, opts)
+			if err != nil {
+				t.Fatalf("ExecuteStream() error = %v", err)
+			}
+			var payloads []string
+			for chunk := range chunks {
+				if chunk.Err != nil {
+					t.Fatalf("stream chunk error = %v", chunk.Err)
+				}
+				payloads = append(payloads, string(chunk.Payload))
+			}
+			executor.streams.Wait()
+
+			auths, cancelled := executor.snapshot()
+			if len(auths) != tc.wantCalls {
+				t.Fatalf("executor called for %v, want %d calls", auths, tc.wantCalls)
+			}
+			if len(auths) == 2 && auths[0] == auths[1] {
+				t.Fatalf("both branches used credential %s", auths[0])
+			}
+			winner := auths[tc.wantWinner]
+			if len(payloads) != 1 || payloads[0] != winner {
+				t.Fatalf("stream payloads = %v, want [%s]", payloads, winner)
+			}
+			if report.AuthID != winner {
+				t.Fatalf("report.AuthID = %s, want %s", report.AuthID, winner)
+			}
+			if len(cancelled) != tc.wantCancelled {
+				t.Fatalf("cancelled branches = %v, want %d", cancelled, tc.wantCancelled)
+			}
+			if usage := collectUsage(t, provider); len(usage) != 1 || usage[0] != winner {
+				t.Fatalf("usage recorded for %v, want only the winner %s", usage, winner)
+			}
+		})
+	}
+}
+
+// addTestAuth registers another credential of provider serving models.
+func addTestAuth(t *testing.T, manager *Manager, provider, id string, models ...string) {
+	t.Helper()
+	if _, err := manager.Register(context.Background(), &Auth{ID: id, Provider: provider, Status: StatusActive}); err != nil {
+		t.Fatalf("failed to register auth: %v", err)
+	}
+	infos := make([]*registry.ModelInfo, 0, len(models))
+	for _, model := range models {
+		infos = append(infos, &registry.ModelInfo{ID: model, Object: "model", OwnedBy: provider})
+	}
+	registry.GetGlobalRegistry().RegisterClient(id, provider, infos)
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// ImageExecutor is implemented by provider executors that can generate or edit images.
+// The request payload is an OpenAI images/generations body; edit requests additionally
+// carry their source images inline as an "images" array of {"mime_type","data"} objects
+// and an optional "mask" object. The response payload is an OpenAI images response.
+type ImageExecutor interface {
+	GenerateImage(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error)
+}
+
+// ExecuteImage generates images using the configured selector and executor.
+// Only providers whose executor implements ImageExecutor are considered; credential
+// rotation, cooldowns and retries behave as they do for Execute.
+func (m *Manager) ExecuteImage(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	supports := func(executor ProviderExecutor) bool {
+		_, ok := executor.(ImageExecutor)
+		return ok
+	}
+	unsupported := &Error{
+		Code:       "images_not_supported",
+		Message:    fmt.Sprintf("model %s does not support image generation", req.Model),
+		HTTPStatus: http.StatusBadRequest,
+	}
+	return m.executeCapability(ctx, providers, req, opts, supports, unsupported, func(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+		return executor.(ImageExecutor).GenerateImage(ctx, auth, req, opts)
+	})
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -15,6 +16,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -113,6 +115,27 @@ type Manager struct {
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
+	// executionHooks observe and mutate provider attempts.
+	executionHooks []ExecutionHook
+
+	// circuits tracks shared health of custom upstream endpoints.
+	circuits *circuitBreakers
+
+	// modelFallbacks maps a model to the ordered models tried when it cannot serve a request.
+	modelFallbacks map[string][]string
+
+	// hedge configures hedged streaming for latency-critical models.
+	hedge HedgeConfig
+
+	// responseCache serves repeated deterministic requests without calling upstream.
+	responseCache *responseCache
+
+	// health probes credentials in the background and keeps the latest outcomes.
+	health *healthChecker
+
+	// rateLimits holds the upstream limits reported per credential and model.
+	rateLimits *rateLimits
+
 	// Auto refresh state
 	refreshCancel context.CancelFunc
 }
@@ -132,6 +155,10 @@ func NewManager(store Store, selector Selector, hook Hook) *Manager {
 		hook:            hook,
 		auths:           make(map[string]*Auth),
 		providerOffsets: make(map[string]int),
+		circuits:        newCircuitBreakers(),
+		responseCache:   newResponseCache(),
+		health:          newHealthChecker(),
+		rateLimits:      newRateLimits(),
 	}
 }
 
@@ -244,17 +271,74 @@ func (m *Manager) Load(ctx context.Context) error {
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When the model has a configured fallback chain, retryable failures move on to the next model.
 func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	ctx = coreusage.WithRequestedModel(ctx, req.Model)
+	key, cacheable := m.responseCacheKey(req, opts)
+	if !cacheable {
+		return m.executeChain(ctx, providers, req, opts)
+	}
+	if entry, hit := m.responseCache.get(key); hit {
+		serveCachedResponse(ctx, req, entry)
+		return cliproxyexecutor.Response{Payload: bytes.Clone(entry.payload)}, nil
+	}
+	ctx, report := executionReport(ctx)
+	resp, err := m.executeChain(ctx, providers, req, opts)
+	if err == nil {
+		entry := &cachedResponse{info: newCacheEntryInfo(req, opts), servedModel: servedModel(report, req.Model), payload: bytes.Clone(resp.Payload)}
+		entry.info.Bytes = len(entry.payload)
+		m.responseCache.put(key, entry)
+	}
+	return resp, err
+}
+
+// executeChain executes req through the model and its fallback chain.
+func (m *Manager) executeChain(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	chain := m.fallbackChain(req.Model)
+	var lastErr error
+	for i, model := range chain {
+		attemptProviders, attemptReq := providers, req
+		if i > 0 {
+			if !shouldFallback(ctx, lastErr) || routingFromOptions(opts).noRetry() {
+				break
+			}
+			if !fallbackAllowed(opts, model) {
+				log.Debugf("model fallback: skipping %s, not allowed for this request", model)
+				continue
+			}
+			if attemptProviders = util.GetProviderName(model); len(attemptProviders) == 0 {
+				continue
+			}
+			attemptReq.Model = model
+			log.Debugf("model fallback: %s -> %s after error: %v", req.Model, model, lastErr)
+		}
+		resp, errExec := m.executeModel(ctx, attemptProviders, attemptReq, opts, len(chain) == 1)
+		if errExec == nil {
+			reportServedModel(ctx, req.Model, model)
+			return resp, nil
+		}
+		lastErr = errExec
+	}
+	return cliproxyexecutor.Response{}, lastErr
+}
+
+// executeModel runs the retry loop for a single model across its providers.
+// waitCooldown allows sleeping until a cooling credential becomes available.
+func (m *Manager) executeModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, waitCooldown bool) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	normalized, errRoute := m.routeProviders(normalized, opts)
+	if errRoute != nil {
+		return cliproxyexecutor.Response{}, errRoute
+	}
 	rotated := m.rotateProviders(req.Model, normalized)
 	defer m.advanceProviderCursor(req.Model, normalized)
 
 	retryTimes, maxWait := m.retrySettings()
 	attempts := retryTimes + 1
-	if attempts < 1 {
+	if attempts < 1 || routingFromOptions(opts).noRetry() {
 		attempts = 1
 	}
 
@@ -268,7 +352,7 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 		}
 		lastErr = errExec
 		wait, shouldRetry := m.shouldRetryAfterError(errExec, attempt, attempts, rotated, req.Model, maxWait)
-		if !shouldRetry {
+		if !shouldRetry || (!waitCooldown && wait > 0) {
 			break
 		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
@@ -288,12 +372,16 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	normalized, errRoute := m.routeProviders(normalized, opts)
+	if errRoute != nil {
+		return cliproxyexecutor.Response{}, errRoute
+	}
 	rotated := m.rotateProviders(req.Model, normalized)
 	defer m.advanceProviderCursor(req.Model, normalized)
 
 	retryTimes, maxWait := m.retrySettings()
 	attempts := retryTimes + 1
-	if attempts < 1 {
+	if attempts < 1 || routingFromOptions(opts).noRetry() {
 		attempts = 1
 	}
 
@@ -322,17 +410,80 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// Model fallbacks apply only until the upstream stream has been established. Hedged models
+// race a second attempt when the first chunk is slow to arrive.
 func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	ctx = coreusage.WithRequestedModel(ctx, req.Model)
+	key, cacheable := m.responseCacheKey(req, opts)
+	if !cacheable {
+		return m.executeStreamUncached(ctx, providers, req, opts)
+	}
+	if entry, hit := m.responseCache.get(key); hit {
+		serveCachedResponse(ctx, req, entry)
+		return replayCachedStream(entry), nil
+	}
+	ctx, report := executionReport(ctx)
+	chunks, err := m.executeStreamUncached(ctx, providers, req, opts)
+	if err != nil {
+		return nil, err
+	}
+	return m.cacheStream(ctx, key, req, opts, report, chunks), nil
+}
+
+// executeStreamUncached streams req upstream, hedging it when configured.
+func (m *Manager) executeStreamUncached(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	if delay := m.hedgeDelay(req.Model, opts); delay > 0 {
+		return m.executeStreamHedged(ctx, providers, req, opts, delay)
+	}
+	return m.executeStreamChain(ctx, providers, req, opts)
+}
+
+// executeStreamChain streams req through the model and its fallback chain.
+func (m *Manager) executeStreamChain(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	chain := m.fallbackChain(req.Model)
+	var lastErr error
+	for i, model := range chain {
+		attemptProviders, attemptReq := providers, req
+		if i > 0 {
+			if !shouldFallback(ctx, lastErr) || routingFromOptions(opts).noRetry() {
+				break
+			}
+			if !fallbackAllowed(opts, model) {
+				log.Debugf("model fallback: skipping %s, not allowed for this request", model)
+				continue
+			}
+			if attemptProviders = util.GetProviderName(model); len(attemptProviders) == 0 {
+				continue
+			}
+			attemptReq.Model = model
+			log.Debugf("model fallback: %s -> %s after error: %v", req.Model, model, lastErr)
+		}
+		chunks, errStream := m.executeStreamModel(ctx, attemptProviders, attemptReq, opts, len(chain) == 1)
+		if errStream == nil {
+			reportServedModel(ctx, req.Model, model)
+			return chunks, nil
+		}
+		lastErr = errStream
+	}
+	return nil, lastErr
+}
+
+// executeStreamModel runs the streaming retry loop for a single model across its providers.
+func (m *Manager) executeStreamModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, waitCooldown bool) (<-chan cliproxyexecutor.StreamChunk, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	normalized, errRoute := m.routeProviders(normalized, opts)
+	if errRoute != nil {
+		return nil, errRoute
+	}
 	rotated := m.rotateProviders(req.Model, normalized)
 	defer m.advanceProviderCursor(req.Model, normalized)
 
 	retryTimes, maxWait := m.retrySettings()
 	attempts := retryTimes + 1
-	if attempts < 1 {
+	if attempts < 1 || routingFromOptions(opts).noRetry() {
 		attempts = 1
 	}
 
@@ -346,7 +497,7 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 		}
 		lastErr = errStream
 		wait, shouldRetry := m.shouldRetryAfterError(errStream, attempt, attempts, rotated, req.Model, maxWait)
-		if !shouldRetry {
+		if !shouldRetry || (!waitCooldown && wait > 0) {
 			break
 		}
 		if errWait := waitForCooldown(ctx, wait); errWait != nil {
@@ -366,7 +517,7 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
+		auth, executor, probe, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -382,12 +533,16 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 		}
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
-		}
-		resp, errExec := executor.Execute(execCtx, auth, req, opts)
+		execCtx := m.withRoundTripper(m.withRateLimits(ctx), auth)
+		execAuth, execReq, execOpts := auth, req, opts
+		execCtx, execution, hooks := m.beginExecution(execCtx, provider, auth, req, opts)
+		if execution != nil {
+			execAuth, execReq, execOpts = execution.Auth, execution.Request, execution.Options
+		}
+		finishAttempt := m.beginAttempt(auth, req.Model, probe)
+		resp, errExec := executor.Execute(execCtx, execAuth, execReq, execOpts)
+		finishAttempt(errExec)
+		finishExecution(execCtx, execution, hooks, resp, errExec)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: req.Model, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -403,18 +558,31 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		reportAuth(ctx, provider, auth)
 		return resp, nil
 	}
 }
 
 func (m *Manager) executeCountWithProvider(ctx context.Context, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return m.executeCallWithProvider(ctx, provider, req, opts, func(execCtx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+		return executor.CountTokens(execCtx, auth, req, opts)
+	})
+}
+
+// providerCall performs one auxiliary executor call (token counting, embeddings, images)
+// with the selected auth and the request and options as adjusted by execution hooks.
+type providerCall func(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error)
+
+// executeCallWithProvider rotates through the provider's auths until call succeeds,
+// recording each outcome. Execution hooks see every attempt as they do for Execute.
+func (m *Manager) executeCallWithProvider(ctx context.Context, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, call providerCall) (cliproxyexecutor.Response, error) {
 	if provider == "" {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
 	tried := make(map[string]struct{})
 	var lastErr error
 	for {
-		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
+		auth, executor, probe, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return cliproxyexecutor.Response{}, lastErr
@@ -430,12 +598,16 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 		}
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
-		}
-		resp, errExec := executor.CountTokens(execCtx, auth, req, opts)
+		execCtx := m.withRoundTripper(m.withRateLimits(ctx), auth)
+		execAuth, execReq, execOpts := auth, req, opts
+		execCtx, execution, hooks := m.beginExecution(execCtx, provider, auth, req, opts)
+		if execution != nil {
+			execAuth, execReq, execOpts = execution.Auth, execution.Request, execution.Options
+		}
+		finishAttempt := m.beginAttempt(auth, req.Model, probe)
+		resp, errExec := call(execCtx, executor, execAuth, execReq, execOpts)
+		finishAttempt(errExec)
+		finishExecution(execCtx, execution, hooks, resp, errExec)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: req.Model, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -451,18 +623,74 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 			continue
 		}
 		m.MarkResult(execCtx, result)
+		reportAuth(ctx, provider, auth)
 		return resp, nil
 	}
 }
 
+// executeCapability runs call for an optional executor capability (embeddings, images)
+// across the providers whose executor passes supports. unsupported is returned when no
+// provider qualifies. Credential rotation, cooldowns and retries behave as they do for Execute.
+func (m *Manager) executeCapability(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, supports func(ProviderExecutor) bool, unsupported *Error, call providerCall) (cliproxyexecutor.Response, error) {
+	ctx = coreusage.WithRequestedModel(ctx, req.Model)
+	normalized, errRoute := m.routeProviders(m.normalizeProviders(providers), opts)
+	if errRoute != nil {
+		return cliproxyexecutor.Response{}, errRoute
+	}
+	supported := make([]string, 0, len(normalized))
+	for _, provider := range normalized {
+		if executor := m.executorFor(provider); executor != nil && supports(executor) {
+			supported = append(supported, provider)
+		}
+	}
+	if len(supported) == 0 {
+		return cliproxyexecutor.Response{}, unsupported
+	}
+	rotated := m.rotateProviders(req.Model, supported)
+	defer m.advanceProviderCursor(req.Model, supported)
+
+	retryTimes, maxWait := m.retrySettings()
+	attempts := retryTimes + 1
+	if attempts < 1 || routingFromOptions(opts).noRetry() {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		resp, errExec := m.executeProvidersOnce(ctx, rotated, func(execCtx context.Context, provider string) (cliproxyexecutor.Response, error) {
+			return m.executeCallWithProvider(execCtx, provider, req, opts, call)
+		})
+		if errExec == nil {
+			return resp, nil
+		}
+		lastErr = errExec
+		wait, shouldRetry := m.shouldRetryAfterError(errExec, attempt, attempts, rotated, req.Model, maxWait)
+		if !shouldRetry {
+			break
+		}
+		if errWait := waitForCooldown(ctx, wait); errWait != nil {
+			return cliproxyexecutor.Response{}, errWait
+		}
+	}
+	if lastErr != nil {
+		return cliproxyexecutor.Response{}, lastErr
+	}
+	return cliproxyexecutor.Response{}, &Error{Code: "auth_not_found", Message: "no auth available"}
+}
+
 func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
 	if provider == "" {
 		return nil, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
 	tried := make(map[string]struct{})
+	branch := hedgeBranchFromContext(ctx)
 	var lastErr error
 	for {
-		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
+		if branch.isLost() {
+			return nil, context.Canceled
+		}
+		branch.excludeSiblings(tried)
+		auth, executor, probe, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
 		if errPick != nil {
 			if lastErr != nil {
 				return nil, lastErr
@@ -478,13 +706,21 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 		}
 
 		tried[auth.ID] = struct{}{}
-		execCtx := ctx
-		if rt := m.roundTripperFor(auth); rt != nil {
-			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
-			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
-		}
-		chunks, errStream := executor.ExecuteStream(execCtx, auth, req, opts)
+		branch.claim(auth.ID)
+		execCtx := m.withRoundTripper(m.withRateLimits(ctx), auth)
+		execAuth, execReq, execOpts := auth, req, opts
+		execCtx, execution, hooks := m.beginExecution(execCtx, provider, auth, req, opts)
+		if execution != nil {
+			execAuth, execReq, execOpts = execution.Auth, execution.Request, execution.Options
+		}
+		finishAttempt := m.beginAttempt(auth, req.Model, probe)
+		chunks, errStream := executor.ExecuteStream(execCtx, execAuth, execReq, execOpts)
 		if errStream != nil {
+			finishAttempt(errStream)
+			finishExecution(execCtx, execution, hooks, cliproxyexecutor.Response{}, errStream)
+			if branch.isLost() {
+				return nil, errStream
+			}
 			rerr := &Error{Message: errStream.Error()}
 			var se cliproxyexecutor.StatusError
 			if errors.As(errStream, &se) && se != nil {
@@ -500,22 +736,42 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
 			defer close(out)
 			var failed bool
+			var streamErr error
+		forward:
 			for chunk := range streamChunks {
+				observeStreamChunk(streamCtx, execution, hooks, chunk)
 				if chunk.Err != nil && !failed {
 					failed = true
+					streamErr = chunk.Err
 					rerr := &Error{Message: chunk.Err.Error()}
 					var se cliproxyexecutor.StatusError
 					if errors.As(chunk.Err, &se) && se != nil {
 						rerr.HTTPStatus = se.StatusCode()
 					}
-					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: false, Error: rerr})
+					if !branch.isLost() {
+						m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: false, Error: rerr})
+					}
+				}
+				select {
+				case out <- chunk:
+				case <-streamCtx.Done():
+					// The caller stopped reading; drain the upstream so the attempt
+					// still finishes and its hooks run.
+					for range streamChunks {
+					}
+					if streamErr == nil {
+						streamErr = streamCtx.Err()
+					}
+					break forward
 				}
-				out <- chunk
 			}
-			if !failed {
+			finishAttempt(streamErr)
+			finishExecution(streamCtx, execution, hooks, cliproxyexecutor.Response{}, streamErr)
+			if !failed && !branch.isLost() {
 				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: true})
 			}
 		}(execCtx, auth.Clone(), provider, chunks)
+		reportAuth(ctx, provider, auth)
 		return out, nil
 	}
 }
@@ -666,6 +922,9 @@ func (m *Manager) executeProvidersOnce(ctx context.Context, providers []string,
 		if errExec == nil {
 			return resp, nil
 		}
+		if lastErr != nil && isRoutingStop(errExec) {
+			break
+		}
 		lastErr = errExec
 	}
 	if lastErr != nil {
@@ -684,6 +943,9 @@ func (m *Manager) executeStreamProvidersOnce(ctx context.Context, providers []st
 		if errExec == nil {
 			return chunks, nil
 		}
+		if lastErr != nil && isRoutingStop(errExec) {
+			break
+		}
 		lastErr = errExec
 	}
 	if lastErr != nil {
@@ -1081,16 +1343,22 @@ func (m *Manager) GetByID(id string) (*Auth, bool) {
 	return auth.Clone(), true
 }
 
-func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
+func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, bool, error) {
+	routing := routingFromOptions(opts)
+	if routing.noRetry() && routing.attempted.Load() {
+		return nil, nil, false, errRoutingAttempted
+	}
 	m.mu.RLock()
 	executor, okExecutor := m.executors[provider]
 	if !okExecutor {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "executor_not_found", Message: "executor not registered"}
+		return nil, nil, false, &Error{Code: "executor_not_found", Message: "executor not registered"}
 	}
 	candidates := make([]*Auth, 0, len(m.auths))
+	var tripped, throttled []*Auth
 	modelKey := strings.TrimSpace(model)
 	registryRef := registry.GetGlobalRegistry()
+	now := time.Now()
 	for _, candidate := range m.auths {
 		if candidate.Provider != provider || candidate.Disabled {
 			continue
@@ -1098,27 +1366,83 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if routing.excludes(candidate.ID) {
+			continue
+		}
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
+		// A pinned credential skips the circuit breaker and cooldowns below.
+		if routing != nil && routing.AuthID == candidate.ID {
+			candidates = append(candidates, candidate)
+			break
+		}
+		if !m.circuits.admits(candidate, now) {
+			tripped = append(tripped, candidate)
+			continue
+		}
+		if low, _ := m.rateLimits.lowUntil(candidate.ID, modelKey, now); low {
+			throttled = append(throttled, candidate)
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
+	// Credentials close to an upstream limit are only used when nothing else is left.
 	if len(candidates) == 0 {
-		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
+		candidates = throttled
 	}
-	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
-	if errPick != nil {
+	if len(candidates) == 0 {
 		m.mu.RUnlock()
-		return nil, nil, errPick
+		if len(tripped) > 0 {
+			return nil, nil, false, newCircuitOpenError(m.circuits.retryAt(tripped), now)
+		}
+		return nil, nil, false, &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	if selected == nil {
-		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+	selected := candidates[0]
+	probe := false
+	if routing == nil || routing.AuthID == "" {
+		for {
+			var errPick error
+			selected, errPick = m.selector.Pick(ctx, provider, model, opts, candidates)
+			if errPick != nil {
+				m.mu.RUnlock()
+				return nil, nil, false, errPick
+			}
+			if selected == nil {
+				m.mu.RUnlock()
+				return nil, nil, false, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+			}
+			// Another request may have taken the last half-open probe slot since
+			// the candidates were filtered.
+			var admitted bool
+			if probe, admitted = m.circuits.allow(selected, now); admitted {
+				break
+			}
+			tripped = append(tripped, selected)
+			candidates = removeAuth(candidates, selected)
+			if len(candidates) == 0 {
+				m.mu.RUnlock()
+				return nil, nil, false, newCircuitOpenError(m.circuits.retryAt(tripped), now)
+			}
+		}
+	}
+	if routing.noRetry() {
+		routing.attempted.Store(true)
 	}
 	authCopy := selected.Clone()
 	m.mu.RUnlock()
-	return authCopy, executor, nil
+	return authCopy, executor, probe, nil
+}
+
+// removeAuth returns auths without target, preserving order.
+func removeAuth(auths []*Auth, target *Auth) []*Auth {
+	out := make([]*Auth, 0, len(auths))
+	for _, auth := range auths {
+		if auth != target {
+			out = append(out, auth)
+		}
+	}
+	return out
 }
 
 func (m *Manager) persist(ctx context.Context, auth *Auth) error {
@@ -1209,6 +1533,17 @@ func (m *Manager) snapshotAuths() []*Auth {
 	return out
 }
 
+// authIDs returns the IDs of every registered auth.
+func (m *Manager) authIDs() map[string]struct{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make(map[string]struct{}, len(m.auths))
+	for id := range m.auths {
+		ids[id] = struct{}{}
+	}
+	return ids
+}
+
 func (m *Manager) shouldRefresh(a *Auth, now time.Time) bool {
 	if a == nil || a.Disabled {
 		return false
@@ -1478,15 +1813,39 @@ func (m *Manager) executorFor(provider string) ProviderExecutor {
 // roundTripperContextKey is an unexported context key type to avoid collisions.
 type roundTripperContextKey struct{}
 
-// roundTripperFor retrieves an HTTP RoundTripper for the given auth if a provider is registered.
-func (m *Manager) roundTripperFor(auth *Auth) http.RoundTripper {
+// forcedRoundTripperContextKey carries a RoundTripper executors must use even when
+// a proxy is configured.
+type forcedRoundTripperContextKey struct{}
+
+// ForcedRoundTripperFromContext returns the RoundTripper that must carry every
+// upstream request of the attempt, ahead of any configured proxy, or nil when the
+// executor may choose its transport.
+func ForcedRoundTripperFromContext(ctx context.Context) http.RoundTripper {
+	if ctx == nil {
+		return nil
+	}
+	rt, _ := ctx.Value(forcedRoundTripperContextKey{}).(http.RoundTripper)
+	return rt
+}
+
+// withRoundTripper attaches the RoundTripper the registered provider returns for auth.
+func (m *Manager) withRoundTripper(ctx context.Context, auth *Auth) context.Context {
 	m.mu.RLock()
 	p := m.rtProvider
 	m.mu.RUnlock()
 	if p == nil || auth == nil {
-		return nil
+		return ctx
 	}
-	return p.RoundTripperFor(auth)
+	rt := p.RoundTripperFor(auth)
+	if rt == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, roundTripperContextKey{}, rt)
+	ctx = context.WithValue(ctx, "cliproxy.roundtripper", rt)
+	if forcing, ok := p.(ForcingRoundTripperProvider); ok && forcing.ForcesRoundTripper() {
+		ctx = context.WithValue(ctx, forcedRoundTripperContextKey{}, rt)
+	}
+	return ctx
 }
 
 // RoundTripperProvider defines a minimal provider of per-auth HTTP transports.
@@ -1494,6 +1853,14 @@ type RoundTripperProvider interface {
 	RoundTripperFor(auth *Auth) http.RoundTripper
 }
 
+// ForcingRoundTripperProvider is a RoundTripperProvider whose transports must see
+// every upstream exchange regardless of proxy settings, such as record and replay
+// transports. Executors find them with ForcedRoundTripperFromContext.
+type ForcingRoundTripperProvider interface {
+	RoundTripperProvider
+	ForcesRoundTripper() bool
+}
+
 // RequestPreparer is an optional interface that provider executors can implement
 // to mutate outbound HTTP requests with provider credentials.
 type RequestPreparer interface {
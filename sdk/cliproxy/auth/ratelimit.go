@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit window units.
+const (
+	RateLimitUnitRequests = "requests"
+	RateLimitUnitTokens   = "tokens"
+	// RateLimitUnitPercent marks windows reported as a share of an opaque allowance,
+	// such as Codex usage windows; Limit is then 100.
+	RateLimitUnitPercent = "percent"
+)
+
+const (
+	// DefaultRateLimitMinRemaining is the share of a limit below which a credential
+	// is avoided when no threshold is configured.
+	DefaultRateLimitMinRemaining = 0.05
+
+	// rateLimitUndatedTTL is how long a window without a reset time is trusted.
+	rateLimitUndatedTTL = time.Minute
+)
+
+// RateLimitWindow is one upstream limit as reported in response headers.
+type RateLimitWindow struct {
+	// Name identifies the limit, e.g. "requests", "input-tokens", "5h" or "primary".
+	Name string `json:"name"`
+	// Unit is RateLimitUnitRequests, RateLimitUnitTokens or RateLimitUnitPercent.
+	Unit      string `json:"unit"`
+	Limit     int64  `json:"limit"`
+	Remaining int64  `json:"remaining"`
+	// WindowMinutes is the length of the window when the upstream reports it.
+	WindowMinutes int `json:"window_minutes,omitempty"`
+	// ResetAt is when the window refills; zero when unknown.
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// RateLimitState is the latest set of limits reported for one credential and model.
+type RateLimitState struct {
+	Windows   []RateLimitWindow `json:"windows"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// RateLimitConfig controls how reported limits influence credential selection.
+type RateLimitConfig struct {
+	// DisableAvoidance keeps routing to credentials whose reported quota is nearly exhausted.
+	DisableAvoidance bool
+	// MinRemaining is the share (0-1) of any limit below which a credential is
+	// skipped while other credentials remain.
+	MinRemaining float64
+}
+
+// rateLimits keeps the limits reported by executors, keyed by auth ID and model.
+// Limits that apply to the whole account are stored under the empty model.
+type rateLimits struct {
+	mu     sync.RWMutex
+	cfg    RateLimitConfig
+	states map[string]map[string]*RateLimitState
+}
+
+func newRateLimits() *rateLimits {
+	return &rateLimits{
+		cfg:    RateLimitConfig{MinRemaining: DefaultRateLimitMinRemaining},
+		states: make(map[string]map[string]*RateLimitState),
+	}
+}
+
+type rateLimitContextKey struct{}
+
+// withRateLimits lets executors called with the returned context report limits.
+func (m *Manager) withRateLimits(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateLimitContextKey{}, m.rateLimits)
+}
+
+// RecordRateLimits stores the limits an upstream reported for the credential.
+// Executors call it with the context they were given; model is empty for limits
+// that cover the whole account. Windows replace earlier ones of the same name.
+func RecordRateLimits(ctx context.Context, authID, model string, windows []RateLimitWindow) {
+	if ctx == nil || authID == "" || len(windows) == 0 {
+		return
+	}
+	tracker, ok := ctx.Value(rateLimitContextKey{}).(*rateLimits)
+	if !ok || tracker == nil {
+		return
+	}
+	tracker.record(authID, strings.TrimSpace(model), windows, time.Now())
+}
+
+func (r *rateLimits) record(authID, model string, windows []RateLimitWindow, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	models := r.states[authID]
+	if models == nil {
+		models = make(map[string]*RateLimitState)
+		r.states[authID] = models
+	}
+	state := models[model]
+	if state == nil {
+		state = &RateLimitState{}
+		models[model] = state
+	}
+	for _, window := range windows {
+		replaced := false
+		for i := range state.Windows {
+			if state.Windows[i].Name == window.Name {
+				state.Windows[i] = window
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			state.Windows = append(state.Windows, window)
+		}
+	}
+	state.UpdatedAt = now
+}
+
+// lowUntil reports whether any current window of the credential for model, or of
+// the whole account, has less than the configured share left, and when the
+// earliest such window refills.
+func (r *rateLimits) lowUntil(authID, model string, now time.Time) (bool, time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cfg.DisableAvoidance {
+		return false, time.Time{}
+	}
+	models := r.states[authID]
+	if models == nil {
+		return false, time.Time{}
+	}
+	low := false
+	var resetAt time.Time
+	for _, key := range []string{model, ""} {
+		state := models[key]
+		if state == nil {
+			continue
+		}
+		for _, window := range state.Windows {
+			if !window.current(state.UpdatedAt, now) || window.Limit <= 0 {
+				continue
+			}
+			if float64(window.Remaining) >= float64(window.Limit)*r.cfg.MinRemaining && window.Remaining > 0 {
+				continue
+			}
+			low = true
+			if resetAt.IsZero() || (!window.ResetAt.IsZero() && window.ResetAt.Before(resetAt)) {
+				resetAt = window.ResetAt
+			}
+		}
+		if model == "" {
+			break
+		}
+	}
+	return low, resetAt
+}
+
+// current reports whether the window still describes the upstream state.
+func (w RateLimitWindow) current(updatedAt, now time.Time) bool {
+	if w.ResetAt.IsZero() {
+		return now.Sub(updatedAt) < rateLimitUndatedTTL
+	}
+	return now.Before(w.ResetAt)
+}
+
+// prune drops the limits of credentials not in known and windows that no longer
+// describe the upstream state.
+func (r *rateLimits) prune(known map[string]struct{}, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for authID, models := range r.states {
+		if _, ok := known[authID]; !ok {
+			delete(r.states, authID)
+			continue
+		}
+		for model, state := range models {
+			current := state.Windows[:0]
+			for _, window := range state.Windows {
+				if window.current(state.UpdatedAt, now) {
+					current = append(current, window)
+				}
+			}
+			state.Windows = current
+			if len(current) == 0 {
+				delete(models, model)
+			}
+		}
+		if len(models) == 0 {
+			delete(r.states, authID)
+		}
+	}
+}
+
+func (r *rateLimits) snapshot(authID string) map[string]RateLimitState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	models := r.states[authID]
+	if len(models) == 0 {
+		return nil
+	}
+	out := make(map[string]RateLimitState, len(models))
+	for model, state := range models {
+		out[model] = RateLimitState{
+			Windows:   append([]RateLimitWindow(nil), state.Windows...),
+			UpdatedAt: state.UpdatedAt,
+		}
+	}
+	return out
+}
+
+// SetRateLimitConfig updates how reported limits influence credential selection.
+func (m *Manager) SetRateLimitConfig(cfg RateLimitConfig) {
+	if m == nil {
+		return
+	}
+	if cfg.MinRemaining <= 0 || cfg.MinRemaining >= 1 {
+		cfg.MinRemaining = DefaultRateLimitMinRemaining
+	}
+	m.rateLimits.mu.Lock()
+	m.rateLimits.cfg = cfg
+	m.rateLimits.mu.Unlock()
+}
+
+// RateLimits returns the limits last reported for the credential, keyed by model.
+// Limits covering the whole account are listed under the empty model.
+func (m *Manager) RateLimits(authID string) map[string]RateLimitState {
+	if m == nil {
+		return nil
+	}
+	return m.rateLimits.snapshot(authID)
+}
+
+// RateLimitLow reports whether the credential is being avoided for model because
+// a reported limit is nearly exhausted, and when that limit refills.
+func (m *Manager) RateLimitLow(authID, model string) (bool, time.Time) {
+	if m == nil {
+		return false, time.Time{}
+	}
+	return m.rateLimits.lowUntil(authID, model, time.Now())
+}
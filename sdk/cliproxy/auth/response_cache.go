@@ -0,0 +1,371 @@
+package auth
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
+)
+
+// ResponseCacheMetadataKey is the Options.Metadata key holding a per-request cache
+// preference (bool). true caches the request even when it is not deterministic;
+// false bypasses the cache. It has no effect while the cache is disabled.
+const ResponseCacheMetadataKey = "response_cache"
+
+// ResponseCacheScopeMetadataKey is the Options.Metadata key holding the client
+// identity (string) that cached responses are scoped to, so one client never
+// receives a response cached for another.
+const ResponseCacheScopeMetadataKey = "response_cache_scope"
+
+const (
+	// DefaultResponseCacheTTL is how long entries live when no TTL is configured.
+	DefaultResponseCacheTTL = time.Hour
+	// DefaultResponseCacheEntries caps the number of entries when no limit is configured.
+	DefaultResponseCacheEntries = 1000
+	// DefaultResponseCacheBytes caps the total cached payload size when no limit is configured.
+	DefaultResponseCacheBytes = 64 << 20
+	// DefaultResponseCacheEntryBytes caps a single cached response when no limit is configured.
+	DefaultResponseCacheEntryBytes = 1 << 20
+)
+
+// ResponseCacheConfig configures the cache of deterministic responses.
+type ResponseCacheConfig struct {
+	// Enabled turns the cache on. Only requests with temperature 0 are cached unless
+	// the caller opts in through ResponseCacheMetadataKey.
+	Enabled bool
+	// TTL is how long an entry is served.
+	TTL time.Duration
+	// MaxEntries caps the number of cached responses; the least recently used are evicted.
+	MaxEntries int
+	// MaxBytes caps the total size of cached payloads.
+	MaxBytes int64
+	// MaxEntryBytes caps the size of a single cached response; larger ones are not stored.
+	MaxEntryBytes int
+}
+
+// ResponseCacheEntry describes one cached response.
+type ResponseCacheEntry struct {
+	Key          string    `json:"key"`
+	Model        string    `json:"model"`
+	SourceFormat string    `json:"source_format"`
+	Stream       bool      `json:"stream"`
+	Bytes        int       `json:"bytes"`
+	Hits         int64     `json:"hits"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// ResponseCacheStats is a snapshot of the response cache.
+type ResponseCacheStats struct {
+	Enabled       bool                 `json:"enabled"`
+	TTLSeconds    int64                `json:"ttl_seconds"`
+	MaxEntries    int                  `json:"max_entries"`
+	MaxBytes      int64                `json:"max_bytes"`
+	MaxEntryBytes int                  `json:"max_entry_bytes"`
+	Entries       int                  `json:"entries"`
+	Bytes         int64                `json:"bytes"`
+	Hits          int64                `json:"hits"`
+	Misses        int64                `json:"misses"`
+	Items         []ResponseCacheEntry `json:"items"`
+}
+
+// responseCache is an LRU of responses keyed by a canonical hash of the request.
+type responseCache struct {
+	mu     sync.Mutex
+	cfg    ResponseCacheConfig
+	items  map[string]*list.Element
+	lru    *list.List
+	bytes  int64
+	hits   int64
+	misses int64
+}
+
+type cachedResponse struct {
+	info ResponseCacheEntry
+	// servedModel is the model that produced the response, reported again on hits.
+	servedModel string
+	payload     []byte
+	chunks      [][]byte
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{items: make(map[string]*list.Element), lru: list.New()}
+}
+
+// SetResponseCacheConfig replaces the response cache configuration. Disabling the
+// cache or lowering its limits drops the entries that no longer fit.
+func (m *Manager) SetResponseCacheConfig(cfg ResponseCacheConfig) {
+	if m == nil {
+		return
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultResponseCacheTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultResponseCacheEntries
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultResponseCacheBytes
+	}
+	if cfg.MaxEntryBytes <= 0 {
+		cfg.MaxEntryBytes = DefaultResponseCacheEntryBytes
+	}
+	c := m.responseCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg = cfg
+	if !cfg.Enabled {
+		c.clearLocked()
+		return
+	}
+	c.evictLocked()
+}
+
+// ResponseCacheStats returns the cache counters and its live entries, most recently used first.
+func (m *Manager) ResponseCacheStats() ResponseCacheStats {
+	c := m.responseCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := ResponseCacheStats{
+		Enabled:       c.cfg.Enabled,
+		TTLSeconds:    int64(c.cfg.TTL / time.Second),
+		MaxEntries:    c.cfg.MaxEntries,
+		MaxBytes:      c.cfg.MaxBytes,
+		MaxEntryBytes: c.cfg.MaxEntryBytes,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Items:         make([]ResponseCacheEntry, 0, c.lru.Len()),
+	}
+	now := time.Now()
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(*cachedResponse)
+		if now.After(entry.info.ExpiresAt) {
+			continue
+		}
+		stats.Items = append(stats.Items, entry.info)
+		stats.Bytes += int64(entry.info.Bytes)
+	}
+	stats.Entries = len(stats.Items)
+	return stats
+}
+
+// FlushResponseCache drops every cached response and returns how many were removed.
+func (m *Manager) FlushResponseCache() int {
+	c := m.responseCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.lru.Len()
+	c.clearLocked()
+	return n
+}
+
+// responseCacheKey returns the cache key for a request, or false when the request
+// must not be cached. The key covers the client scope, source format, model, streaming
+// mode and the canonical form of the payload, which fully determines the translated
+// upstream request.
+func (m *Manager) responseCacheKey(req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (string, bool) {
+	c := m.responseCache
+	c.mu.Lock()
+	enabled := c.cfg.Enabled
+	c.mu.Unlock()
+	// Routed requests exist to reach a particular credential, so they always go upstream.
+	if !enabled || routingFromOptions(opts) != nil {
+		return "", false
+	}
+	if force, ok := opts.Metadata[ResponseCacheMetadataKey].(bool); ok {
+		if !force {
+			return "", false
+		}
+	} else if !deterministicRequest(req.Payload) {
+		return "", false
+	}
+	canonical, ok := canonicalJSON(req.Payload)
+	if !ok {
+		return "", false
+	}
+	scope, _ := opts.Metadata[ResponseCacheScopeMetadataKey].(string)
+	h := sha256.New()
+	for _, part := range []string{scope, opts.SourceFormat.String(), req.Model, boolString(opts.Stream), opts.Alt} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// deterministicRequest reports whether the payload pins temperature to 0 in any of
+// the supported request schemas.
+func deterministicRequest(payload []byte) bool {
+	for _, path := range []string{"temperature", "generationConfig.temperature", "request.generationConfig.temperature"} {
+		if value := gjson.GetBytes(payload, path); value.Exists() {
+			return value.Type == gjson.Number && value.Float() == 0
+		}
+	}
+	return false
+}
+
+// canonicalJSON re-encodes payload with sorted object keys and no insignificant whitespace.
+func canonicalJSON(payload []byte) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// get returns a live entry and counts the lookup as a hit or miss.
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if ok {
+		entry := el.Value.(*cachedResponse)
+		if time.Now().Before(entry.info.ExpiresAt) {
+			entry.info.Hits++
+			c.hits++
+			c.lru.MoveToFront(el)
+			return entry, true
+		}
+		c.removeLocked(el)
+	}
+	c.misses++
+	return nil, false
+}
+
+// put stores a response unless the cache was disabled meanwhile or it is too large.
+func (c *responseCache) put(key string, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.cfg.Enabled || entry.info.Bytes > c.cfg.MaxEntryBytes {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+	now := time.Now()
+	entry.info.Key = key
+	entry.info.CreatedAt = now
+	entry.info.ExpiresAt = now.Add(c.cfg.TTL)
+	c.items[key] = c.lru.PushFront(entry)
+	c.bytes += int64(entry.info.Bytes)
+	c.evictLocked()
+}
+
+func (c *responseCache) evictLocked() {
+	for c.lru.Len() > 0 && (c.lru.Len() > c.cfg.MaxEntries || c.bytes > c.cfg.MaxBytes) {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+func (c *responseCache) removeLocked(el *list.Element) {
+	entry := c.lru.Remove(el).(*cachedResponse)
+	delete(c.items, entry.info.Key)
+	c.bytes -= int64(entry.info.Bytes)
+}
+
+func (c *responseCache) clearLocked() {
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+// executionReport returns the report attached to ctx, attaching a new one when the
+// caller did not, so the cache can learn which model answered.
+func executionReport(ctx context.Context) (context.Context, *ExecutionReport) {
+	if report, ok := ctx.Value(executionReportContextKey{}).(*ExecutionReport); ok && report != nil {
+		return ctx, report
+	}
+	return WithExecutionReport(ctx)
+}
+
+// serveCachedResponse reports a cache hit to the caller and the usage plugins.
+// No tokens are recorded since the upstream was not called.
+func serveCachedResponse(ctx context.Context, req cliproxyexecutor.Request, entry *cachedResponse) {
+	if report, ok := ctx.Value(executionReportContextKey{}).(*ExecutionReport); ok && report != nil {
+		report.CacheHit = true
+	}
+	reportServedModel(ctx, req.Model, entry.servedModel)
+	coreusage.PublishRecord(ctx, coreusage.Record{
+		Model:       entry.servedModel,
+		RequestedAt: time.Now(),
+		CacheHit:    true,
+	})
+}
+
+// replayCachedStream emits the stored chunks of a cached streaming response.
+func replayCachedStream(entry *cachedResponse) <-chan cliproxyexecutor.StreamChunk {
+	out := make(chan cliproxyexecutor.StreamChunk, len(entry.chunks))
+	for _, chunk := range entry.chunks {
+		out <- cliproxyexecutor.StreamChunk{Payload: bytes.Clone(chunk)}
+	}
+	close(out)
+	return out
+}
+
+// cacheStream forwards chunks unchanged and stores the stream once it completes
+// without error. Streams cut short by the client or larger than an entry may be are
+// not stored.
+func (m *Manager) cacheStream(ctx context.Context, key string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, report *ExecutionReport, in <-chan cliproxyexecutor.StreamChunk) <-chan cliproxyexecutor.StreamChunk {
+	c := m.responseCache
+	c.mu.Lock()
+	limit := c.cfg.MaxEntryBytes
+	c.mu.Unlock()
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		entry := &cachedResponse{info: newCacheEntryInfo(req, opts)}
+		storable := true
+		for chunk := range in {
+			if chunk.Err != nil {
+				storable = false
+			}
+			if storable {
+				entry.info.Bytes += len(chunk.Payload)
+				if entry.info.Bytes > limit {
+					storable = false
+					entry.chunks = nil
+				} else {
+					entry.chunks = append(entry.chunks, bytes.Clone(chunk.Payload))
+				}
+			}
+			out <- chunk
+		}
+		if storable && ctx.Err() == nil {
+			entry.servedModel = servedModel(report, req.Model)
+			c.put(key, entry)
+		}
+	}()
+	return out
+}
+
+func newCacheEntryInfo(req cliproxyexecutor.Request, opts cliproxyexecutor.Options) ResponseCacheEntry {
+	return ResponseCacheEntry{Model: req.Model, SourceFormat: opts.SourceFormat.String(), Stream: opts.Stream}
+}
+
+func servedModel(report *ExecutionReport, requested string) string {
+	if report != nil && report.Model != "" {
+		return report.Model
+	}
+	return requested
+}
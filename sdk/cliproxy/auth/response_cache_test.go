@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	manager.SetResponseCacheConfig(ResponseCacheConfig{Enabled: true})
+
+	baseReq := cliproxyexecutor.Request{Model: "cache-model", Payload: []byte(`{"model":"cache-model","temperature":0,"messages":[{"role":"user","content":"hi"}]}`)}
+	baseOpts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Metadata: map[string]any{ResponseCacheScopeMetadataKey: "client-a"}}
+	baseKey, ok := manager.responseCacheKey(baseReq, baseOpts)
+	if !ok {
+		t.Fatalf("responseCacheKey() did not cache a deterministic request")
+	}
+
+	testCases := []struct {
+		name          string
+		payload       string
+		model         string
+		opts          cliproxyexecutor.Options
+		wantCacheable bool
+		wantSameKey   bool
+	}{
+		{
+			name:          "reordered keys and whitespace",
+			payload:       `{ "messages": [{"content":"hi","role":"user"}], "temperature": 0, "model": "cache-model" }`,
+			wantCacheable: true,
+			wantSameKey:   true,
+		},
+		{
+			name:          "different prompt",
+			payload:       `{"model":"cache-model","temperature":0,"messages":[{"role":"user","content":"bye"}]}`,
+			wantCacheable: true,
+		},
+		{
+			name:          "different model",
+			model:         "cache-model-2",
+			wantCacheable: true,
+		},
+		{
+			name:          "different client scope",
+			opts:          cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Metadata: map[string]any{ResponseCacheScopeMetadataKey: "client-b"}},
+			wantCacheable: true,
+		},
+		{
+			name:          "different source format",
+			opts:          cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude, Metadata: map[string]any{ResponseCacheScopeMetadataKey: "client-a"}},
+			wantCacheable: true,
+		},
+		{
+			name:          "streaming",
+			opts:          cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Stream: true, Metadata: map[string]any{ResponseCacheScopeMetadataKey: "client-a"}},
+			wantCacheable: true,
+		},
+		{
+			name:    "non-zero temperature",
+			payload: `{"model":"cache-model","temperature":0.7,"messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:    "temperature not set",
+			payload: `{"model":"cache-model","messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:          "gemini generation config",
+			payload:       `{"contents":[],"generationConfig":{"temperature":0}}`,
+			wantCacheable: true,
+		},
+		{
+			name:          "caller opts in",
+			payload:       `{"model":"cache-model","temperature":1,"messages":[]}`,
+			opts:          cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Metadata: map[string]any{ResponseCacheMetadataKey: true}},
+			wantCacheable: true,
+		},
+		{
+			name: "caller opts out",
+			opts: cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Metadata: map[string]any{ResponseCacheMetadataKey: false}},
+		},
+		{
+			name: "routed request",
+			opts: cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Metadata: map[string]any{RoutingMetadataKey: &Routing{AuthID: "cache-auth"}}},
+		},
+		{
+			name:    "invalid json",
+			payload: `{"temperature":0,`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, opts := baseReq, baseOpts
+			if tc.payload != "" {
+				req.Payload = []byte(tc.payload)
+			}
+			if tc.model != "" {
+				req.Model = tc.model
+			}
+			if tc.opts.Metadata != nil {
+				opts = tc.opts
+			}
+			key, cacheable := manager.responseCacheKey(req, opts)
+			if cacheable != tc.wantCacheable {
+				t.Fatalf("responseCacheKey() cacheable = %v, want %v", cacheable, tc.wantCacheable)
+			}
+			if cacheable && (key == baseKey) != tc.wantSameKey {
+				t.Fatalf("responseCacheKey() same key = %v, want %v", key == baseKey, tc.wantSameKey)
+			}
+		})
+	}
+}
+
+func TestResponseCacheKeyDisabled(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	req := cliproxyexecutor.Request{Model: "cache-model", Payload: []byte(`{"temperature":0}`)}
+	if _, ok := manager.responseCacheKey(req, cliproxyexecutor.Options{}); ok {
+		t.Fatalf("responseCacheKey() cached a request while the cache is disabled")
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      ResponseCacheConfig
+		sizes    []int
+		touch    []int
+		wantKeys []int
+	}{
+		{
+			name:     "within limits",
+			cfg:      ResponseCacheConfig{MaxEntries: 3, MaxBytes: 100, MaxEntryBytes: 50},
+			sizes:    []int{10, 10, 10},
+			wantKeys: []int{0, 1, 2},
+		},
+		{
+			name:     "entry limit evicts the oldest",
+			cfg:      ResponseCacheConfig{MaxEntries: 2, MaxBytes: 100, MaxEntryBytes: 50},
+			sizes:    []int{10, 10, 10},
+			wantKeys: []int{1, 2},
+		},
+		{
+			name:     "byte limit evicts the oldest",
+			cfg:      ResponseCacheConfig{MaxEntries: 10, MaxBytes: 50, MaxEntryBytes: 50},
+			sizes:    []int{20, 20, 20},
+			wantKeys: []int{1, 2},
+		},
+		{
+			name:     "reads keep an entry alive",
+			cfg:      ResponseCacheConfig{MaxEntries: 2, MaxBytes: 100, MaxEntryBytes: 50},
+			sizes:    []int{10, 10, 10},
+			touch:    []int{0},
+			wantKeys: []int{0, 2},
+		},
+		{
+			name:     "oversized entry is not stored",
+			cfg:      ResponseCacheConfig{MaxEntries: 10, MaxBytes: 100, MaxEntryBytes: 15},
+			sizes:    []int{10, 20, 10},
+			wantKeys: []int{0, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := NewManager(nil, nil, nil)
+			tc.cfg.Enabled = true
+			manager.SetResponseCacheConfig(tc.cfg)
+			c := manager.responseCache
+
+			for i, size := range tc.sizes {
+				if i == len(tc.sizes)-1 {
+					for _, j := range tc.touch {
+						if _, ok := c.get(fmt.Sprintf("key-%d", j)); !ok {
+							t.Fatalf("get(key-%d) missed before eviction", j)
+						}
+					}
+				}
+				c.put(fmt.Sprintf("key-%d", i), &cachedResponse{info: ResponseCacheEntry{Bytes: size}, payload: make([]byte, size)})
+			}
+
+			stats := manager.ResponseCacheStats()
+			var wantBytes int64
+			for _, i := range tc.wantKeys {
+				wantBytes += int64(tc.sizes[i])
+			}
+			if stats.Entries != len(tc.wantKeys) || stats.Bytes != wantBytes || c.bytes != wantBytes {
+				t.Fatalf("cache holds %d entries, %d bytes (counter %d), want %d entries, %d bytes", stats.Entries, stats.Bytes, c.bytes, len(tc.wantKeys), wantBytes)
+			}
+			for _, i := range tc.wantKeys {
+				if _, ok := c.items[fmt.Sprintf("key-%d", i)]; !ok {
+					t.Fatalf("key-%d was evicted", i)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	manager.SetResponseCacheConfig(ResponseCacheConfig{Enabled: true, TTL: time.Minute})
+	c := manager.responseCache
+
+	c.put("fresh", &cachedResponse{info: ResponseCacheEntry{Bytes: 5}, payload: []byte("fresh")})
+	c.put("stale", &cachedResponse{info: ResponseCacheEntry{Bytes: 5}, payload: []byte("stale")})
+	c.items["stale"].Value.(*cachedResponse).info.ExpiresAt = time.Now().Add(-time.Second)
+
+	if stats := manager.ResponseCacheStats(); stats.Entries != 1 {
+		t.Fatalf("ResponseCacheStats() reports %d live entries, want 1", stats.Entries)
+	}
+	if _, ok := c.get("stale"); ok {
+		t.Fatalf("get() served an expired entry")
+	}
+	if _, ok := c.items["stale"]; ok || c.bytes != 5 {
+		t.Fatalf("expired entry was not removed on lookup (bytes %d)", c.bytes)
+	}
+	if _, ok := c.get("fresh"); !ok {
+		t.Fatalf("get() missed a live entry")
+	}
+	if stats := manager.ResponseCacheStats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("hits = %d, misses = %d, want 1 and 1", stats.Hits, stats.Misses)
+	}
+
+	manager.SetResponseCacheConfig(ResponseCacheConfig{Enabled: false})
+	if stats := manager.ResponseCacheStats(); stats.Entries != 0 || c.bytes != 0 {
+		t.Fatalf("disabling the cache kept %d entries", stats.Entries)
+	}
+}
+
+func TestExecuteServesCachedResponse(t *testing.T) {
+	manager, executor := newTestManager(t, "response-cache-test", []string{"cache-exec-model"}, nil)
+	manager.SetResponseCacheConfig(ResponseCacheConfig{Enabled: true})
+
+	req := cliproxyexecutor.Request{Model: "cache-exec-model", Payload: []byte(`{"temperature":0}`)}
+	for i := 0; i < 3; i++ {
+		resp, err := manager.Execute(context.Background(), []string{"response-cache-test"}, req, cliproxyexecutor.Options{})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if string(resp.Payload) != "cache-exec-model" {
+			t.Fatalf("Execute() payload = %s", resp.Payload)
+		}
+	}
+	if calls := executor.calls(); len(calls) != 1 {
+		t.Fatalf("executor called %d times, want 1", len(calls))
+	}
+	if stats := manager.ResponseCacheStats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatalf("hits = %d, misses = %d, want 2 and 1", stats.Hits, stats.Misses)
+	}
+}
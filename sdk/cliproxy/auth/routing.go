@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// RoutingMetadataKey is the Options.Metadata key holding a *Routing that overrides
+// provider and credential selection for one request.
+const RoutingMetadataKey = "routing"
+
+// Routing pins or restricts the credentials used for a single request. It is meant
+// for operators debugging a specific account; a Routing must not be shared
+// between requests.
+type Routing struct {
+	// Provider restricts the request to one provider.
+	Provider string
+	// AuthID pins the request to one credential, which is used even while it is
+	// cooling down or its circuit is open.
+	AuthID string
+	// ExcludeAuths lists credentials that must not be used.
+	ExcludeAuths []string
+	// NoRetry limits the request to a single upstream attempt: no other credential,
+	// provider, retry, fallback model or hedge follows a failure.
+	NoRetry bool
+
+	attempted atomic.Bool
+}
+
+// errRoutingAttempted stops credential rotation once a NoRetry request has made its attempt.
+var errRoutingAttempted = &Error{Code: "no_retry", Message: "request routing allows a single attempt", HTTPStatus: http.StatusServiceUnavailable}
+
+func routingFromOptions(opts cliproxyexecutor.Options) *Routing {
+	routing, _ := opts.Metadata[RoutingMetadataKey].(*Routing)
+	return routing
+}
+
+// excludes reports whether the routing rules out the credential id.
+func (r *Routing) excludes(id string) bool {
+	if r == nil {
+		return false
+	}
+	if r.AuthID != "" && r.AuthID != id {
+		return true
+	}
+	for _, excluded := range r.ExcludeAuths {
+		if excluded == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Routing) noRetry() bool { return r != nil && r.NoRetry }
+
+// routeProviders narrows providers to those the request's routing allows.
+func (m *Manager) routeProviders(providers []string, opts cliproxyexecutor.Options) ([]string, error) {
+	routing := routingFromOptions(opts)
+	if routing == nil {
+		return providers, nil
+	}
+	want := strings.ToLower(strings.TrimSpace(routing.Provider))
+	if routing.AuthID != "" {
+		m.mu.RLock()
+		pinned, ok := m.auths[routing.AuthID]
+		m.mu.RUnlock()
+		if !ok {
+			return nil, &Error{Code: "auth_not_found", Message: fmt.Sprintf("auth %s not found", routing.AuthID), HTTPStatus: http.StatusNotFound}
+		}
+		if want != "" && want != pinned.Provider {
+			return nil, &Error{Code: "invalid_routing", Message: fmt.Sprintf("auth %s belongs to provider %s, not %s", routing.AuthID, pinned.Provider, want), HTTPStatus: http.StatusBadRequest}
+		}
+		want = pinned.Provider
+	}
+	if want == "" {
+		return providers, nil
+	}
+	for _, provider := range providers {
+		if provider == want {
+			return []string{want}, nil
+		}
+	}
+	return nil, &Error{Code: "provider_not_found", Message: fmt.Sprintf("provider %s does not serve this model", want), HTTPStatus: http.StatusBadRequest}
+}
+
+// isRoutingStop reports whether err only signals that a NoRetry request has used its attempt.
+func isRoutingStop(err error) bool {
+	return errors.Is(err, errRoutingAttempted)
+}
+
+// reportAuth records the credential that served the request.
+func reportAuth(ctx context.Context, provider string, auth *Auth) {
+	if ctx == nil || auth == nil {
+		return
+	}
+	if report, ok := ctx.Value(executionReportContextKey{}).(*ExecutionReport); ok && report != nil {
+		report.Provider = provider
+		report.AuthID = auth.ID
+	}
+}
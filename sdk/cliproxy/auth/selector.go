@@ -102,14 +102,39 @@ func (e *modelCooldownError) Headers() http.Header {
 func (s *RoundRobinSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
 	_ = ctx
 	_ = opts
-	if len(auths) == 0 {
-		return nil, &Error{Code: "auth_not_found", Message: "no auth candidates"}
+	available, err := availableAuthsForModel(provider, model, auths, time.Now())
+	if err != nil {
+		return nil, err
 	}
+	return s.next(provider+":"+model, available), nil
+}
+
+// next returns the next auth from available using the cursor stored under key.
+func (s *RoundRobinSelector) next(key string, available []*Auth) *Auth {
+	s.mu.Lock()
 	if s.cursors == nil {
 		s.cursors = make(map[string]int)
 	}
+	index := s.cursors[key]
+
+	if index >= 2_147_483_640 {
+		index = 0
+	}
+
+	s.cursors[key] = index + 1
+	s.mu.Unlock()
+	// log.Debugf("available: %d, index: %d, key: %d", len(available), index, index%len(available))
+	return available[index%len(available)]
+}
+
+// availableAuthsForModel filters auths down to those not blocked for model, sorted by ID
+// so selection is deterministic even if the caller's candidate order is unstable.
+// When every candidate is cooling down it returns a modelCooldownError.
+func availableAuthsForModel(provider, model string, auths []*Auth, now time.Time) ([]*Auth, error) {
+	if len(auths) == 0 {
+		return nil, &Error{Code: "auth_not_found", Message: "no auth candidates"}
+	}
 	available := make([]*Auth, 0, len(auths))
-	now := time.Now()
 	cooldownCount := 0
 	var earliest time.Time
 	for i := 0; i < len(auths); i++ {
@@ -136,22 +161,10 @@ func (s *RoundRobinSelector) Pick(ctx context.Context, provider, model string, o
 		}
 		return nil, &Error{Code: "auth_unavailable", Message: "no auth available"}
 	}
-	// Make round-robin deterministic even if caller's candidate order is unstable.
 	if len(available) > 1 {
 		sort.Slice(available, func(i, j int) bool { return available[i].ID < available[j].ID })
 	}
-	key := provider + ":" + model
-	s.mu.Lock()
-	index := s.cursors[key]
-
-	if index >= 2_147_483_640 {
-		index = 0
-	}
-
-	s.cursors[key] = index + 1
-	s.mu.Unlock()
-	// log.Debugf("available: %d, index: %d, key: %d", len(available), index, index%len(available))
-	return available[index%len(available)], nil
+	return available, nil
 }
 
 func isAuthBlockedForModel(auth *Auth, model string, now time.Time) (bool, blockReason, time.Time) {
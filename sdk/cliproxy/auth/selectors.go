@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// Built-in selection strategy names accepted by NewSelector.
+const (
+	SelectorRoundRobin      = "round-robin"
+	SelectorLeastInFlight   = "least-in-flight"
+	SelectorWeighted        = "weighted"
+	SelectorFillFirst       = "fill-first"
+	SelectorLowestErrorRate = "lowest-error-rate"
+)
+
+// WeightAttribute is the auth attribute (or auth file metadata key) read by WeightedSelector.
+const WeightAttribute = "weight"
+
+// SelectionObserver is an optional Selector extension. When the Manager's selector
+// implements it, the Manager reports the lifecycle of every attempt made with a
+// picked auth so the selector can track load or outcomes.
+type SelectionObserver interface {
+	// AttemptStarted fires right before the executor runs with auth.
+	AttemptStarted(auth *Auth, model string)
+	// AttemptFinished fires once the attempt completes; for streams after the last chunk.
+	AttemptFinished(auth *Auth, model string, err error)
+}
+
+// NewSelector constructs a built-in selector by strategy name. An empty name
+// selects round-robin.
+func NewSelector(strategy string) (Selector, error) {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "", SelectorRoundRobin:
+		return &RoundRobinSelector{}, nil
+	case SelectorLeastInFlight:
+		return NewLeastInFlightSelector(), nil
+	case SelectorWeighted:
+		return NewWeightedSelector(), nil
+	case SelectorFillFirst:
+		return &FillFirstSelector{}, nil
+	case SelectorLowestErrorRate:
+		return NewLowestErrorRateSelector(), nil
+	default:
+		return nil, fmt.Errorf("unknown selector strategy %q", strategy)
+	}
+}
+
+// SetSelector replaces the selector used for auth selection.
+func (m *Manager) SetSelector(selector Selector) {
+	if m == nil {
+		return
+	}
+	if selector == nil {
+		selector = &RoundRobinSelector{}
+	}
+	m.mu.Lock()
+	m.selector = selector
+	m.mu.Unlock()
+}
+
+// beginAttempt notifies the endpoint circuit breaker and an observing selector that
+// auth is about to be used and returns the function reporting the attempt outcome.
+// probe is the half-open probe reservation returned by pickNext.
+func (m *Manager) beginAttempt(auth *Auth, model string, probe bool) func(error) {
+	if auth == nil {
+		return func(error) {}
+	}
+	finishCircuit := m.circuits.begin(auth, probe)
+	m.mu.RLock()
+	observer, ok := m.selector.(SelectionObserver)
+	m.mu.RUnlock()
+	if ok {
+		observer.AttemptStarted(auth, model)
+	}
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			finishCircuit(err)
+			if ok {
+				observer.AttemptFinished(auth, model, err)
+			}
+		})
+	}
+}
+
+// LeastInFlightSelector picks the available auth with the fewest attempts in flight,
+// breaking ties in round-robin order.
+type LeastInFlightSelector struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	ties     RoundRobinSelector
+}
+
+// NewLeastInFlightSelector constructs a LeastInFlightSelector.
+func NewLeastInFlightSelector() *LeastInFlightSelector {
+	return &LeastInFlightSelector{inFlight: make(map[string]int)}
+}
+
+// Pick implements Selector.
+func (s *LeastInFlightSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	available, err := availableAuthsForModel(provider, model, auths, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	lowest := math.MaxInt
+	least := make([]*Auth, 0, len(available))
+	for _, candidate := range available {
+		count := s.inFlight[candidate.ID]
+		switch {
+		case count < lowest:
+			lowest = count
+			least = append(least[:0], candidate)
+		case count == lowest:
+			least = append(least, candidate)
+		}
+	}
+	s.mu.Unlock()
+	return s.ties.next(provider+":"+model, least), nil
+}
+
+// AttemptStarted implements SelectionObserver.
+func (s *LeastInFlightSelector) AttemptStarted(auth *Auth, _ string) {
+	s.mu.Lock()
+	s.inFlight[auth.ID]++
+	s.mu.Unlock()
+}
+
+// AttemptFinished implements SelectionObserver.
+func (s *LeastInFlightSelector) AttemptFinished(auth *Auth, _ string, _ error) {
+	s.mu.Lock()
+	if s.inFlight[auth.ID] <= 1 {
+		delete(s.inFlight, auth.ID)
+	} else {
+		s.inFlight[auth.ID]--
+	}
+	s.mu.Unlock()
+}
+
+// WeightedSelector distributes requests in proportion to each auth's "weight"
+// attribute using smooth weighted round-robin. Missing or invalid weights count as 1;
+// a weight of 0 only receives traffic when no weighted auth is available.
+type WeightedSelector struct {
+	mu      sync.Mutex
+	current map[string]map[string]int
+}
+
+// NewWeightedSelector constructs a WeightedSelector.
+func NewWeightedSelector() *WeightedSelector {
+	return &WeightedSelector{current: make(map[string]map[string]int)}
+}
+
+// Pick implements Selector.
+func (s *WeightedSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	available, err := availableAuthsForModel(provider, model, auths, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	weighted := make([]*Auth, 0, len(available))
+	weights := make([]int, 0, len(available))
+	total := 0
+	for _, candidate := range available {
+		if weight := authWeight(candidate); weight > 0 {
+			weighted = append(weighted, candidate)
+			weights = append(weights, weight)
+			total += weight
+		}
+	}
+	if total == 0 {
+		// Only zero-weight auths remain; fall back to equal weights.
+		weighted = available
+		weights = weights[:0]
+		for range available {
+			weights = append(weights, 1)
+		}
+		total = len(available)
+	}
+	available = weighted
+
+	key := provider + ":" + model
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.current[key]
+	if !ok {
+		current = make(map[string]int)
+		s.current[key] = current
+	}
+	var selected *Auth
+	best := math.MinInt
+	seen := make(map[string]struct{}, len(available))
+	for i, candidate := range available {
+		seen[candidate.ID] = struct{}{}
+		current[candidate.ID] += weights[i]
+		if current[candidate.ID] > best {
+			best = current[candidate.ID]
+			selected = candidate
+		}
+	}
+	current[selected.ID] -= total
+	for id := range current {
+		if _, ok = seen[id]; !ok {
+			delete(current, id)
+		}
+	}
+	return selected, nil
+}
+
+// authWeight reads the weight attribute, falling back to a "weight" entry in the
+// auth file metadata so OAuth credentials can be weighted without config changes.
+func authWeight(auth *Auth) int {
+	if auth == nil {
+		return 1
+	}
+	raw := ""
+	if auth.Attributes != nil {
+		raw = strings.TrimSpace(auth.Attributes[WeightAttribute])
+	}
+	if raw == "" && auth.Metadata != nil {
+		switch v := auth.Metadata[WeightAttribute].(type) {
+		case float64:
+			raw = strconv.Itoa(int(v))
+		case string:
+			raw = strings.TrimSpace(v)
+		}
+	}
+	if raw == "" {
+		return 1
+	}
+	weight, err := strconv.Atoi(raw)
+	if err != nil || weight < 0 {
+		return 1
+	}
+	return weight
+}
+
+// FillFirstSelector always picks the first available auth in ID order, draining one
+// credential before spilling over to the next. Secondary credentials stay idle
+// until the primary is cooling down or disabled.
+type FillFirstSelector struct{}
+
+// Pick implements Selector.
+func (s *FillFirstSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	available, err := availableAuthsForModel(provider, model, auths, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return available[0], nil
+}
+
+// errorRateHalfLife controls how quickly past failures stop penalising an auth.
+const errorRateHalfLife = 5 * time.Minute
+
+// errorRateAlpha is the weight given to the most recent outcome.
+const errorRateAlpha = 0.2
+
+// LowestErrorRateSelector picks the available auth with the lowest recent error
+// rate for the requested model. Rates are exponentially weighted and decay toward
+// zero over time so recovered credentials are retried. Ties are broken round-robin.
+type LowestErrorRateSelector struct {
+	mu    sync.Mutex
+	rates map[string]errorRate
+	ties  RoundRobinSelector
+}
+
+type errorRate struct {
+	value   float64
+	updated time.Time
+}
+
+// NewLowestErrorRateSelector constructs a LowestErrorRateSelector.
+func NewLowestErrorRateSelector() *LowestErrorRateSelector {
+	return &LowestErrorRateSelector{rates: make(map[string]errorRate)}
+}
+
+// Pick implements Selector.
+func (s *LowestErrorRateSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	now := time.Now()
+	available, err := availableAuthsForModel(provider, model, auths, now)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	lowest := math.Inf(1)
+	best := make([]*Auth, 0, len(available))
+	for _, candidate := range available {
+		rate := s.rates[errorRateKey(candidate.ID, model)].at(now)
+		// Treat near-identical rates as ties so load still spreads across healthy auths.
+		switch {
+		case rate < lowest-0.01:
+			lowest = rate
+			best = append(best[:0], candidate)
+		case math.Abs(rate-lowest) <= 0.01:
+			best = append(best, candidate)
+		}
+	}
+	s.mu.Unlock()
+	return s.ties.next(provider+":"+model, best), nil
+}
+
+// AttemptStarted implements SelectionObserver.
+func (s *LowestErrorRateSelector) AttemptStarted(*Auth, string) {}
+
+// AttemptFinished implements SelectionObserver.
+func (s *LowestErrorRateSelector) AttemptFinished(auth *Auth, model string, err error) {
+	if errors.Is(err, context.Canceled) {
+		// Client cancellations say nothing about credential health.
+		return
+	}
+	outcome := 0.0
+	if err != nil {
+		outcome = 1
+	}
+	now := time.Now()
+	key := errorRateKey(auth.ID, model)
+	s.mu.Lock()
+	current := s.rates[key].at(now)
+	s.rates[key] = errorRate{value: current*(1-errorRateAlpha) + outcome*errorRateAlpha, updated: now}
+	s.mu.Unlock()
+}
+
+func (r errorRate) at(now time.Time) float64 {
+	if r.updated.IsZero() || r.value == 0 {
+		return 0
+	}
+	elapsed := now.Sub(r.updated)
+	if elapsed <= 0 {
+		return r.value
+	}
+	return r.value * math.Exp2(-elapsed.Seconds()/errorRateHalfLife.Seconds())
+}
+
+func errorRateKey(authID, model string) string { return authID + "|" + model }
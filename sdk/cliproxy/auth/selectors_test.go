@@ -0,0 +1,313 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// pickSequence picks count times and returns the picked auth IDs joined by spaces.
+func pickSequence(t *testing.T, selector Selector, model string, auths []*Auth, count int) string {
+	t.Helper()
+	picks := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		picked, err := selector.Pick(context.Background(), "test", model, cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+		picks = append(picks, picked.ID)
+	}
+	return strings.Join(picks, " ")
+}
+
+func TestWeightedSelectorDistribution(t *testing.T) {
+	testCases := []struct {
+		name  string
+		auths []*Auth
+		want  string
+	}{
+		{
+			name: "smooth interleaving in proportion to weight",
+			auths: []*Auth{
+				{ID: "a", Attributes: map[string]string{WeightAttribute: "5"}},
+				{ID: "b", Attributes: map[string]string{WeightAttribute: "1"}},
+				{ID: "c", Attributes: map[string]string{WeightAttribute: "1"}},
+			},
+			want: "a a b a c a a a a b a c a a",
+		},
+		{
+			name: "missing and invalid weights count as one",
+			auths: []*Auth{
+				{ID: "a", Attributes: map[string]string{WeightAttribute: "2"}},
+				{ID: "b"},
+				{ID: "c", Attributes: map[string]string{WeightAttribute: "heavy"}},
+			},
+			want: "a b c a a b c a",
+		},
+		{
+			name: "metadata weight for auth files",
+			auths: []*Auth{
+				{ID: "a", Metadata: map[string]any{WeightAttribute: float64(3)}},
+				{ID: "b"},
+			},
+			want: "a a b a a a b a",
+		},
+		{
+			name: "zero weight idles while weighted auths are available",
+			auths: []*Auth{
+				{ID: "a", Attributes: map[string]string{WeightAttribute: "0"}},
+				{ID: "b"},
+			},
+			want: "b b b b",
+		},
+		{
+			name: "only zero weights fall back to equal shares",
+			auths: []*Auth{
+				{ID: "a", Attributes: map[string]string{WeightAttribute: "0"}},
+				{ID: "b", Attributes: map[string]string{WeightAttribute: "0"}},
+			},
+			want: "a b a b",
+		},
+		{
+			name: "disabled auth is skipped",
+			auths: []*Auth{
+				{ID: "a", Attributes: map[string]string{WeightAttribute: "5"}, Disabled: true},
+				{ID: "b", Attributes: map[string]string{WeightAttribute: "1"}},
+				{ID: "c", Attributes: map[string]string{WeightAttribute: "1"}},
+			},
+			want: "b c b c",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			count := len(strings.Fields(tc.want))
+			if got := pickSequence(t, NewWeightedSelector(), "model", tc.auths, count); got != tc.want {
+				t.Fatalf("picks = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFillFirstSelector(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	testCases := []struct {
+		name  string
+		auths []*Auth
+		want  string
+	}{
+		{
+			name:  "first auth in ID order takes every request",
+			auths: []*Auth{{ID: "c"}, {ID: "a"}, {ID: "b"}},
+			want:  "a a a",
+		},
+		{
+			name:  "disabled primary spills to the next auth",
+			auths: []*Auth{{ID: "a", Status: StatusDisabled}, {ID: "b"}, {ID: "c"}},
+			want:  "b b b",
+		},
+		{
+			name: "primary cooling down for the model spills to the next auth",
+			auths: []*Auth{
+				{ID: "a", ModelStates: map[string]*ModelState{"model": {Unavailable: true, NextRetryAfter: future}}},
+				{ID: "b"},
+			},
+			want: "b b b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := pickSequence(t, &FillFirstSelector{}, "model", tc.auths, 3); got != tc.want {
+				t.Fatalf("picks = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLeastInFlightSelectorPick(t *testing.T) {
+	auths := []*Auth{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	selector := NewLeastInFlightSelector()
+
+	// Each started attempt pushes the next pick to an idle auth.
+	picked := make(map[string]bool)
+	for i := 0; i < len(auths); i++ {
+		auth, err := selector.Pick(context.Background(), "test", "model", cliproxyexecutor.Options{}, auths)
+		if err != nil {
+			t.Fatalf("Pick() error = %v", err)
+		}
+		if picked[auth.ID] {
+			t.Fatalf("Pick() = %s again while other auths are idle", auth.ID)
+		}
+		picked[auth.ID] = true
+		selector.AttemptStarted(auth, "model")
+	}
+	selector.AttemptStarted(auths[0], "model")
+	selector.AttemptFinished(auths[1], "model", nil)
+	if got := pickSequence(t, selector, "model", auths, 2); got != "b b" {
+		t.Fatalf("picks = %q, want the only auth with fewer attempts in flight", got)
+	}
+}
+
+// inFlightExecutor reports the in-flight count the selector holds for the auth it
+// runs with, then fails or waits for cancellation.
+type inFlightExecutor struct {
+	*testExecutor
+	selector *LeastInFlightSelector
+	err      error
+	cancel   context.CancelFunc
+	observed int
+}
+
+func (e *inFlightExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.selector.mu.Lock()
+	e.observed = e.selector.inFlight[auth.ID]
+	e.selector.mu.Unlock()
+	if e.cancel != nil {
+		e.cancel()
+		<-ctx.Done()
+		return cliproxyexecutor.Response{}, ctx.Err()
+	}
+	return cliproxyexecutor.Response{Payload: []byte("ok")}, e.err
+}
+
+func TestLeastInFlightSelectorReleasesAttempts(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		cancel  bool
+		wantErr bool
+	}{
+		{name: "success", wantErr: false},
+		{name: "upstream error", err: testStatusError{code: http.StatusInternalServerError}, wantErr: true},
+		{name: "client cancellation", cancel: true, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := "least-in-flight-" + strings.ReplaceAll(tc.name, " ", "-")
+			manager, base := newTestManager(t, provider, []string{"model"}, nil)
+			manager.SetRetryConfig(0, 0)
+			selector := NewLeastInFlightSelector()
+			manager.SetSelector(selector)
+			executor := &inFlightExecutor{testExecutor: base, selector: selector, err: tc.err}
+			manager.RegisterExecutor(executor)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancel {
+				executor.cancel = cancel
+			}
+			_, err := manager.Execute(ctx, []string{provider}, cliproxyexecutor.Request{Model: "model"}, cliproxyexecutor.Options{})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if executor.observed != 1 {
+				t.Fatalf("in-flight count during the attempt = %d, want 1", executor.observed)
+			}
+			selector.mu.Lock()
+			remaining := len(selector.inFlight)
+			selector.mu.Unlock()
+			if remaining != 0 {
+				t.Fatalf("in-flight attempts after Execute = %d, want 0", remaining)
+			}
+		})
+	}
+}
+
+func TestLowestErrorRateSelector(t *testing.T) {
+	failure := testStatusError{code: http.StatusBadGateway}
+	testCases := []struct {
+		name string
+		// failures lists auth IDs that each finish one attempt with outcome, in order.
+		failures []string
+		outcome  error
+		age      time.Duration
+		want     string
+	}{
+		{
+			name: "healthy auths share load round-robin",
+			want: "a b a b",
+		},
+		{
+			name:     "failing auth is avoided",
+			failures: []string{"a", "a"},
+			outcome:  failure,
+			want:     "b b b b",
+		},
+		{
+			name:     "auth with fewer failures is preferred",
+			failures: []string{"a", "a", "a", "b"},
+			outcome:  failure,
+			want:     "b b b b",
+		},
+		{
+			name:     "failures decay until the auth is retried",
+			failures: []string{"a", "a"},
+			outcome:  failure,
+			age:      20 * errorRateHalfLife,
+			want:     "a b a b",
+		},
+		{
+			name:     "client cancellations are ignored",
+			failures: []string{"a", "a"},
+			outcome:  context.Canceled,
+			want:     "a b a b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			auths := []*Auth{{ID: "a"}, {ID: "b"}}
+			byID := map[string]*Auth{"a": auths[0], "b": auths[1]}
+			selector := NewLowestErrorRateSelector()
+			for _, id := range tc.failures {
+				selector.AttemptFinished(byID[id], "model", tc.outcome)
+			}
+			for key, rate := range selector.rates {
+				rate.updated = rate.updated.Add(-tc.age)
+				selector.rates[key] = rate
+			}
+			if got := pickSequence(t, selector, "model", auths, 4); got != tc.want {
+				t.Fatalf("picks = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestErrorRateDecay(t *testing.T) {
+	now := time.Now()
+	rate := errorRate{value: 0.8, updated: now}
+	testCases := []struct {
+		name    string
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "fresh", elapsed: 0, want: 0.8},
+		{name: "one half-life", elapsed: errorRateHalfLife, want: 0.4},
+		{name: "two half-lives", elapsed: 2 * errorRateHalfLife, want: 0.2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rate.at(now.Add(tc.elapsed))
+			if diff := got - tc.want; diff > 1e-9 || diff < -1e-9 {
+				t.Fatalf("rate after %s = %f, want %f", tc.elapsed, got, tc.want)
+			}
+		})
+	}
+
+	// A success after decay lowers the rate further instead of resetting the decay.
+	selector := NewLowestErrorRateSelector()
+	auth := &Auth{ID: "a"}
+	selector.AttemptFinished(auth, "model", errors.New("boom"))
+	first := selector.rates[errorRateKey("a", "model")].value
+	selector.AttemptFinished(auth, "model", nil)
+	if second := selector.rates[errorRateKey("a", "model")].value; second >= first {
+		t.Fatalf("rate after a success = %f, want below %f", second, first)
+	}
+}
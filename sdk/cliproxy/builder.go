@@ -8,9 +8,11 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/pipeline"
 )
 
 // Builder constructs a Service instance with customizable providers.
@@ -46,6 +48,9 @@ type Builder struct {
 
 	// serverOptions contains additional server configuration options.
 	serverOptions []api.ServerOption
+
+	// pipelineHooks observe and mutate provider executions.
+	pipelineHooks []pipeline.Hook
 }
 
 // Hooks allows callers to plug into service lifecycle stages.
@@ -142,6 +147,18 @@ func (b *Builder) WithServerOptions(opts ...api.ServerOption) *Builder {
 	return b
 }
 
+// WithPipelineHooks registers hooks invoked around every provider execution.
+// Hooks can inspect or mutate the request, see the selected auth, swap the HTTP client,
+// and observe stream chunks.
+func (b *Builder) WithPipelineHooks(hooks ...pipeline.Hook) *Builder {
+	for _, hook := range hooks {
+		if hook != nil {
+			b.pipelineHooks = append(b.pipelineHooks, hook)
+		}
+	}
+	return b
+}
+
 // WithLocalManagementPassword configures a password that is only accepted from localhost management requests.
 func (b *Builder) WithLocalManagementPassword(password string) *Builder {
 	if password == "" {
@@ -200,7 +217,16 @@ func (b *Builder) Build() (*Service, error) {
 		coreManager = coreauth.NewManager(tokenStore, nil, nil)
 	}
 	// Attach a default RoundTripper provider so providers can opt-in per-auth transports.
-	coreManager.SetRoundTripperProvider(newDefaultRoundTripperProvider())
+	rtProvider, err := newRoundTripperProvider(b.cfg, b.configPath)
+	if err != nil {
+		return nil, err
+	}
+	coreManager.SetRoundTripperProvider(rtProvider)
+	// Record upstream attempt metrics; the manager skips the hook while metrics are disabled.
+	coreManager.AddExecutionHook(metrics.NewExecutionHook())
+	if len(b.pipelineHooks) > 0 {
+		coreManager.AddExecutionHook(pipeline.NewExecutionHook(b.pipelineHooks...))
+	}
 
 	service := &Service{
 		cfg:            b.cfg,
@@ -0,0 +1,301 @@
+// Package cassette records the HTTP exchanges of provider executors to a directory
+// and replays them later, so translators and client integrations can be developed
+// without network access or credentials.
+//
+// Each exchange is stored as one JSON file. Credentials in request headers and the
+// "key" query parameter are redacted before anything is written. Response bodies are
+// kept as the chunks in which they arrived, together with their delays, so streaming
+// responses replay with their original timing.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Mode selects whether a Store records new exchanges or replays stored ones.
+type Mode string
+
+const (
+	// ModeRecord forwards requests upstream and stores every completed exchange.
+	ModeRecord Mode = "record"
+	// ModeReplay answers requests from the stored exchanges without network access.
+	ModeReplay Mode = "replay"
+)
+
+// redacted replaces credential values in stored requests.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are the request headers whose values are never written to disk.
+var sensitiveHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"X-Api-Key":           {},
+	"X-Goog-Api-Key":      {},
+	"Api-Key":             {},
+	"Cookie":              {},
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	RecordedAt time.Time        `json:"recorded_at"`
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the redacted outbound request.
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	// Body is the request body; BodySHA256 identifies it when matching replays.
+	Body       string `json:"body,omitempty"`
+	BodySHA256 string `json:"body_sha256"`
+}
+
+// RecordedResponse is the upstream response, with its body split into the chunks
+// in which it was received.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	// LatencyMS is the time between sending the request and receiving the headers.
+	LatencyMS int64   `json:"latency_ms"`
+	Chunks    []Chunk `json:"chunks,omitempty"`
+}
+
+// Chunk is a piece of a response body. Data holds UTF-8 text; other bytes are kept
+// base64-encoded in Base64.
+type Chunk struct {
+	// DelayMS is the time since the previous chunk, or since the headers for the first one.
+	DelayMS int64  `json:"delay_ms"`
+	Data    string `json:"data,omitempty"`
+	Base64  string `json:"base64,omitempty"`
+}
+
+func newChunk(delay time.Duration, data []byte) Chunk {
+	chunk := Chunk{DelayMS: delay.Milliseconds()}
+	if utf8.Valid(data) {
+		chunk.Data = string(data)
+	} else {
+		chunk.Base64 = base64.StdEncoding.EncodeToString(data)
+	}
+	return chunk
+}
+
+func (c Chunk) bytes() []byte {
+	if c.Base64 != "" {
+		data, err := base64.StdEncoding.DecodeString(c.Base64)
+		if err == nil {
+			return data
+		}
+	}
+	return []byte(c.Data)
+}
+
+// Store is a cassette directory. In replay mode it indexes the interactions found
+// there when it is opened.
+type Store struct {
+	dir  string
+	mode Mode
+
+	mu  sync.Mutex
+	seq int
+	// exact indexes interactions by method, URL and body hash; route by method and URL only.
+	exact map[string]*replayQueue
+	route map[string]*replayQueue
+}
+
+// replayQueue hands out recordings of the same request in recording order and then
+// keeps answering with the last one.
+type replayQueue struct {
+	items []*Interaction
+	next  int
+}
+
+func (q *replayQueue) pop() *Interaction {
+	item := q.items[min(q.next, len(q.items)-1)]
+	q.next++
+	return item
+}
+
+// Open prepares dir for mode. Recording creates the directory; replaying loads every
+// interaction already in it.
+func Open(dir string, mode Mode) (*Store, error) {
+	s := &Store{dir: dir, mode: mode, exact: make(map[string]*replayQueue), route: make(map[string]*replayQueue)}
+	switch mode {
+	case ModeRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("cassette: create %s: %w", dir, err)
+		}
+	case ModeReplay:
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cassette: unknown mode %q (want %q or %q)", mode, ModeRecord, ModeReplay)
+	}
+	return s, nil
+}
+
+// Mode reports whether the store records or replays.
+func (s *Store) Mode() Mode { return s.mode }
+
+// Dir returns the cassette directory.
+func (s *Store) Dir() string { return s.dir }
+
+// Len returns the number of interactions available for replay.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, q := range s.route {
+		n += len(q.items)
+	}
+	return n
+}
+
+func (s *Store) load() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("cassette: list %s: %w", s.dir, err)
+	}
+	// File names start with the recording time, so sorting restores recording order.
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, errRead := os.ReadFile(path)
+		if errRead != nil {
+			return fmt.Errorf("cassette: read %s: %w", path, errRead)
+		}
+		var interaction Interaction
+		if errUnmarshal := json.Unmarshal(data, &interaction); errUnmarshal != nil {
+			return fmt.Errorf("cassette: parse %s: %w", path, errUnmarshal)
+		}
+		req := interaction.Request
+		s.index(s.exact, exactKey(req.Method, req.URL, req.BodySHA256), &interaction)
+		s.index(s.route, routeKey(req.Method, req.URL), &interaction)
+	}
+	return nil
+}
+
+func (s *Store) index(m map[string]*replayQueue, key string, interaction *Interaction) {
+	q := m[key]
+	if q == nil {
+		q = &replayQueue{}
+		m[key] = q
+	}
+	q.items = append(q.items, interaction)
+}
+
+// lookup finds the recording for a request: one with the same body when possible,
+// otherwise the next one recorded for the same method and URL.
+func (s *Store) lookup(method, rawURL, bodyHash string) (*Interaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.exact[exactKey(method, rawURL, bodyHash)]; ok {
+		return q.pop(), true
+	}
+	if q, ok := s.route[routeKey(method, rawURL)]; ok {
+		return q.pop(), true
+	}
+	return nil, false
+}
+
+// save writes interaction as a new file in the cassette directory.
+func (s *Store) save(interaction *Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.seq++
+	seq := s.seq
+	s.mu.Unlock()
+	host := "request"
+	if parsed, errParse := url.Parse(interaction.Request.URL); errParse == nil && parsed.Host != "" {
+		host = strings.NewReplacer(":", "_", "/", "_").Replace(parsed.Host)
+	}
+	name := fmt.Sprintf("%s-%06d-%s-%s.json", interaction.RecordedAt.UTC().Format("20060102T150405.000000000"), seq, host, interaction.Request.BodySHA256[:8])
+	tmp, err := os.CreateTemp(s.dir, ".cassette-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func exactKey(method, rawURL, bodyHash string) string {
+	return method + " " + rawURL + " " + bodyHash
+}
+
+func routeKey(method, rawURL string) string {
+	return method + " " + rawURL
+}
+
+// redactURL hides API keys passed in the query string.
+func redactURL(u *url.URL) string {
+	clone := *u
+	query := clone.Query()
+	changed := false
+	for name := range query {
+		if strings.EqualFold(name, "key") || strings.EqualFold(name, "api_key") {
+			query.Set(name, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		clone.RawQuery = query.Encode()
+	}
+	return clone.String()
+}
+
+// redactHeaders copies h, replacing credential values.
+func redactHeaders(h http.Header, sensitive map[string]struct{}) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for name := range out {
+		if _, ok := sensitive[http.CanonicalHeaderKey(name)]; ok {
+			out[name] = []string{redacted}
+		}
+	}
+	return out
+}
+
+// bodyHash hashes a request body, ignoring key order and whitespace in JSON bodies.
+func bodyHash(body []byte) string {
+	canonical := body
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if decoder.Decode(&value) == nil {
+		if encoded, err := json.Marshal(value); err == nil {
+			canonical = encoded
+		}
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,185 @@
+package cassette
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testSecret = "sk-cassette-secret"
+
+// newUpstream serves a streamed body whose chunks are numbered by request count, and
+// sets a cookie the recording must not keep.
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := calls.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Set-Cookie", "session="+testSecret)
+		if r.URL.Path == "/binary" {
+			_, _ = w.Write([]byte{0xff, 0xfe, 0x00, byte(call)})
+			return
+		}
+		flusher := w.(http.Flusher)
+		for _, chunk := range []string{"data: first\n\n", fmt.Sprintf("data: call %d\n\n", call)} {
+			_, _ = w.Write([]byte(chunk))
+			flusher.Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type testRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+func send(t *testing.T, transport http.RoundTripper, baseURL string, req testRequest) (int, http.Header, string, error) {
+	t.Helper()
+	httpReq, err := http.NewRequest(req.method, baseURL+req.path, strings.NewReader(req.body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+testSecret)
+	httpReq.Header.Set("X-Api-Key", testSecret)
+	httpReq.Header.Set("X-Goog-Api-Key", testSecret)
+	httpReq.Header.Set("Cookie", "token="+testSecret)
+	httpReq.Header.Set("Anthropic-Version", "2023-06-01")
+	resp, err := transport.RoundTrip(httpReq)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp.StatusCode, resp.Header, string(data), nil
+}
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	upstream := newUpstream(t)
+	dir := t.TempDir()
+	recorder, err := Open(dir, ModeRecord)
+	if err != nil {
+		t.Fatalf("Open(record) error = %v", err)
+	}
+	recordTransport := NewTransport(recorder, nil)
+
+	stream := testRequest{method: http.MethodPost, path: "/v1/messages?key=" + testSecret, body: `{"model":"claude","stream":true}`}
+	other := testRequest{method: http.MethodPost, path: "/v1/messages?key=" + testSecret, body: `{"model":"claude","stream":false}`}
+	binary := testRequest{method: http.MethodGet, path: "/binary"}
+	var recorded []string
+	for _, req := range []testRequest{stream, stream, binary} {
+		_, _, body, errSend := send(t, recordTransport, upstream.URL, req)
+		if errSend != nil {
+			t.Fatalf("recording %s %s: %v", req.method, req.path, errSend)
+		}
+		recorded = append(recorded, body)
+	}
+
+	player, err := Open(dir, ModeReplay)
+	if err != nil {
+		t.Fatalf("Open(replay) error = %v", err)
+	}
+	if player.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3 recordings", player.Len())
+	}
+	// Replays never reach the network; the upstream is gone.
+	upstream.Close()
+	replayTransport := NewTransport(player, nil)
+
+	testCases := []struct {
+		name string
+		req  testRequest
+		want string
+	}{
+		{name: "first recording of a request", req: stream, want: recorded[0]},
+		{name: "repeated request gets the next recording", req: testRequest{method: http.MethodPost, path: stream.path, body: `{"stream":true, "model":"claude"}`}, want: recorded[1]},
+		{name: "exhausted recordings repeat the last one", req: stream, want: recorded[1]},
+		{name: "other body falls back to the route's recordings in order", req: other, want: recorded[0]},
+		{name: "binary body", req: binary, want: recorded[2]},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, header, body, errSend := send(t, replayTransport, upstream.URL, tc.req)
+			if errSend != nil {
+				t.Fatalf("replay error = %v", errSend)
+			}
+			if status != http.StatusOK || header.Get("Content-Type") != "text/event-stream" {
+				t.Fatalf("replayed status %d, headers %v", status, header)
+			}
+			if body != tc.want {
+				t.Fatalf("replayed body = %q, want %q", body, tc.want)
+			}
+		})
+	}
+
+	if _, _, _, err = send(t, replayTransport, upstream.URL, testRequest{method: http.MethodGet, path: "/unknown"}); err == nil {
+		t.Fatalf("replaying an unrecorded request succeeded")
+	}
+}
+
+func TestRecordingRedactsCredentials(t *testing.T) {
+	upstream := newUpstream(t)
+	dir := t.TempDir()
+	recorder, err := Open(dir, ModeRecord)
+	if err != nil {
+		t.Fatalf("Open(record) error = %v", err)
+	}
+	req := testRequest{method: http.MethodPost, path: "/v1beta/models/gemini:streamGenerateContent?alt=sse&key=" + testSecret, body: `{"contents":[]}`}
+	if _, _, _, err = send(t, NewTransport(recorder, nil), upstream.URL, req); err != nil {
+		t.Fatalf("recording error = %v", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("cassette files = %v (%v), want one", paths, err)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	stored := string(data)
+	if strings.Contains(stored, testSecret) {
+		t.Fatalf("cassette stores the credential:\n%s", stored)
+	}
+
+	player, err := Open(dir, ModeReplay)
+	if err != nil {
+		t.Fatalf("Open(replay) error = %v", err)
+	}
+	interaction, ok := player.lookup(http.MethodPost, strings.Replace(upstream.URL+req.path, testSecret, redacted, 1), bodyHash([]byte(req.body)))
+	if !ok {
+		t.Fatalf("recording not found under the redacted URL")
+	}
+	testCases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "authorization header", got: interaction.Request.Headers.Get("Authorization"), want: redacted},
+		{name: "x-api-key header", got: interaction.Request.Headers.Get("X-Api-Key"), want: redacted},
+		{name: "x-goog-api-key header", got: interaction.Request.Headers.Get("X-Goog-Api-Key"), want: redacted},
+		{name: "cookie header", got: interaction.Request.Headers.Get("Cookie"), want: redacted},
+		{name: "set-cookie response header", got: interaction.Response.Headers.Get("Set-Cookie"), want: redacted},
+		{name: "other headers are kept", got: interaction.Request.Headers.Get("Anthropic-Version"), want: "2023-06-01"},
+		{name: "body is kept", got: interaction.Request.Body, want: req.body},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Fatalf("got %q, want %q", tc.got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// sensitiveResponseHeaders are the response headers whose values are never written to disk.
+var sensitiveResponseHeaders = map[string]struct{}{
+	"Set-Cookie": {},
+}
+
+// Transport records exchanges through next, or replays them from the store.
+// Executors use it in preference to any proxy transport because it already wraps
+// the transport that would otherwise have been used.
+type Transport struct {
+	store *Store
+	next  http.RoundTripper
+}
+
+// NewTransport returns a transport bound to store. next carries recorded requests
+// upstream and defaults to http.DefaultTransport; it is unused while replaying.
+func NewTransport(store *Store, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{store: store, next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: read request body: %w", err)
+		}
+		body = data
+	}
+	rawURL := redactURL(req.URL)
+	hash := bodyHash(body)
+	if t.store.mode == ModeReplay {
+		return t.replay(req, rawURL, hash)
+	}
+
+	outbound := req.Clone(req.Context())
+	outbound.Body = io.NopCloser(bytes.NewReader(body))
+	outbound.ContentLength = int64(len(body))
+	start := time.Now()
+	resp, err := t.next.RoundTrip(outbound)
+	if err != nil {
+		return nil, err
+	}
+	interaction := &Interaction{
+		RecordedAt: start,
+		Request: RecordedRequest{
+			Method:     req.Method,
+			URL:        rawURL,
+			Headers:    redactHeaders(req.Header, sensitiveHeaders),
+			Body:       string(body),
+			BodySHA256: hash,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header, sensitiveResponseHeaders),
+			LatencyMS:  time.Since(start).Milliseconds(),
+		},
+	}
+	resp.Body = &recordingBody{body: resp.Body, store: t.store, interaction: interaction, last: time.Now()}
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, rawURL, hash string) (*http.Response, error) {
+	interaction, ok := t.store.lookup(req.Method, rawURL, hash)
+	if !ok {
+		return nil, fmt.Errorf("cassette: no recording for %s %s in %s", req.Method, rawURL, t.store.dir)
+	}
+	recorded := interaction.Response
+	if err := sleepContext(req.Context(), time.Duration(recorded.LatencyMS)*time.Millisecond); err != nil {
+		return nil, err
+	}
+	header := recorded.Headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          &replayBody{ctx: req.Context(), chunks: recorded.Chunks},
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+// recordingBody captures a response body as it is read and stores the interaction
+// once the body has been read to the end. Bodies abandoned early are not stored.
+type recordingBody struct {
+	body        io.ReadCloser
+	store       *Store
+	interaction *Interaction
+	last        time.Time
+	once        sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		now := time.Now()
+		b.interaction.Response.Chunks = append(b.interaction.Response.Chunks, newChunk(now.Sub(b.last), p[:n]))
+		b.last = now
+	}
+	if err == io.EOF {
+		b.once.Do(func() {
+			if errSave := b.store.save(b.interaction); errSave != nil {
+				log.Errorf("cassette: save %s %s: %v", b.interaction.Request.Method, b.interaction.Request.URL, errSave)
+			}
+		})
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error { return b.body.Close() }
+
+// replayBody returns recorded chunks after their recorded delays.
+type replayBody struct {
+	ctx     context.Context
+	chunks  []Chunk
+	pending []byte
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	for len(b.pending) == 0 {
+		if len(b.chunks) == 0 {
+			return 0, io.EOF
+		}
+		chunk := b.chunks[0]
+		b.chunks = b.chunks[1:]
+		if err := sleepContext(b.ctx, time.Duration(chunk.DelayMS)*time.Millisecond); err != nil {
+			return 0, err
+		}
+		b.pending = chunk.bytes()
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *replayBody) Close() error { return nil }
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Provider is a coreauth.RoundTripperProvider that hands every auth a cassette
+// transport, wrapping the transport inner would have chosen.
+type Provider struct {
+	store *Store
+	inner coreauth.RoundTripperProvider
+}
+
+// NewProvider wraps inner, which may be nil, with cassette recording or replay.
+func NewProvider(store *Store, inner coreauth.RoundTripperProvider) *Provider {
+	return &Provider{store: store, inner: inner}
+}
+
+// ForcesRoundTripper implements coreauth.ForcingRoundTripperProvider: cassettes must
+// see every exchange, whatever proxy is configured.
+func (p *Provider) ForcesRoundTripper() bool { return true }
+
+// RoundTripperFor implements coreauth.RoundTripperProvider.
+func (p *Provider) RoundTripperFor(auth *coreauth.Auth) http.RoundTripper {
+	var next http.RoundTripper
+	if p.inner != nil && p.store.mode == ModeRecord {
+		next = p.inner.RoundTripperFor(auth)
+	}
+	return NewTransport(p.store, next)
+}
@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"context"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+)
+
+// NewExecutionHook adapts pipeline hooks to the coreauth.Manager execution hook contract.
+// Hooks run in registration order and share one Context per provider attempt; mutations
+// made to it during BeforeExecute are applied to the attempt.
+func NewExecutionHook(hooks ...Hook) cliproxyauth.ExecutionHook {
+	filtered := make([]Hook, 0, len(hooks))
+	for _, hook := range hooks {
+		if hook != nil {
+			filtered = append(filtered, hook)
+		}
+	}
+	return &executionHookAdapter{
+		hooks:      filtered,
+		translator: sdktranslator.NewPipeline(sdktranslator.Default()),
+	}
+}
+
+type executionHookAdapter struct {
+	hooks      []Hook
+	translator *sdktranslator.Pipeline
+}
+
+// BeforeExecute implements cliproxyauth.ExecutionHook.
+func (a *executionHookAdapter) BeforeExecute(ctx context.Context, exec *cliproxyauth.Execution) {
+	if a == nil || exec == nil || len(a.hooks) == 0 {
+		return
+	}
+	execCtx := a.contextFor(exec)
+	for _, hook := range a.hooks {
+		hook.BeforeExecute(ctx, execCtx)
+	}
+	exec.Request = execCtx.Request
+	exec.Options = execCtx.Options
+	exec.Auth = execCtx.Auth
+	exec.HTTPClient = execCtx.HTTPClient
+}
+
+// AfterExecute implements cliproxyauth.ExecutionHook.
+func (a *executionHookAdapter) AfterExecute(ctx context.Context, exec *cliproxyauth.Execution, resp cliproxyexecutor.Response, err error) {
+	if a == nil || exec == nil || len(a.hooks) == 0 {
+		return
+	}
+	execCtx := a.contextFor(exec)
+	for _, hook := range a.hooks {
+		hook.AfterExecute(ctx, execCtx, resp, err)
+	}
+}
+
+// OnStreamChunk implements cliproxyauth.ExecutionHook.
+func (a *executionHookAdapter) OnStreamChunk(ctx context.Context, exec *cliproxyauth.Execution, chunk cliproxyexecutor.StreamChunk) {
+	if a == nil || exec == nil || len(a.hooks) == 0 {
+		return
+	}
+	execCtx := a.contextFor(exec)
+	for _, hook := range a.hooks {
+		hook.OnStreamChunk(ctx, execCtx, chunk)
+	}
+}
+
+// contextFor returns the Context of the attempt, creating it on first use.
+func (a *executionHookAdapter) contextFor(exec *cliproxyauth.Execution) *Context {
+	if execCtx, ok := exec.Value(a).(*Context); ok {
+		return execCtx
+	}
+	execCtx := &Context{
+		Request:    exec.Request,
+		Options:    exec.Options,
+		Auth:       exec.Auth,
+		Translator: a.translator,
+		HTTPClient: exec.HTTPClient,
+	}
+	exec.SetValue(a, execCtx)
+	return execCtx
+}
@@ -5,10 +5,13 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"sync"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/cassette"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/proxy"
 )
@@ -29,7 +32,12 @@ func (p *defaultRoundTripperProvider) RoundTripperFor(auth *coreauth.Auth) http.
 	if auth == nil {
 		return nil
 	}
-	proxyStr := strings.TrimSpace(auth.ProxyURL)
+	return p.forProxyURL(auth.ProxyURL)
+}
+
+// forProxyURL returns the cached transport for proxyStr, or nil when it is empty or invalid.
+func (p *defaultRoundTripperProvider) forProxyURL(proxyStr string) http.RoundTripper {
+	proxyStr = strings.TrimSpace(proxyStr)
 	if proxyStr == "" {
 		return nil
 	}
@@ -75,3 +83,48 @@ func (p *defaultRoundTripperProvider) RoundTripperFor(auth *coreauth.Auth) http.
 	p.mu.Unlock()
 	return transport
 }
+
+// globalProxyRoundTripperProvider falls back to the global proxy-url for auths
+// without their own proxy, mirroring how executors build their HTTP clients.
+type globalProxyRoundTripperProvider struct {
+	*defaultRoundTripperProvider
+	proxyURL string
+}
+
+// RoundTripperFor implements coreauth.RoundTripperProvider.
+func (p globalProxyRoundTripperProvider) RoundTripperFor(auth *coreauth.Auth) http.RoundTripper {
+	if rt := p.defaultRoundTripperProvider.RoundTripperFor(auth); rt != nil {
+		return rt
+	}
+	return p.forProxyURL(p.proxyURL)
+}
+
+// newRoundTripperProvider returns the default per-auth transport provider, wrapped
+// for cassette recording or replay when cassette.mode is set.
+func newRoundTripperProvider(cfg *config.Config, configPath string) (coreauth.RoundTripperProvider, error) {
+	provider := newDefaultRoundTripperProvider()
+	if cfg == nil {
+		return provider, nil
+	}
+	mode := strings.ToLower(strings.TrimSpace(cfg.Cassette.Mode))
+	if mode == "" || mode == "off" {
+		return provider, nil
+	}
+	dir := strings.TrimSpace(cfg.Cassette.Dir)
+	if dir == "" {
+		dir = "cassettes"
+	}
+	if !filepath.IsAbs(dir) && configPath != "" {
+		dir = filepath.Join(filepath.Dir(configPath), dir)
+	}
+	store, err := cassette.Open(dir, cassette.Mode(mode))
+	if err != nil {
+		return nil, err
+	}
+	if store.Mode() == cassette.ModeReplay {
+		log.Warnf("cassette replay mode: answering upstream requests from %d recordings in %s", store.Len(), dir)
+	} else {
+		log.Warnf("cassette record mode: writing upstream exchanges to %s", dir)
+	}
+	return cassette.NewProvider(store, globalProxyRoundTripperProvider{defaultRoundTripperProvider: provider, proxyURL: cfg.ProxyURL}), nil
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
@@ -81,6 +82,9 @@ type Service struct {
 	// coreManager handles core authentication and execution.
 	coreManager *coreauth.Manager
 
+	// routing records the routing config last applied to the auth selector.
+	routing config.RoutingConfig
+
 	// shutdownOnce ensures shutdown is called only once.
 	shutdownOnce sync.Once
 
@@ -192,6 +196,7 @@ func (s *Service) ensureWebsocketGateway() {
 		LogWarnf:       log.Warnf,
 	}
 	s.wsGateway = wsrelay.NewManager(opts)
+	metrics.SetWebsocketSessionSource(s.wsGateway.SessionCount)
 }
 
 func (s *Service) wsOnConnected(channelID string) {
@@ -289,6 +294,107 @@ func (s *Service) applyRetryConfig(cfg *config.Config) {
 	s.coreManager.SetRetryConfig(cfg.RequestRetry, maxInterval)
 }
 
+func (s *Service) applyCircuitBreakerConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	breaker := cfg.CircuitBreaker
+	s.coreManager.SetCircuitBreakerConfig(coreauth.CircuitBreakerConfig{
+		Disabled:         breaker.Disable,
+		FailureThreshold: breaker.FailureThreshold,
+		OpenDuration:     time.Duration(breaker.OpenSeconds) * time.Second,
+		HalfOpenProbes:   breaker.HalfOpenProbes,
+	})
+}
+
+func (s *Service) applyHedgingConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	s.coreManager.SetHedgeConfig(coreauth.HedgeConfig{
+		Delay:  time.Duration(cfg.Hedging.DelayMS) * time.Millisecond,
+		Models: cfg.Hedging.Models,
+	})
+}
+
+func (s *Service) applyResponseCacheConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	cache := cfg.ResponseCache
+	s.coreManager.SetResponseCacheConfig(coreauth.ResponseCacheConfig{
+		Enabled:       cache.Enable,
+		TTL:           time.Duration(cache.TTLSeconds) * time.Second,
+		MaxEntries:    cache.MaxEntries,
+		MaxBytes:      int64(cache.MaxSizeMB) << 20,
+		MaxEntryBytes: cache.MaxEntryKB << 10,
+	})
+}
+
+func (s *Service) applyHealthCheckConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	check := cfg.HealthCheck
+	providers := make(map[string]coreauth.ProviderHealthCheck, len(check.Providers))
+	for provider, override := range check.Providers {
+		providers[provider] = coreauth.ProviderHealthCheck{
+			Disabled: override.Disable,
+			Interval: time.Duration(override.IntervalSeconds) * time.Second,
+			Model:    override.Model,
+			Method:   override.Method,
+		}
+	}
+	s.coreManager.SetHealthCheckConfig(coreauth.HealthCheckConfig{
+		Enabled:   check.Enable,
+		Interval:  time.Duration(check.IntervalSeconds) * time.Second,
+		Timeout:   time.Duration(check.TimeoutSeconds) * time.Second,
+		Providers: providers,
+	})
+}
+
+func (s *Service) applyQuotaTrackingConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	s.coreManager.SetRateLimitConfig(coreauth.RateLimitConfig{
+		DisableAvoidance: cfg.QuotaTracking.DisableAvoidance,
+		MinRemaining:     float64(cfg.QuotaTracking.MinRemainingPercent) / 100,
+	})
+}
+
+// applyRoutingConfig swaps the auth selector when the routing config changes.
+// An empty strategy without session affinity leaves the manager's current selector untouched.
+func (s *Service) applyRoutingConfig(cfg *config.Config) {
+	if s == nil || s.coreManager == nil || cfg == nil {
+		return
+	}
+	routing := cfg.Routing
+	routing.Strategy = strings.ToLower(strings.TrimSpace(routing.Strategy))
+	if routing == s.routing {
+		return
+	}
+	if routing.Strategy == "" && !routing.SessionAffinity.Enable && s.routing == (config.RoutingConfig{}) {
+		return
+	}
+	selector, err := coreauth.NewSelector(routing.Strategy)
+	if err != nil {
+		log.Errorf("invalid routing strategy, keeping previous selector: %v", err)
+		return
+	}
+	if routing.SessionAffinity.Enable {
+		ttl := time.Duration(routing.SessionAffinity.TTLSeconds) * time.Second
+		selector = coreauth.NewAffinitySelector(selector, ttl)
+	}
+	s.coreManager.SetSelector(selector)
+	s.routing = routing
+	name := routing.Strategy
+	if name == "" {
+		name = coreauth.SelectorRoundRobin
+	}
+	log.Infof("auth selection strategy set to %s (session affinity: %t)", name, routing.SessionAffinity.Enable)
+}
+
 func openAICompatInfoFromAuth(a *coreauth.Auth) (providerKey string, compatName string, ok bool) {
 	if a == nil {
 		return "", "", false
@@ -409,6 +515,13 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	s.applyRetryConfig(s.cfg)
+	s.applyCircuitBreakerConfig(s.cfg)
+	s.applyRoutingConfig(s.cfg)
+	s.coreManager.SetModelFallbacks(s.cfg.ModelFallbacks)
+	s.applyHedgingConfig(s.cfg)
+	s.applyResponseCacheConfig(s.cfg)
+	s.applyHealthCheckConfig(s.cfg)
+	s.applyQuotaTrackingConfig(s.cfg)
 
 	if s.coreManager != nil {
 		if errLoad := s.coreManager.Load(ctx); errLoad != nil {
@@ -493,6 +606,13 @@ func (s *Service) Run(ctx context.Context) error {
 			return
 		}
 		s.applyRetryConfig(newCfg)
+		s.applyCircuitBreakerConfig(newCfg)
+		s.applyRoutingConfig(newCfg)
+		s.coreManager.SetModelFallbacks(newCfg.ModelFallbacks)
+		s.applyHedgingConfig(newCfg)
+		s.applyResponseCacheConfig(newCfg)
+		s.applyHealthCheckConfig(newCfg)
+		s.applyQuotaTrackingConfig(newCfg)
 		if s.server != nil {
 			s.server.UpdateClients(newCfg)
 		}
@@ -525,6 +645,7 @@ func (s *Service) Run(ctx context.Context) error {
 		interval := 15 * time.Minute
 		s.coreManager.StartAutoRefresh(context.Background(), interval)
 		log.Infof("core auth auto-refresh started (interval=%s)", interval)
+		s.coreManager.StartHealthCheck(context.Background())
 	}
 
 	select {
@@ -562,6 +683,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
+			s.coreManager.StopHealthCheck()
 		}
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
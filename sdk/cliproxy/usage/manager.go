@@ -3,6 +3,7 @@ package usage
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -19,6 +20,11 @@ type Record struct {
 	RequestedAt time.Time
 	Failed      bool
 	Detail      Detail
+	// RequestedModel is the model the client asked for; it differs from Model when
+	// a fallback served the request. Publish fills it from the context when empty.
+	RequestedModel string
+	// CacheHit marks a response served from the response cache without an upstream call.
+	CacheHit bool
 }
 
 // Detail holds the token usage breakdown.
@@ -109,8 +115,14 @@ func (m *Manager) Publish(ctx context.Context, record Record) {
 	if m == nil {
 		return
 	}
+	if suppressed(ctx) {
+		return
+	}
 	// ensure worker is running even if Start was not called explicitly
 	m.Start(context.Background())
+	if record.RequestedModel == "" {
+		record.RequestedModel = requestedModelFromContext(ctx)
+	}
 	m.mu.Lock()
 	if m.closed {
 		m.mu.Unlock()
@@ -179,3 +191,66 @@ func StartDefault(ctx context.Context) { DefaultManager().Start(ctx) }
 
 // StopDefault stops the default manager's dispatcher.
 func StopDefault() { DefaultManager().Stop() }
+
+type requestedModelContextKey struct{}
+
+// WithRequestedModel records the model the client asked for so records published
+// under ctx carry it even when a fallback model served the request.
+func WithRequestedModel(ctx context.Context, model string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if existing, ok := ctx.Value(requestedModelContextKey{}).(string); ok && existing == model {
+		return ctx
+	}
+	return context.WithValue(ctx, requestedModelContextKey{}, model)
+}
+
+func requestedModelFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	model, _ := ctx.Value(requestedModelContextKey{}).(string)
+	return model
+}
+
+type clientAPIKeyContextKey struct{}
+
+// WithClientAPIKey attributes work done under ctx to a client API key when no HTTP
+// request carries one, as for batch jobs run in the background.
+func WithClientAPIKey(ctx context.Context, key string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, clientAPIKeyContextKey{}, key)
+}
+
+// ClientAPIKeyFromContext returns the key set with WithClientAPIKey.
+func ClientAPIKeyFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	key, ok := ctx.Value(clientAPIKeyContextKey{}).(string)
+	return key, ok
+}
+
+type suppressionContextKey struct{}
+
+// WithSuppression returns a context whose records are dropped once the returned
+// function is called. Calling it right away keeps the proxy's own traffic, such as
+// credential health probes, out of usage statistics.
+func WithSuppression(ctx context.Context) (context.Context, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	flag := &atomic.Bool{}
+	return context.WithValue(ctx, suppressionContextKey{}, flag), func() { flag.Store(true) }
+}
+
+func suppressed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	flag, ok := ctx.Value(suppressionContextKey{}).(*atomic.Bool)
+	return ok && flag.Load()
+}
@@ -15,8 +15,69 @@ type SDKConfig struct {
 	// APIKeys is a list of keys for authenticating clients to this proxy server.
 	APIKeys []string `yaml:"api-keys" json:"api-keys"`
 
+	// APIKeyPolicies defines per-client quotas and model restrictions keyed by API key.
+	APIKeyPolicies []APIKeyPolicy `yaml:"api-key-policies,omitempty" json:"api-key-policies,omitempty"`
+
 	// Access holds request authentication provider configuration.
 	Access AccessConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
+
+	// ContextGuard checks requests against the target model's context window before they are sent.
+	ContextGuard ContextGuardConfig `yaml:"context-guard,omitempty" json:"context-guard,omitempty"`
+}
+
+// ContextGuardConfig controls the pre-flight context window check. Requests that do
+// not fit are rejected or shortened according to Strategy.
+type ContextGuardConfig struct {
+	// Enable turns on token counting for chat requests to models with published limits.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// Strategy is "reject" (default), "drop-oldest", "keep-last" or "summarize".
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// KeepLast is the number of recent messages kept by keep-last and left verbatim by summarize.
+	KeepLast int `yaml:"keep-last,omitempty" json:"keep-last,omitempty"`
+
+	// SummaryModel is the model that condenses older turns for the summarize strategy.
+	SummaryModel string `yaml:"summary-model,omitempty" json:"summary-model,omitempty"`
+}
+
+// APIKeyPolicy limits how a single client API key may use the proxy.
+// Zero values leave the corresponding limit disabled.
+type APIKeyPolicy struct {
+	// APIKey is the client key the policy applies to.
+	APIKey string `yaml:"api-key" json:"api-key"`
+
+	// RequestsPerMinute caps requests admitted in any rolling 60 second window.
+	RequestsPerMinute int `yaml:"requests-per-minute,omitempty" json:"requests-per-minute,omitempty"`
+
+	// TokensPerDay caps total tokens consumed per local calendar day.
+	TokensPerDay int64 `yaml:"tokens-per-day,omitempty" json:"tokens-per-day,omitempty"`
+
+	// MaxConcurrentStreams caps the number of streaming responses open at once.
+	MaxConcurrentStreams int `yaml:"max-concurrent-streams,omitempty" json:"max-concurrent-streams,omitempty"`
+
+	// AllowedModels restricts the models the key may request. Entries ending in "*"
+	// match by prefix. An empty list allows every model.
+	AllowedModels []string `yaml:"allowed-models,omitempty" json:"allowed-models,omitempty"`
+
+	// Role grants extra capabilities. "admin" allows per-request routing headers.
+	Role string `yaml:"role,omitempty" json:"role,omitempty"`
+}
+
+// APIKeyRoleAdmin is the APIKeyPolicy role allowed to steer requests to specific credentials.
+const APIKeyRoleAdmin = "admin"
+
+// APIKeyPolicy returns the policy configured for key, or nil when none applies.
+func (c *SDKConfig) APIKeyPolicy(key string) *APIKeyPolicy {
+	if c == nil || key == "" {
+		return nil
+	}
+	for i := range c.APIKeyPolicies {
+		if c.APIKeyPolicies[i].APIKey == key {
+			return &c.APIKeyPolicies[i]
+		}
+	}
+	return nil
 }
 
 // AccessConfig groups request authentication providers.
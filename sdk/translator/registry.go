@@ -2,6 +2,7 @@ package translator
 
 import (
 	"context"
+	"sort"
 	"sync"
 )
 
@@ -38,6 +39,68 @@ func (r *Registry) Register(from, to Format, request RequestTransform, response
 	r.responses[from][to] = response
 }
 
+// Pair describes the transforms registered from one format to another. Request
+// converts From payloads into To payloads; the response transforms convert To
+// responses back into the From format.
+type Pair struct {
+	From              Format `json:"from"`
+	To                Format `json:"to"`
+	Request           bool   `json:"request"`
+	StreamResponse    bool   `json:"stream_response"`
+	NonStreamResponse bool   `json:"non_stream_response"`
+	TokenCount        bool   `json:"token_count"`
+}
+
+// Pairs lists every registered format pair, sorted by source and target format.
+func (r *Registry) Pairs() []Pair {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	index := make(map[[2]Format]*Pair)
+	pair := func(from, to Format) *Pair {
+		key := [2]Format{from, to}
+		if p, ok := index[key]; ok {
+			return p
+		}
+		p := &Pair{From: from, To: to}
+		index[key] = p
+		return p
+	}
+	for from, byTarget := range r.requests {
+		for to, fn := range byTarget {
+			pair(from, to).Request = fn != nil
+		}
+	}
+	for from, byTarget := range r.responses {
+		for to, fn := range byTarget {
+			p := pair(from, to)
+			p.StreamResponse = fn.Stream != nil
+			p.NonStreamResponse = fn.NonStream != nil
+			p.TokenCount = fn.TokenCount != nil
+		}
+	}
+	pairs := make([]Pair, 0, len(index))
+	for _, p := range index {
+		pairs = append(pairs, *p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].From != pairs[j].From {
+			return pairs[i].From < pairs[j].From
+		}
+		return pairs[i].To < pairs[j].To
+	})
+	return pairs
+}
+
+// HasRequestTransformer indicates whether a request translator exists.
+func (r *Registry) HasRequestTransformer(from, to Format) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fn, ok := r.requests[from][to]
+	return ok && fn != nil
+}
+
 // TranslateRequest converts a payload between schemas, returning the original payload
 // if no translator is registered.
 func (r *Registry) TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool) []byte {
@@ -121,6 +184,16 @@ func TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool
 	return defaultRegistry.TranslateRequest(from, to, model, rawJSON, stream)
 }
 
+// Pairs lists the format pairs of the default registry.
+func Pairs() []Pair {
+	return defaultRegistry.Pairs()
+}
+
+// HasRequestTransformer inspects the default registry.
+func HasRequestTransformer(from, to Format) bool {
+	return defaultRegistry.HasRequestTransformer(from, to)
+}
+
 // HasResponseTransformer inspects the default registry.
 func HasResponseTransformer(from, to Format) bool {
 	return defaultRegistry.HasResponseTransformer(from, to)